@@ -0,0 +1,73 @@
+package aboki_africa_assessment
+
+import (
+	"context"
+	"time"
+)
+
+const EntryTypeCampaign = "CAMPAIGN"
+
+// SystemCampaignsAccount funds the rewards paid out by campaigns.
+const SystemCampaignsAccount = "system:campaigns"
+
+// Campaign rewards registrations made with one of its codes according to its own reward rule, as long
+// as it is running and its budget lasts. Codes of a campaign with a sponsor refer new users to the
+// sponsor, who earns the rewards, codes of a campaign without one reward the new users themselves.
+type Campaign struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	StartsAt      time.Time `json:"starts_at"`
+	EndsAt        time.Time `json:"ends_at"`
+	BudgetPoints  int       `json:"budget_points"`
+	SpentPoints   int       `json:"spent_points"`
+	Registrations int       `json:"registrations"`
+	SponsorUserID string    `json:"sponsor_user_id,omitempty"`
+	// WelcomePoints, when set, replaces the configured welcome bonus of users registering with the campaign's codes.
+	WelcomePoints *int     `json:"welcome_points,omitempty"`
+	Codes         []string `json:"codes"`
+	// Rule is the campaign's reward rule, only set on campaigns returned by the campaign service.
+	Rule        *RewardRule           `json:"rule,omitempty"`
+	Redemptions []*CampaignRedemption `json:"redemptions,omitempty"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+}
+
+// IsRunning reports whether registrations at the given time fall within the campaign's window.
+func (c *Campaign) IsRunning(now time.Time) bool {
+	return !now.Before(c.StartsAt) && now.Before(c.EndsAt)
+}
+
+func (c *Campaign) RemainingBudget() int {
+	return c.BudgetPoints - c.SpentPoints
+}
+
+// CampaignRedemption records a registration made with a campaign code and what it earned.
+type CampaignRedemption struct {
+	ID             string    `json:"id"`
+	CampaignID     string    `json:"campaign_id"`
+	Code           string    `json:"code"`
+	UserID         string    `json:"user_id"`
+	BeneficiaryID  string    `json:"beneficiary_id"`
+	ReferralID     string    `json:"referral_id,omitempty"`
+	RuleID         string    `json:"rule_id,omitempty"`
+	RuleVersion    int       `json:"rule_version"`
+	Points         int       `json:"points"`
+	JournalEntryID string    `json:"journal_entry_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type CampaignRepository interface {
+	CreateCampaign(ctx context.Context, campaign *Campaign) error
+	CreateCampaignCode(ctx context.Context, campaignID, code string) error
+	FindCampaignByID(ctx context.Context, id string) (*Campaign, error)
+	// FindCampaignByCode locks the campaign the code belongs to for the rest of the surrounding transaction.
+	FindCampaignByCode(ctx context.Context, code string) (*Campaign, error)
+	ListCampaigns(ctx context.Context) ([]*Campaign, error)
+	// UpdateCampaignUsage saves the spent points and registrations of the campaign.
+	UpdateCampaignUsage(ctx context.Context, campaign *Campaign) error
+	CreateRedemption(ctx context.Context, redemption *CampaignRedemption) error
+	ListRedemptions(ctx context.Context, campaignID string) ([]*CampaignRedemption, error)
+	// IsCodeTaken reports whether the code is already used by a campaign or as a referral code, including
+	// retired referral codes so codes are never handed to someone else.
+	IsCodeTaken(ctx context.Context, code string) (bool, error)
+}
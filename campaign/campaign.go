@@ -0,0 +1,138 @@
+// Package campaign manages referral campaigns: time boxed, budgeted reward rules redeemed through
+// their own codes.
+package campaign
+
+import (
+	"context"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/errors"
+	"github.com/Qalifah/aboki-africa-assessment/rules"
+
+	"github.com/jackc/pgx/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+type Service struct {
+	campaignRepository   core.CampaignRepository
+	userRepository       core.UserRepository
+	rewardRuleRepository core.RewardRuleRepository
+	rewardRules          *rules.Engine
+	beginTxFunc          func() (pgx.Tx, error)
+}
+
+func New(campaignRepository core.CampaignRepository, userRepository core.UserRepository, rewardRuleRepository core.RewardRuleRepository,
+	rewardRules *rules.Engine, beginTxFunc func() (pgx.Tx, error)) *Service {
+	return &Service{
+		campaignRepository:   campaignRepository,
+		userRepository:       userRepository,
+		rewardRuleRepository: rewardRuleRepository,
+		rewardRules:          rewardRules,
+		beginTxFunc:          beginTxFunc,
+	}
+}
+
+// Create saves the campaign along with its reward rule and codes, and loads the rule into the engine.
+func (s *Service) Create(ctx context.Context, req *Request, logger *log.Entry) (*core.Campaign, error) {
+	tx, err := s.beginTxFunc()
+	if err != nil {
+		logger.WithError(err).Error("failed to start transaction")
+		return nil, errors.ErrGeneric
+	}
+	defer tx.Rollback(ctx)
+
+	ctx = context.WithValue(ctx, core.TxContextKey, tx)
+	if req.SponsorUserID != "" {
+		_, err = s.userRepository.FindUserByID(ctx, req.SponsorUserID)
+		if err == pgx.ErrNoRows {
+			return nil, errors.ErrCampaignSponsorNotFound
+		}
+		if err != nil {
+			logger.WithError(err).Error("failed to find campaign sponsor")
+			return nil, errors.ErrGeneric
+		}
+	}
+
+	campaign := &core.Campaign{
+		Name:          req.Name,
+		StartsAt:      req.StartsAt,
+		EndsAt:        req.EndsAt,
+		BudgetPoints:  req.BudgetPoints,
+		SponsorUserID: req.SponsorUserID,
+		WelcomePoints: req.WelcomePoints,
+		Codes:         req.Codes,
+	}
+	if err = s.campaignRepository.CreateCampaign(ctx, campaign); err != nil {
+		logger.WithError(err).Error("failed to create campaign")
+		return nil, errors.ErrGeneric
+	}
+
+	for _, code := range req.Codes {
+		taken, err := s.campaignRepository.IsCodeTaken(ctx, code)
+		if err != nil {
+			logger.WithError(err).Error("failed to check campaign code")
+			return nil, errors.ErrGeneric
+		}
+		if taken {
+			return nil, errors.ErrCampaignCodeTaken
+		}
+
+		if err = s.campaignRepository.CreateCampaignCode(ctx, campaign.ID, code); err != nil {
+			logger.WithError(err).Error("failed to create campaign code")
+			return nil, errors.ErrGeneric
+		}
+	}
+
+	campaign.Rule = req.Rule
+	campaign.Rule.CampaignID = campaign.ID
+	if err = s.rewardRuleRepository.CreateRewardRule(ctx, campaign.Rule); err != nil {
+		logger.WithError(err).Error("failed to create campaign reward rule")
+		return nil, errors.ErrGeneric
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.WithError(err).Error("failed to commit transaction")
+		return nil, errors.ErrGeneric
+	}
+
+	// the campaign exists either way, the rule is picked up by the next periodic reload otherwise
+	if err = s.rewardRules.Reload(context.Background()); err != nil {
+		logger.WithError(err).Error("failed to reload reward rules")
+	}
+
+	return campaign, nil
+}
+
+// Find returns the campaign with its reward rule and redemptions.
+func (s *Service) Find(ctx context.Context, id string, logger *log.Entry) (*core.Campaign, error) {
+	campaign, err := s.campaignRepository.FindCampaignByID(ctx, id)
+	if err == pgx.ErrNoRows {
+		return nil, errors.ErrCampaignNotFound
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to find campaign")
+		return nil, errors.ErrGeneric
+	}
+
+	campaign.Rule = s.rewardRules.CampaignRule(campaign.ID)
+	campaign.Redemptions, err = s.campaignRepository.ListRedemptions(ctx, campaign.ID)
+	if err != nil {
+		logger.WithError(err).Error("failed to list campaign redemptions")
+		return nil, errors.ErrGeneric
+	}
+
+	return campaign, nil
+}
+
+func (s *Service) List(ctx context.Context, logger *log.Entry) ([]*core.Campaign, error) {
+	campaigns, err := s.campaignRepository.ListCampaigns(ctx)
+	if err != nil {
+		logger.WithError(err).Error("failed to list campaigns")
+		return nil, errors.ErrGeneric
+	}
+
+	for _, campaign := range campaigns {
+		campaign.Rule = s.rewardRules.CampaignRule(campaign.ID)
+	}
+	return campaigns, nil
+}
@@ -0,0 +1,84 @@
+package campaign
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+)
+
+var codePattern = regexp.MustCompile(`^[A-Za-z0-9-]{3,20}$`)
+
+type Request struct {
+	Name         string    `json:"name"`
+	StartsAt     time.Time `json:"starts_at"`
+	EndsAt       time.Time `json:"ends_at"`
+	BudgetPoints int       `json:"budget_points"`
+	// SponsorUserID is the user the campaign's codes refer new users to. Without a sponsor the codes
+	// belong to the system and their rewards go to the new users themselves.
+	SponsorUserID string `json:"sponsor_user_id"`
+	// WelcomePoints replaces the configured welcome bonus of users registering with the campaign's codes.
+	WelcomePoints *int     `json:"welcome_points"`
+	Codes         []string `json:"codes"`
+	// Rule decides how many points each registration with a campaign code earns. Rule.Reward is
+	// evaluated against the number of registrations the campaign has had including the new one.
+	Rule *core.RewardRule `json:"rule"`
+}
+
+func (r *Request) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	if r.StartsAt.IsZero() || r.EndsAt.IsZero() {
+		return fmt.Errorf("starts_at and ends_at are required")
+	}
+	if !r.EndsAt.After(r.StartsAt) {
+		return fmt.Errorf("ends_at must be after starts_at")
+	}
+
+	if r.BudgetPoints <= 0 {
+		return fmt.Errorf("budget_points must be greater than zero")
+	}
+
+	if r.WelcomePoints != nil && *r.WelcomePoints < 0 {
+		return fmt.Errorf("welcome_points can't be negative")
+	}
+
+	if r.SponsorUserID != "" && !core.IsUUID(r.SponsorUserID) {
+		return fmt.Errorf("sponsor_user_id must be a uuid")
+	}
+
+	if len(r.Codes) == 0 {
+		return fmt.Errorf("a campaign needs at least one code")
+	}
+	seen := map[string]bool{}
+	for _, code := range r.Codes {
+		if !codePattern.MatchString(code) {
+			return fmt.Errorf("code %q must be 3 to 20 letters, digits or dashes", code)
+		}
+		if seen[strings.ToUpper(code)] {
+			return fmt.Errorf("code %q is listed more than once", code)
+		}
+		seen[strings.ToUpper(code)] = true
+	}
+
+	if r.Rule == nil {
+		return fmt.Errorf("rule is required")
+	}
+	if r.Rule.Name == "" {
+		r.Rule.Name = r.Name
+	}
+	r.Rule.Active = true
+	r.Rule.Level = 1
+	if err := r.Rule.Validate(); err != nil {
+		return fmt.Errorf("rule: %v", err)
+	}
+	if r.Rule.CapPoints > 0 {
+		return fmt.Errorf("rule: campaign rules are capped by the campaign budget instead of cap_points")
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package aboki_africa_assessment
+
+import (
+	"context"
+	"time"
+)
+
+// Reasons a referral is clawed back for.
+const (
+	ClawbackReasonFraud          = "FRAUD"
+	ClawbackReasonAccountDeleted = "ACCOUNT_DELETED"
+	ClawbackReasonOther          = "OTHER"
+)
+
+var ClawbackReasons = map[string]bool{
+	ClawbackReasonFraud:          true,
+	ClawbackReasonAccountDeleted: true,
+	ClawbackReasonOther:          true,
+}
+
+// Clawback records a referral being taken back and the rewards it reversed, the referral's own and the
+// milestone rewards its referrer no longer earns without it.
+type Clawback struct {
+	ID         string `json:"id"`
+	ReferralID string `json:"referral_id"`
+	Reason     string `json:"reason"`
+	Note       string `json:"note"`
+	// Points is what the released rewards taken back were worth, Debt the part of it their users had
+	// already spent and now owe.
+	Points    int               `json:"points"`
+	Debt      int               `json:"debt"`
+	Rewards   []*ReferralReward `json:"rewards,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+type ClawbackRepository interface {
+	// CreateClawback records the clawback and marks its rewards clawed back.
+	CreateClawback(ctx context.Context, clawback *Clawback) error
+	// ListClawbacks returns the most recent clawbacks, newest first.
+	ListClawbacks(ctx context.Context, limit int) ([]*Clawback, error)
+}
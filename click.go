@@ -0,0 +1,46 @@
+package aboki_africa_assessment
+
+import (
+	"context"
+	"time"
+)
+
+// ReferralClick is a visit to the link of a referral code. The visitor's IP is only kept hashed.
+type ReferralClick struct {
+	ID             string    `json:"id"`
+	ReferralCodeID string    `json:"referral_code_id"`
+	Code           string    `json:"code"`
+	IPHash         string    `json:"ip_hash"`
+	UserAgent      string    `json:"user_agent"`
+	UTMSource      string    `json:"utm_source,omitempty"`
+	UTMMedium      string    `json:"utm_medium,omitempty"`
+	UTMCampaign    string    `json:"utm_campaign,omitempty"`
+	UTMTerm        string    `json:"utm_term,omitempty"`
+	UTMContent     string    `json:"utm_content,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ReferralFunnel follows a referral code from clicks on its link to registrations with it, and on to
+// referrals whose referee qualified.
+type ReferralFunnel struct {
+	ReferralCodeID string `json:"referral_code_id"`
+	Code           string `json:"code"`
+	Active         bool   `json:"active"`
+	Clicks         int    `json:"clicks"`
+	// UniqueVisitors counts distinct IPs among the clicks.
+	UniqueVisitors int `json:"unique_visitors"`
+	Registrations  int `json:"registrations"`
+	Qualified      int `json:"qualified"`
+	// ClickConversion is registrations per unique visitor, QualifiedConversion qualified referrals per registration.
+	ClickConversion     float64   `json:"click_conversion"`
+	QualifiedConversion float64   `json:"qualified_conversion"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+type ReferralClickRepository interface {
+	// CreateClick records the click against the live referral code matching its Code, pgx.ErrNoRows
+	// when there is none.
+	CreateClick(ctx context.Context, click *ReferralClick) error
+	// ListFunnels returns the funnel of every code the user had, newest first.
+	ListFunnels(ctx context.Context, userID string) ([]*ReferralFunnel, error)
+}
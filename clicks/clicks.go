@@ -0,0 +1,92 @@
+// Package clicks tracks visits to referral links and follows the visitors through to registrations
+// and qualified referrals.
+package clicks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/errors"
+
+	"github.com/jackc/pgx/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+type Service struct {
+	clickRepository core.ReferralClickRepository
+	userRepository  core.UserRepository
+	ipHashKey       []byte
+}
+
+func New(clickRepository core.ReferralClickRepository, userRepository core.UserRepository, ipHashKey string) *Service {
+	return &Service{
+		clickRepository: clickRepository,
+		userRepository:  userRepository,
+		ipHashKey:       []byte(ipHashKey),
+	}
+}
+
+// Record records a click on the link of a live referral code, errors.ErrReferralCodeNotFound when the
+// code doesn't refer anyone.
+func (s *Service) Record(ctx context.Context, req *Request, logger *log.Entry) (*core.ReferralClick, error) {
+	click := &core.ReferralClick{
+		Code:        req.Code,
+		IPHash:      s.hashIP(req.IP),
+		UserAgent:   req.UserAgent,
+		UTMSource:   req.UTMSource,
+		UTMMedium:   req.UTMMedium,
+		UTMCampaign: req.UTMCampaign,
+		UTMTerm:     req.UTMTerm,
+		UTMContent:  req.UTMContent,
+	}
+
+	err := s.clickRepository.CreateClick(ctx, click)
+	if err == pgx.ErrNoRows {
+		return nil, errors.ErrReferralCodeNotFound
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to record referral click")
+		return nil, errors.ErrGeneric
+	}
+
+	return click, nil
+}
+
+// Funnels returns the funnel of every referral code the user had, newest first.
+func (s *Service) Funnels(ctx context.Context, userID string, logger *log.Entry) ([]*core.ReferralFunnel, error) {
+	_, err := s.userRepository.FindUserByID(ctx, userID)
+	if err == pgx.ErrNoRows {
+		return nil, errors.ErrUserNotFound
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to find user")
+		return nil, errors.ErrGeneric
+	}
+
+	funnels, err := s.clickRepository.ListFunnels(ctx, userID)
+	if err != nil {
+		logger.WithError(err).Error("failed to list referral funnels")
+		return nil, errors.ErrGeneric
+	}
+
+	for _, funnel := range funnels {
+		if funnel.UniqueVisitors > 0 {
+			funnel.ClickConversion = float64(funnel.Registrations) / float64(funnel.UniqueVisitors)
+		}
+		if funnel.Registrations > 0 {
+			funnel.QualifiedConversion = float64(funnel.Qualified) / float64(funnel.Registrations)
+		}
+	}
+
+	return funnels, nil
+}
+
+// hashIP keys the hash so IPs can't be recovered by hashing the whole address space.
+func (s *Service) hashIP(ip string) string {
+	mac := hmac.New(sha256.New, s.ipHashKey)
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
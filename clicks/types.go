@@ -0,0 +1,43 @@
+package clicks
+
+import (
+	"fmt"
+)
+
+const (
+	maxCodeLen      = 20
+	maxUserAgentLen = 512
+	maxUTMLen       = 255
+)
+
+// Request is a visit to a referral link.
+type Request struct {
+	Code        string
+	IP          string
+	UserAgent   string
+	UTMSource   string
+	UTMMedium   string
+	UTMCampaign string
+	UTMTerm     string
+	UTMContent  string
+}
+
+// Validate checks the code and cuts the visitor-supplied fields down to size.
+func (r *Request) Validate() error {
+	if r.Code == "" || len(r.Code) > maxCodeLen {
+		return fmt.Errorf("code must be between 1 and %d characters", maxCodeLen)
+	}
+
+	r.UserAgent = truncate(r.UserAgent, maxUserAgentLen)
+	for _, field := range []*string{&r.UTMSource, &r.UTMMedium, &r.UTMCampaign, &r.UTMTerm, &r.UTMContent} {
+		*field = truncate(*field, maxUTMLen)
+	}
+	return nil
+}
+
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
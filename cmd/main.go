@@ -12,11 +12,21 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
-	
-	"github.com/Qalifah/aboki-africa-assessment/routes"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/campaign"
+	"github.com/Qalifah/aboki-africa-assessment/clicks"
 	"github.com/Qalifah/aboki-africa-assessment/config"
 	"github.com/Qalifah/aboki-africa-assessment/database/postgres"
+	"github.com/Qalifah/aboki-africa-assessment/fraud"
 	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/Qalifah/aboki-africa-assessment/jobs"
+	"github.com/Qalifah/aboki-africa-assessment/leaderboard"
+	"github.com/Qalifah/aboki-africa-assessment/payout"
+	"github.com/Qalifah/aboki-africa-assessment/paystack"
+	"github.com/Qalifah/aboki-africa-assessment/reconciliation"
+	"github.com/Qalifah/aboki-africa-assessment/routes"
+	"github.com/Qalifah/aboki-africa-assessment/rules"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
 )
@@ -43,6 +53,10 @@ func main() {
 		log.Fatalf("failed to decode config file: %v", err)
 	}
 
+	if action := cfg.Referral.QualifyingAction; action != "" && !core.QualifyingActions[action] {
+		log.Fatalf("unknown referral qualifying action %q", action)
+	}
+
 	postgresClient, err := postgres.New(context.Background(), cfg.Postgres)
 	if err != nil {
 		log.Fatalf("failed to create postgre client: %v", err)
@@ -53,11 +67,59 @@ func main() {
 	referralRepo := postgres.NewReferralRepository(postgresClient)
 	pointsRepo := postgres.NewPointRepository(postgresClient)
 	transactionRepo := postgres.NewTransactionRepository(postgresClient)
+	ledgerRepo := postgres.NewLedgerRepository(postgresClient)
+	payoutRepo := postgres.NewPayoutRepository(postgresClient)
+	idempotencyRepo := postgres.NewIdempotencyRepository(postgresClient)
+	holdRepo := postgres.NewHoldRepository(postgresClient)
+	scheduledTransferRepo := postgres.NewScheduledTransferRepository(postgresClient)
+	rewardRuleRepo := postgres.NewRewardRuleRepository(postgresClient)
+	campaignRepo := postgres.NewCampaignRepository(postgresClient)
+	leaderboardRepo := postgres.NewLeaderboardRepository(postgresClient)
+	referralClickRepo := postgres.NewReferralClickRepository(postgresClient)
+	clawbackRepo := postgres.NewClawbackRepository(postgresClient)
+	fraudRepo := postgres.NewFraudRepository(postgresClient)
+
+	rewardRules := rules.New(rewardRuleRepo)
+	if err = rewardRules.Reload(context.Background()); err != nil {
+		log.Fatalf("failed to load reward rules: %v", err)
+	}
+	fraudRules := fraud.New(fraudRepo, cfg.ReferralLinks.IPHashKey, cfg.Fraud.Window)
+	reconciliationRepo := postgres.NewReconciliationRepository(postgresClient)
+
+	h := handler.New(userRepo, referralCodeRepo, referralRepo, pointsRepo, transactionRepo, ledgerRepo, holdRepo, scheduledTransferRepo, rewardRuleRepo, campaignRepo, clawbackRepo, fraudRepo, rewardRules, fraudRules, cfg, postgresClient.BeginTx)
 
-	h := handler.New(userRepo, referralCodeRepo, referralRepo, pointsRepo, transactionRepo, postgresClient.BeginTx)
+	paystackClient := paystack.New(cfg.PaystackAPIKey, cfg.PaystackBaseURL)
+	payoutService := payout.New(payoutRepo, pointsRepo, ledgerRepo, paystackClient, cfg, postgresClient.BeginTx)
+
+	reconciliationService := reconciliation.New(reconciliationRepo, ledgerRepo, postgresClient.BeginTx)
+	leaderboardService := leaderboard.New(leaderboardRepo, postgresClient.BeginTx)
+
+	idempotency := routes.NewIdempotency(idempotencyRepo, cfg.Idempotency.TTL)
+	proxies, err := routes.NewProxies(cfg.TrustedProxies, cfg.CountryHeader)
+	if err != nil {
+		log.Fatalf("failed to parse trusted proxies: %v", err)
+	}
+
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go jobs.Every(jobsCtx, cfg.Idempotency.SweepInterval, "idempotency_sweeper", idempotency.SweepExpired)
+	go jobs.Every(jobsCtx, cfg.Expiry.Interval, "points_expiry", h.ExpirePoints)
+	go jobs.Every(jobsCtx, cfg.Holds.Interval, "holds_expiry", h.ExpireHolds)
+	go jobs.Every(jobsCtx, cfg.Schedules.Interval, "scheduled_transfers", h.RunScheduledTransfers)
+	go jobs.Every(jobsCtx, cfg.Referral.QualificationInterval, "referral_qualification", h.QualifyReferrals)
+	go jobs.Every(jobsCtx, cfg.Clawback.SettlementInterval, "debt_settlement", h.SettleDebts)
+	go jobs.Every(jobsCtx, cfg.Rules.ReloadInterval, "reward_rules_reload", rewardRules.Reload)
+	go jobs.Every(jobsCtx, cfg.Leaderboard.RefreshInterval, "leaderboard_refresh", leaderboardService.Refresh)
+	go jobs.Every(jobsCtx, cfg.Reconciliation.Interval, "reconciliation", reconciliationService.Job(cfg.Reconciliation.Repair))
 
 	router := httptreemux.New()
-	routes.SetupRoutes(router, h)
+	routes.SetupRoutes(router, h, idempotency, proxies)
+	routes.SetupPayoutRoutes(router, payoutService)
+	routes.SetupRewardRuleRoutes(router, rewardRules)
+	routes.SetupCampaignRoutes(router, campaign.New(campaignRepo, userRepo, rewardRuleRepo, rewardRules, postgresClient.BeginTx))
+	routes.SetupReconciliationRoutes(router, reconciliationService)
+	routes.SetupLeaderboardRoutes(router, leaderboardService)
+	routes.SetupReferralLinkRoutes(router, clicks.New(referralClickRepo, userRepo, cfg.ReferralLinks.IPHashKey), cfg.ReferralLinks, proxies)
 
 	srv := &http.Server{
 		Addr:    ":" + cfg.ServePort,
@@ -75,13 +137,14 @@ func main() {
 
 	// Wait for interrupt signal to gracefully shutdown the server with
 	// a timeout of 5 seconds.
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 	// kill (no param) default send syscanll.SIGTERM
 	// kill -2 is syscall.SIGINT
 	// kill -9 is syscall. SIGKILL but can"t be catch, so no need to add it
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Print("shutdown server ...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
@@ -106,4 +169,4 @@ func unmarshalRequestBody(respBody io.ReadCloser, data interface{}) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
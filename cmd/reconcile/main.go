@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/config"
+	"github.com/Qalifah/aboki-africa-assessment/database/postgres"
+	"github.com/Qalifah/aboki-africa-assessment/reconciliation"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	configPath *string
+	repair     *bool
+)
+
+func init() {
+	configPath = flag.String("config_path", "", "path to config file")
+	repair = flag.Bool("repair", false, "correct the discrepancies found")
+	flag.Parse()
+	if *configPath == "" {
+		log.Fatalln("-config_path flag is required")
+	}
+}
+
+// reconcile runs a single reconciliation and prints its report as JSON. It exits with
+// status 1 when discrepancies were left unrepaired.
+func main() {
+	file, err := os.Open(*configPath)
+	if err != nil {
+		log.Fatalf("unable to open config file: %v", err)
+	}
+
+	cfg := &config.BaseConfig{}
+	err = yaml.NewDecoder(file).Decode(cfg)
+	if err != nil {
+		log.Fatalf("failed to decode config file: %v", err)
+	}
+
+	ctx := context.Background()
+	postgresClient, err := postgres.New(ctx, cfg.Postgres)
+	if err != nil {
+		log.Fatalf("failed to create postgre client: %v", err)
+	}
+
+	s := reconciliation.New(postgres.NewReconciliationRepository(postgresClient), postgres.NewLedgerRepository(postgresClient), postgresClient.BeginTx)
+	run, err := s.Run(ctx, *repair, log.WithField("repair", *repair))
+	if err != nil {
+		log.Fatalf("reconciliation failed: %v", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(run); err != nil {
+		log.Fatalf("failed to write report: %v", err)
+	}
+
+	if run.Status != core.RunStatusCompleted || run.Discrepancies > run.Repaired {
+		os.Exit(1)
+	}
+}
@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 type PostgresConfig struct {
 	Database string `yaml:"database"`
 	Host     string `yaml:"host"`
@@ -9,8 +11,164 @@ type PostgresConfig struct {
 	MaxConn  int    `yaml:"max_conn"`
 }
 
+type BonusConfig struct {
+	// MinClaim is the smallest amount of bonus points a user can claim at once.
+	MinClaim int `yaml:"min_claim"`
+}
+
+type PayoutConfig struct {
+	// KoboPerPoint is the exchange rate used to convert points to Naira.
+	KoboPerPoint int `yaml:"kobo_per_point"`
+	// MinPoints is the smallest amount of points that can be cashed out at once.
+	MinPoints int `yaml:"min_points"`
+}
+
+type IdempotencyConfig struct {
+	// TTL is how long a stored response is replayed for a reused Idempotency-Key.
+	TTL time.Duration `yaml:"ttl"`
+	// SweepInterval is how often expired keys are deleted.
+	SweepInterval time.Duration `yaml:"sweep_interval"`
+}
+
+// ExpiryConfig sets how long points stay spendable depending on how they were earned.
+// A zero lifetime means the points never expire.
+type ExpiryConfig struct {
+	ReferralBonus time.Duration `yaml:"referral_bonus"`
+	Transfer      time.Duration `yaml:"transfer"`
+	Promo         time.Duration `yaml:"promo"`
+	Welcome       time.Duration `yaml:"welcome"`
+	// WarningWindow is how far ahead the balance reports points about to expire.
+	WarningWindow time.Duration `yaml:"warning_window"`
+	// Interval is how often expired points are written off.
+	Interval time.Duration `yaml:"interval"`
+}
+
+type HoldConfig struct {
+	// TTL is how long an authorized transfer reserves points before it expires.
+	TTL time.Duration `yaml:"ttl"`
+	// Interval is how often expired holds are marked as such.
+	Interval time.Duration `yaml:"interval"`
+}
+
+type ScheduleConfig struct {
+	// Interval is how often due scheduled transfers are run.
+	Interval time.Duration `yaml:"interval"`
+	// RetryDelay is how long a failed scheduled transfer waits before it is tried again.
+	RetryDelay time.Duration `yaml:"retry_delay"`
+	// MaxFailures is how many times in a row a schedule may fail for lack of funds before
+	// it is paused. Zero never pauses.
+	MaxFailures int `yaml:"max_failures"`
+}
+
+type ReferralConfig struct {
+	// MaxDepth is how many levels up the referral chain a registration rewards, 1 only rewards
+	// the direct referrer.
+	MaxDepth int `yaml:"max_depth"`
+	// CodeChangeLimit is how many vanity codes a user may claim within CodeChangeWindow.
+	CodeChangeLimit  int           `yaml:"code_change_limit"`
+	CodeChangeWindow time.Duration `yaml:"code_change_window"`
+	// MaxRedemptions caps how many users may register with a user's referral code, zero leaves codes
+	// uncapped. Owners may lower the cap on their code but not raise it.
+	MaxRedemptions int `yaml:"max_redemptions"`
+	// AliasGracePeriod is how long a replaced code keeps referring new users to its owner.
+	AliasGracePeriod time.Duration `yaml:"alias_grace_period"`
+	// QualifyingAction is what a referee has to do before the rewards for referring them are released:
+	// none, first_transfer, min_points or email_verified. With none they're released at registration.
+	QualifyingAction string `yaml:"qualifying_action"`
+	// QualifyingPoints is the points balance a referee has to reach with min_points. The welcome bonus
+	// counts towards it, so it should be set above the welcome bonus.
+	QualifyingPoints int `yaml:"qualifying_points"`
+	// QualifyingWindow is how long pending rewards wait for the referee to qualify before they expire,
+	// zero waits forever.
+	QualifyingWindow time.Duration `yaml:"qualifying_window"`
+	// QualificationInterval is how often pending referrals are checked for qualified and expired ones.
+	QualificationInterval time.Duration `yaml:"qualification_interval"`
+}
+
+type RulesConfig struct {
+	// ReloadInterval is how often reward rules are reloaded from the database to pick up
+	// changes made by other instances.
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+}
+
+// WelcomeConfig is the welcome bonus users registering with a referral or campaign code get. Campaigns
+// can set their own.
+type WelcomeConfig struct {
+	// Points is the welcome bonus, zero grants none.
+	Points int `yaml:"points"`
+	// ByCountry replaces Points for users registering from the given countries, keyed by ISO 3166-1 alpha-2
+	// code. The country is the one the trusted proxies tell in CountryHeader, never what the user says.
+	ByCountry map[string]int `yaml:"by_country"`
+}
+
+type ReferralLinkConfig struct {
+	// RedirectURL is where referral links send visitors, with the code in the ref query parameter.
+	RedirectURL string `yaml:"redirect_url"`
+	// CookieTTL is how long a followed link attributes registrations to its code.
+	CookieTTL time.Duration `yaml:"cookie_ttl"`
+	// IPHashKey keys the hashes kept of visitors' IPs, and of device fingerprints for fraud scoring.
+	IPHashKey string `yaml:"ip_hash_key"`
+}
+
+// ClawbackConfig sets when referral rewards are taken back and how the debt that leaves is settled.
+type ClawbackConfig struct {
+	// DeletionWindow is how long after registering a referee deleting their account claws back the
+	// rewards for referring them, zero never does.
+	DeletionWindow time.Duration `yaml:"deletion_window"`
+	// SettlementInterval is how often debts are settled out of the points their users earned since.
+	SettlementInterval time.Duration `yaml:"settlement_interval"`
+}
+
+// EmailVerificationConfig sets how users prove they own their email address.
+type EmailVerificationConfig struct {
+	// Key signs email verification tokens. It is shared with whatever sends the verification emails,
+	// no address can be verified without it.
+	Key string `yaml:"key"`
+}
+
+// FraudConfig sets how referrals are scored for signs of signup rings.
+type FraudConfig struct {
+	// HoldThreshold is the score from which a referral's rewards are held for review, zero only scores them.
+	HoldThreshold int `yaml:"hold_threshold"`
+	// Window is how far back registrations are compared with a new one.
+	Window time.Duration `yaml:"window"`
+}
+
+type LeaderboardConfig struct {
+	// RefreshInterval is how often the leaderboard snapshots are rebuilt.
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+}
+
+type ReconciliationConfig struct {
+	// Interval is how often balances are reconciled in the background. Zero disables the job.
+	Interval time.Duration `yaml:"interval"`
+	// Repair makes background runs correct the discrepancies they find.
+	Repair bool `yaml:"repair"`
+}
+
 type BaseConfig struct {
-	ServePort      string          `yaml:"serve_port"`
-	PaystackAPIKey string          `yaml:"paystack_api_key"`
-	Postgres       *PostgresConfig `yaml:"postgres"`
-}
\ No newline at end of file
+	ServePort         string                  `yaml:"serve_port"`
+	PaystackAPIKey    string                  `yaml:"paystack_api_key"`
+	PaystackBaseURL   string                  `yaml:"paystack_base_url"`
+	Postgres          *PostgresConfig         `yaml:"postgres"`
+	Bonus             BonusConfig             `yaml:"bonus"`
+	Payout            PayoutConfig            `yaml:"payout"`
+	Idempotency       IdempotencyConfig       `yaml:"idempotency"`
+	Expiry            ExpiryConfig            `yaml:"expiry"`
+	Holds             HoldConfig              `yaml:"holds"`
+	Schedules         ScheduleConfig          `yaml:"schedules"`
+	Referral          ReferralConfig          `yaml:"referral"`
+	Rules             RulesConfig             `yaml:"rules"`
+	ReferralLinks     ReferralLinkConfig      `yaml:"referral_links"`
+	Welcome           WelcomeConfig           `yaml:"welcome"`
+	Clawback          ClawbackConfig          `yaml:"clawback"`
+	Fraud             FraudConfig             `yaml:"fraud"`
+	EmailVerification EmailVerificationConfig `yaml:"email_verification"`
+	Leaderboard       LeaderboardConfig       `yaml:"leaderboard"`
+	Reconciliation    ReconciliationConfig    `yaml:"reconciliation"`
+	// TrustedProxies are the addresses, IPs or CIDR blocks, of the proxies in front of the service. The
+	// client IP is only read from X-Forwarded-For on requests coming from one of them.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// CountryHeader is the header the trusted proxies tell the client's country in, e.g. CF-IPCountry.
+	CountryHeader string `yaml:"country_header"`
+}
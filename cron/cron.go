@@ -0,0 +1,168 @@
+// Package cron parses the cron-like rules recurring scheduled transfers run on.
+//
+// A rule is either one of the descriptors @hourly, @daily, @weekly and @monthly or five
+// space separated fields: minute, hour, day of month, month and day of week. Each field
+// takes "*", a value, a range "a-b" or a comma separated list of those, optionally
+// followed by a step "/n". As in standard cron, when both day of month and day of week
+// are restricted a time matches if either of them does. Rules are evaluated in UTC.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var descriptors = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+type field struct {
+	min, max int
+}
+
+var fields = []field{
+	{0, 59}, // minute
+	{0, 23}, // hour
+	{1, 31}, // day of month
+	{1, 12}, // month
+	{0, 6},  // day of week, sunday is 0
+}
+
+// maxSearch bounds how far ahead Next looks for a matching time, a rule such as
+// "0 0 30 2 *" never matches.
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+type Schedule struct {
+	minute, hour, dom, month, dow map[int]bool
+	domAny, dowAny                bool
+}
+
+func Parse(rule string) (*Schedule, error) {
+	rule = strings.TrimSpace(rule)
+	if expanded, ok := descriptors[rule]; ok {
+		rule = expanded
+	}
+
+	parts := strings.Fields(rule)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("cron rule %q must have %d fields", rule, len(fields))
+	}
+
+	sets := make([]map[int]bool, len(fields))
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron rule %q: %v", rule, err)
+		}
+		sets[i] = set
+	}
+
+	return &Schedule{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		domAny: parts[2] == "*",
+		dowAny: parts[4] == "*",
+	}, nil
+}
+
+// Next returns the first time after t that matches the schedule, or the zero time if there
+// is none within the next five years.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxSearch)
+
+	for t.Before(limit) {
+		if !s.month[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.hour[t.Hour()] {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if !s.minute[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (s *Schedule) matchDay(t time.Time) bool {
+	dom, dow := s.dom[t.Day()], s.dow[int(t.Weekday())]
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+func parseField(part string, f field) (map[int]bool, error) {
+	set := map[int]bool{}
+	for _, item := range strings.Split(part, ",") {
+		step := 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", item)
+			}
+			step = n
+			item = item[:i]
+		}
+
+		lo, hi := f.min, f.max
+		if item != "*" {
+			var err error
+			if i := strings.Index(item, "-"); i >= 0 {
+				lo, err = parseValue(item[:i], f)
+				if err == nil {
+					hi, err = parseValue(item[i+1:], f)
+				}
+			} else {
+				lo, err = parseValue(item, f)
+				hi = lo
+				if step > 1 {
+					hi = f.max
+				}
+			}
+			if err != nil {
+				return nil, err
+			}
+			if lo > hi {
+				return nil, fmt.Errorf("invalid range %q", item)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+func parseValue(s string, f field) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %q out of range %d-%d", s, f.min, f.max)
+	}
+	return v, nil
+}
@@ -0,0 +1,173 @@
+package postgres
+
+import (
+	"context"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+
+	"github.com/jackc/pgx/v4"
+)
+
+type CampaignRepository struct {
+	client *Client
+}
+
+func NewCampaignRepository(client *Client) *CampaignRepository {
+	return &CampaignRepository{
+		client: client,
+	}
+}
+
+const campaignColumns = `campaigns.id, campaigns.name, campaigns.starts_at, campaigns.ends_at, campaigns.budget_points, campaigns.spent_points,
+	campaigns.registrations, COALESCE(campaigns.sponsor_user_id::text, ''), campaigns.welcome_points,
+	ARRAY(SELECT code FROM campaign_codes WHERE campaign_codes.campaign_id = campaigns.id ORDER BY created_at, code),
+	campaigns.created_at, campaigns.updated_at`
+
+func scanCampaign(row pgx.Row) (*core.Campaign, error) {
+	campaign := &core.Campaign{}
+	err := row.Scan(&campaign.ID, &campaign.Name, &campaign.StartsAt, &campaign.EndsAt, &campaign.BudgetPoints, &campaign.SpentPoints,
+		&campaign.Registrations, &campaign.SponsorUserID, &campaign.WelcomePoints, &campaign.Codes, &campaign.CreatedAt, &campaign.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+func (r *CampaignRepository) CreateCampaign(ctx context.Context, campaign *core.Campaign) error {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(ctx, `INSERT INTO campaigns (name, starts_at, ends_at, budget_points, sponsor_user_id, welcome_points)
+	VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, $6) RETURNING id, created_at, updated_at`,
+		campaign.Name, campaign.StartsAt, campaign.EndsAt, campaign.BudgetPoints, campaign.SponsorUserID, campaign.WelcomePoints,
+	)
+
+	return row.Scan(&campaign.ID, &campaign.CreatedAt, &campaign.UpdatedAt)
+}
+
+func (r *CampaignRepository) CreateCampaignCode(ctx context.Context, campaignID, code string) error {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "INSERT INTO campaign_codes (campaign_id, code) VALUES ($1, $2)", campaignID, code)
+	return err
+}
+
+func (r *CampaignRepository) FindCampaignByID(ctx context.Context, id string) (*core.Campaign, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanCampaign(tx.QueryRow(ctx, "SELECT "+campaignColumns+" FROM campaigns WHERE id = $1", id))
+}
+
+func (r *CampaignRepository) FindCampaignByCode(ctx context.Context, code string) (*core.Campaign, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanCampaign(tx.QueryRow(ctx, "SELECT "+campaignColumns+` FROM campaigns
+	WHERE id = (SELECT campaign_id FROM campaign_codes WHERE upper(code) = upper($1)) FOR UPDATE`, code))
+}
+
+func (r *CampaignRepository) ListCampaigns(ctx context.Context) ([]*core.Campaign, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, "SELECT "+campaignColumns+" FROM campaigns ORDER BY starts_at DESC, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	campaigns := []*core.Campaign{}
+	for rows.Next() {
+		campaign, err := scanCampaign(rows)
+		if err != nil {
+			return nil, err
+		}
+		campaigns = append(campaigns, campaign)
+	}
+
+	return campaigns, rows.Err()
+}
+
+func (r *CampaignRepository) UpdateCampaignUsage(ctx context.Context, campaign *core.Campaign) error {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(ctx, `UPDATE campaigns SET spent_points = $1, registrations = $2, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $3 RETURNING updated_at`, campaign.SpentPoints, campaign.Registrations, campaign.ID)
+
+	return row.Scan(&campaign.UpdatedAt)
+}
+
+func (r *CampaignRepository) CreateRedemption(ctx context.Context, redemption *core.CampaignRedemption) error {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(ctx, `INSERT INTO campaign_redemptions (campaign_id, code, user_id, beneficiary_id, referral_id, rule_id, rule_version,
+	points, journal_entry_id) VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, NULLIF($6, '')::uuid, $7, $8, NULLIF($9, '')::uuid)
+	RETURNING id, created_at`,
+		redemption.CampaignID, redemption.Code, redemption.UserID, redemption.BeneficiaryID, redemption.ReferralID, redemption.RuleID,
+		redemption.RuleVersion, redemption.Points, redemption.JournalEntryID,
+	)
+
+	return row.Scan(&redemption.ID, &redemption.CreatedAt)
+}
+
+func (r *CampaignRepository) ListRedemptions(ctx context.Context, campaignID string) ([]*core.CampaignRedemption, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT id, campaign_id, code, user_id, beneficiary_id, COALESCE(referral_id::text, ''),
+	COALESCE(rule_id::text, ''), rule_version, points, COALESCE(journal_entry_id::text, ''), created_at
+	FROM campaign_redemptions WHERE campaign_id = $1 ORDER BY created_at, id`, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	redemptions := []*core.CampaignRedemption{}
+	for rows.Next() {
+		redemption := &core.CampaignRedemption{}
+		err = rows.Scan(&redemption.ID, &redemption.CampaignID, &redemption.Code, &redemption.UserID, &redemption.BeneficiaryID,
+			&redemption.ReferralID, &redemption.RuleID, &redemption.RuleVersion, &redemption.Points, &redemption.JournalEntryID,
+			&redemption.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		redemptions = append(redemptions, redemption)
+	}
+
+	return redemptions, rows.Err()
+}
+
+func (r *CampaignRepository) IsCodeTaken(ctx context.Context, code string) (bool, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var taken bool
+	row := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM campaign_codes WHERE upper(code) = upper($1))
+	OR EXISTS (SELECT 1 FROM referral_codes WHERE upper(code) = upper($1))`, code)
+	if err = row.Scan(&taken); err != nil {
+		return false, err
+	}
+	return taken, nil
+}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+)
+
+type ClawbackRepository struct {
+	client *Client
+}
+
+func NewClawbackRepository(client *Client) *ClawbackRepository {
+	return &ClawbackRepository{
+		client: client,
+	}
+}
+
+func (c *ClawbackRepository) CreateClawback(ctx context.Context, clawback *core.Clawback) error {
+	tx, err := c.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(ctx, `INSERT INTO clawbacks (referral_id, reason, note, points, debt) VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, created_at`, clawback.ReferralID, clawback.Reason, clawback.Note, clawback.Points, clawback.Debt)
+	if err = row.Scan(&clawback.ID, &clawback.CreatedAt); err != nil {
+		return err
+	}
+
+	ids := make([]string, len(clawback.Rewards))
+	for i, reward := range clawback.Rewards {
+		ids[i] = reward.ID
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE referral_rewards SET status = $1, clawback_id = $2 WHERE id::text = ANY($3)",
+		core.RewardStatusClawedBack, clawback.ID, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, reward := range clawback.Rewards {
+		reward.Status = core.RewardStatusClawedBack
+	}
+	return nil
+}
+
+func (c *ClawbackRepository) ListClawbacks(ctx context.Context, limit int) ([]*core.Clawback, error) {
+	tx, err := c.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT id, referral_id, reason, note, points, debt, created_at FROM clawbacks
+	ORDER BY created_at DESC, id LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clawbacks := []*core.Clawback{}
+	for rows.Next() {
+		clawback := &core.Clawback{}
+		err = rows.Scan(&clawback.ID, &clawback.ReferralID, &clawback.Reason, &clawback.Note, &clawback.Points, &clawback.Debt, &clawback.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		clawbacks = append(clawbacks, clawback)
+	}
+
+	return clawbacks, rows.Err()
+}
@@ -0,0 +1,171 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+
+	"github.com/jackc/pgx/v4"
+)
+
+type FraudRepository struct {
+	client *Client
+}
+
+func NewFraudRepository(client *Client) *FraudRepository {
+	return &FraudRepository{
+		client: client,
+	}
+}
+
+const signalsColumns = `registration_signals.user_id, COALESCE(registration_signals.referrer_id::text, ''),
+COALESCE(registration_signals.referral_code_id::text, ''), registration_signals.ip_hash, registration_signals.user_agent,
+registration_signals.device_hash, users.email, users.name, registration_signals.created_at`
+
+func scanSignals(row pgx.Row) (*core.RegistrationSignals, error) {
+	signals := &core.RegistrationSignals{}
+	err := row.Scan(&signals.UserID, &signals.ReferrerID, &signals.ReferralCodeID, &signals.IPHash, &signals.UserAgent,
+		&signals.DeviceHash, &signals.Email, &signals.Name, &signals.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return signals, nil
+}
+
+func (f *FraudRepository) CreateSignals(ctx context.Context, signals *core.RegistrationSignals) error {
+	tx, err := f.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(ctx, `INSERT INTO registration_signals (user_id, referrer_id, referral_code_id, ip_hash, user_agent, device_hash)
+	VALUES ($1, NULLIF($2, '')::uuid, NULLIF($3, '')::uuid, $4, $5, $6) RETURNING created_at`,
+		signals.UserID, signals.ReferrerID, signals.ReferralCodeID, signals.IPHash, signals.UserAgent, signals.DeviceHash)
+
+	return row.Scan(&signals.CreatedAt)
+}
+
+func (f *FraudRepository) FindSignals(ctx context.Context, userID string) (*core.RegistrationSignals, error) {
+	tx, err := f.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanSignals(tx.QueryRow(ctx, `SELECT `+signalsColumns+` FROM registration_signals
+	INNER JOIN users ON users.id = registration_signals.user_id WHERE registration_signals.user_id = $1`, userID))
+}
+
+func (f *FraudRepository) ListReferredSignals(ctx context.Context, referrerID string, since time.Time) ([]*core.RegistrationSignals, error) {
+	tx, err := f.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT `+signalsColumns+` FROM registration_signals
+	INNER JOIN users ON users.id = registration_signals.user_id
+	WHERE registration_signals.referrer_id = $1 AND registration_signals.created_at >= $2
+	ORDER BY registration_signals.created_at DESC, registration_signals.user_id`, referrerID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	signals := []*core.RegistrationSignals{}
+	for rows.Next() {
+		s, err := scanSignals(rows)
+		if err != nil {
+			return nil, err
+		}
+		signals = append(signals, s)
+	}
+
+	return signals, rows.Err()
+}
+
+func (f *FraudRepository) CountDeviceRegistrations(ctx context.Context, deviceHash string, since time.Time) (int, error) {
+	tx, err := f.client.GetTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	row := tx.QueryRow(ctx, "SELECT COUNT(*) FROM registration_signals WHERE device_hash = $1 AND created_at >= $2", deviceHash, since)
+	if err = row.Scan(&n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (f *FraudRepository) CreateFraudReview(ctx context.Context, review *core.FraudReview) error {
+	tx, err := f.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(ctx, `INSERT INTO fraud_reviews (referral_id, score, hits, status) VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at`, review.ReferralID, review.Score, jsonList(review.Hits), review.Status)
+
+	return row.Scan(&review.ID, &review.CreatedAt)
+}
+
+const fraudReviewColumns = `fraud_reviews.id, fraud_reviews.referral_id, referrals.referrer_id, referrals.referee_id, fraud_reviews.score,
+fraud_reviews.hits, fraud_reviews.status, fraud_reviews.note, fraud_reviews.reviewed_at, fraud_reviews.created_at`
+
+func scanFraudReview(row pgx.Row) (*core.FraudReview, error) {
+	review := &core.FraudReview{}
+	err := row.Scan(&review.ID, &review.ReferralID, &review.ReferrerID, &review.RefereeID, &review.Score, &review.Hits,
+		&review.Status, &review.Note, &review.ReviewedAt, &review.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+func (f *FraudRepository) FindFraudReviewByID(ctx context.Context, id string) (*core.FraudReview, error) {
+	tx, err := f.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanFraudReview(tx.QueryRow(ctx, `SELECT `+fraudReviewColumns+` FROM fraud_reviews
+	INNER JOIN referrals ON referrals.id = fraud_reviews.referral_id WHERE fraud_reviews.id = $1 FOR UPDATE OF fraud_reviews`, id))
+}
+
+func (f *FraudRepository) ListFraudReviews(ctx context.Context, status string, limit int) ([]*core.FraudReview, error) {
+	tx, err := f.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT `+fraudReviewColumns+` FROM fraud_reviews
+	INNER JOIN referrals ON referrals.id = fraud_reviews.referral_id WHERE fraud_reviews.status = $1
+	ORDER BY fraud_reviews.created_at, fraud_reviews.id LIMIT $2`, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := []*core.FraudReview{}
+	for rows.Next() {
+		review, err := scanFraudReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+
+	return reviews, rows.Err()
+}
+
+func (f *FraudRepository) UpdateFraudReview(ctx context.Context, review *core.FraudReview) error {
+	tx, err := f.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE fraud_reviews SET status = $1, note = $2, reviewed_at = $3 WHERE id = $4",
+		review.Status, review.Note, review.ReviewedAt, review.ID)
+
+	return err
+}
@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+)
+
+type HoldRepository struct {
+	client *Client
+}
+
+func NewHoldRepository(client *Client) *HoldRepository {
+	return &HoldRepository{
+		client: client,
+	}
+}
+
+func (h *HoldRepository) CreateHold(ctx context.Context, hold *core.Hold) error {
+	tx, err := h.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(ctx, `INSERT INTO holds (sender_id, recipient_id, points, status, expires_at) VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, created_at, updated_at`,
+		hold.SenderID, hold.RecipientID, hold.Points, hold.Status, hold.ExpiresAt,
+	)
+
+	return row.Scan(&hold.ID, &hold.CreatedAt, &hold.UpdatedAt)
+}
+
+func (h *HoldRepository) FindHoldByID(ctx context.Context, id string) (*core.Hold, error) {
+	tx, err := h.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	row := tx.QueryRow(ctx, `SELECT id, sender_id, recipient_id, points, captured_points, status, COALESCE(transaction_id::text, ''),
+	expires_at, created_at, updated_at FROM holds WHERE id = $1 FOR UPDATE`, id)
+
+	hold := &core.Hold{}
+	err = row.Scan(&hold.ID, &hold.SenderID, &hold.RecipientID, &hold.Points, &hold.CapturedPoints, &hold.Status, &hold.TransactionID,
+		&hold.ExpiresAt, &hold.CreatedAt, &hold.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+func (h *HoldRepository) UpdateHold(ctx context.Context, hold *core.Hold) error {
+	tx, err := h.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(ctx, `UPDATE holds SET captured_points = $1, status = $2, transaction_id = NULLIF($3, '')::uuid,
+	updated_at = CURRENT_TIMESTAMP WHERE id = $4 RETURNING updated_at`,
+		hold.CapturedPoints, hold.Status, hold.TransactionID, hold.ID,
+	)
+
+	return row.Scan(&hold.UpdatedAt)
+}
+
+func (h *HoldRepository) GetHeldPoints(ctx context.Context, userID string) (int, error) {
+	tx, err := h.client.GetTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var held int
+	row := tx.QueryRow(ctx, `SELECT COALESCE(SUM(points), 0) FROM holds
+	WHERE sender_id = $1 AND status = 'ACTIVE' AND expires_at > CURRENT_TIMESTAMP`, userID)
+	if err = row.Scan(&held); err != nil {
+		return 0, err
+	}
+	return held, nil
+}
+
+func (h *HoldRepository) ExpireHolds(ctx context.Context, before time.Time) (int64, error) {
+	tx, err := h.client.GetTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	tag, err := tx.Exec(ctx, `UPDATE holds SET status = 'EXPIRED', updated_at = CURRENT_TIMESTAMP
+	WHERE status = 'ACTIVE' AND expires_at <= $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+
+	"github.com/jackc/pgx/v4"
+)
+
+type IdempotencyRepository struct {
+	client *Client
+}
+
+func NewIdempotencyRepository(client *Client) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		client: client,
+	}
+}
+
+// ClaimIdempotencyKey inserts the key, taking over a stored one only once it has expired.
+func (i *IdempotencyRepository) ClaimIdempotencyKey(ctx context.Context, key *core.IdempotencyKey) (bool, error) {
+	tx, err := i.client.GetTx(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	row := tx.QueryRow(ctx, `INSERT INTO idempotency_keys (key, route, request_hash, expires_at) VALUES ($1, $2, $3, $4)
+	ON CONFLICT (key, route) DO UPDATE SET request_hash = EXCLUDED.request_hash, status_code = NULL, response_body = NULL,
+	created_at = CURRENT_TIMESTAMP, expires_at = EXCLUDED.expires_at WHERE idempotency_keys.expires_at <= CURRENT_TIMESTAMP
+	RETURNING created_at`, key.Key, key.Route, key.RequestHash, key.ExpiresAt)
+
+	err = row.Scan(&key.CreatedAt)
+	if err == nil {
+		return true, nil
+	}
+	if err != pgx.ErrNoRows {
+		return false, err
+	}
+
+	row = tx.QueryRow(ctx, `SELECT request_hash, COALESCE(status_code, 0), response_body, created_at, expires_at FROM idempotency_keys
+	WHERE key = $1 AND route = $2`, key.Key, key.Route)
+
+	err = row.Scan(&key.RequestHash, &key.StatusCode, &key.ResponseBody, &key.CreatedAt, &key.ExpiresAt)
+	return false, err
+}
+
+func (i *IdempotencyRepository) SaveIdempotentResponse(ctx context.Context, key *core.IdempotencyKey) error {
+	tx, err := i.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE idempotency_keys SET status_code = $1, response_body = $2 WHERE key = $3 AND route = $4",
+		key.StatusCode, key.ResponseBody, key.Key, key.Route,
+	)
+
+	return err
+}
+
+func (i *IdempotencyRepository) DeleteIdempotencyKey(ctx context.Context, key, route string) error {
+	tx, err := i.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "DELETE FROM idempotency_keys WHERE key = $1 AND route = $2", key, route)
+
+	return err
+}
+
+func (i *IdempotencyRepository) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	tx, err := i.client.GetTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	tag, err := tx.Exec(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= CURRENT_TIMESTAMP")
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
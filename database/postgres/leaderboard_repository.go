@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+)
+
+type LeaderboardRepository struct {
+	client *Client
+}
+
+func NewLeaderboardRepository(client *Client) *LeaderboardRepository {
+	return &LeaderboardRepository{
+		client: client,
+	}
+}
+
+// refreshLeaderboard ranks everyone who referred someone, or earned points for referrals, since $2. Referrals
+// still waiting for the referee to qualify, and their rewards, don't count yet. Ties go to whoever reached the score first, then to the lowest user id.
+const refreshLeaderboard = `WITH referred AS (
+	SELECT referrer_id AS user_id, COUNT(*) AS referrals, MAX(created_at) AS last_referral_at FROM referrals
+	WHERE deleted_at IS NULL AND status = 'QUALIFIED' AND created_at >= $2 GROUP BY referrer_id
+), earned AS (
+	SELECT user_id, SUM(points) AS points, MAX(created_at) AS last_reward_at FROM referral_rewards
+	WHERE status = 'RELEASED' AND created_at >= $2 GROUP BY user_id
+), totals AS (
+	SELECT COALESCE(referred.user_id, earned.user_id) AS user_id, COALESCE(referred.referrals, 0) AS referrals,
+	COALESCE(earned.points, 0) AS points, referred.last_referral_at, earned.last_reward_at
+	FROM referred FULL JOIN earned ON referred.user_id = earned.user_id
+)
+INSERT INTO leaderboard_entries (period, metric, user_id, rank, referrals, points, reached_at)
+SELECT $1, 'referrals', user_id, ROW_NUMBER() OVER (ORDER BY referrals DESC, last_referral_at, user_id), referrals, points, last_referral_at
+FROM totals WHERE referrals > 0
+UNION ALL
+SELECT $1, 'points', user_id, ROW_NUMBER() OVER (ORDER BY points DESC, last_reward_at, user_id), referrals, points, last_reward_at
+FROM totals WHERE points > 0`
+
+func (r *LeaderboardRepository) RefreshLeaderboard(ctx context.Context, period string, since time.Time) error {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(ctx, "DELETE FROM leaderboard_entries WHERE period = $1", period); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(ctx, refreshLeaderboard, period, since); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO leaderboard_refreshes (period, refreshed_at) VALUES ($1, CURRENT_TIMESTAMP)
+	ON CONFLICT (period) DO UPDATE SET refreshed_at = EXCLUDED.refreshed_at`, period)
+	return err
+}
+
+const leaderboardColumns = `leaderboard_entries.rank, leaderboard_entries.user_id, users.name, leaderboard_entries.referrals,
+	leaderboard_entries.points, leaderboard_entries.reached_at`
+
+func (r *LeaderboardRepository) ListLeaderboard(ctx context.Context, period, metric string, limit int) ([]*core.LeaderboardEntry, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, "SELECT "+leaderboardColumns+` FROM leaderboard_entries
+	INNER JOIN users ON users.id = leaderboard_entries.user_id
+	WHERE leaderboard_entries.period = $1 AND leaderboard_entries.metric = $2 ORDER BY leaderboard_entries.rank LIMIT $3`, period, metric, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*core.LeaderboardEntry{}
+	for rows.Next() {
+		entry := &core.LeaderboardEntry{}
+		if err = rows.Scan(&entry.Rank, &entry.UserID, &entry.Name, &entry.Referrals, &entry.Points, &entry.ReachedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+func (r *LeaderboardRepository) FindLeaderboardEntry(ctx context.Context, period, metric, userID string) (*core.LeaderboardEntry, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &core.LeaderboardEntry{}
+	row := tx.QueryRow(ctx, "SELECT "+leaderboardColumns+` FROM leaderboard_entries
+	INNER JOIN users ON users.id = leaderboard_entries.user_id
+	WHERE leaderboard_entries.period = $1 AND leaderboard_entries.metric = $2 AND leaderboard_entries.user_id = $3`, period, metric, userID)
+	if err = row.Scan(&entry.Rank, &entry.UserID, &entry.Name, &entry.Referrals, &entry.Points, &entry.ReachedAt); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (r *LeaderboardRepository) LastRefreshedAt(ctx context.Context, period string) (*time.Time, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var refreshedAt *time.Time
+	row := tx.QueryRow(ctx, "SELECT MAX(refreshed_at) FROM leaderboard_refreshes WHERE period = $1", period)
+	if err = row.Scan(&refreshedAt); err != nil {
+		return nil, err
+	}
+	return refreshedAt, nil
+}
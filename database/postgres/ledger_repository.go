@@ -0,0 +1,252 @@
+package postgres
+
+import (
+	"context"
+	"sort"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/errors"
+)
+
+const nonNegativeBalanceConstraint = "ledger_accounts_non_negative"
+
+type LedgerRepository struct {
+	client *Client
+}
+
+func NewLedgerRepository(client *Client) *LedgerRepository {
+	return &LedgerRepository{
+		client: client,
+	}
+}
+
+func (l *LedgerRepository) CreateUserAccounts(ctx context.Context, userID string) error {
+	tx, err := l.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx,
+		"INSERT INTO ledger_accounts (user_id, code, type, allow_negative) VALUES ($1, $2, $3, false), ($1, $4, $5, false), ($1, $6, $7, true)",
+		userID, core.UserPointsAccount(userID), core.AccountTypePoints, core.UserBonusAccount(userID), core.AccountTypeBonus,
+		core.UserDebtAccount(userID), core.AccountTypeDebt,
+	)
+
+	return err
+}
+
+func (l *LedgerRepository) FindAccountByCode(ctx context.Context, code string) (*core.LedgerAccount, error) {
+	tx, err := l.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	row := tx.QueryRow(ctx, `SELECT id, COALESCE(user_id::text, ''), code, type, balance, allow_negative, created_at, updated_at
+	FROM ledger_accounts WHERE code = $1`, code)
+
+	account := &core.LedgerAccount{}
+	err = row.Scan(&account.ID, &account.UserID, &account.Code, &account.Type, &account.Balance, &account.AllowNegative, &account.CreatedAt, &account.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// LockAccount reads the account and locks it until the surrounding transaction ends.
+func (l *LedgerRepository) LockAccount(ctx context.Context, code string) (*core.LedgerAccount, error) {
+	tx, err := l.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	row := tx.QueryRow(ctx, `SELECT id, COALESCE(user_id::text, ''), code, type, balance, allow_negative, created_at, updated_at
+	FROM ledger_accounts WHERE code = $1 FOR UPDATE`, code)
+
+	account := &core.LedgerAccount{}
+	err = row.Scan(&account.ID, &account.UserID, &account.Code, &account.Type, &account.Balance, &account.AllowNegative, &account.CreatedAt, &account.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// PostEntry writes the entry and its postings and moves the cached balance of every account it touches.
+// Accounts are updated in code order so concurrent entries over the same accounts can't deadlock.
+func (l *LedgerRepository) PostEntry(ctx context.Context, entry *core.JournalEntry) error {
+	if len(entry.Postings) < 2 || !entry.IsBalanced() {
+		return errors.ErrUnbalancedEntry
+	}
+
+	return l.client.WithTx(ctx, func(ctx context.Context) error {
+		tx, err := l.client.GetTx(ctx)
+		if err != nil {
+			return err
+		}
+
+		row := tx.QueryRow(ctx,
+			"INSERT INTO journal_entries (type, reference_id, description) VALUES ($1, NULLIF($2, '')::uuid, $3) RETURNING id, created_at",
+			entry.Type, entry.ReferenceID, entry.Description,
+		)
+		if err = row.Scan(&entry.ID, &entry.CreatedAt); err != nil {
+			return err
+		}
+
+		postings := make([]*core.Posting, len(entry.Postings))
+		copy(postings, entry.Postings)
+		sort.SliceStable(postings, func(i, j int) bool {
+			return postings[i].AccountCode < postings[j].AccountCode
+		})
+
+		for _, posting := range postings {
+			var accountType, userID string
+			row = tx.QueryRow(ctx,
+				`UPDATE ledger_accounts SET balance = balance + $1, updated_at = CURRENT_TIMESTAMP WHERE code = $2
+				RETURNING id, balance, type, COALESCE(user_id::text, '')`,
+				posting.Amount, posting.AccountCode,
+			)
+			if err = row.Scan(&posting.AccountID, &posting.BalanceAfter, &accountType, &userID); err != nil {
+				if IsConstraintError(err, nonNegativeBalanceConstraint) {
+					return errors.ErrInsufficientFunds
+				}
+				return err
+			}
+
+			row = tx.QueryRow(ctx,
+				"INSERT INTO postings (journal_entry_id, account_id, amount, balance_after) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+				entry.ID, posting.AccountID, posting.Amount, posting.BalanceAfter,
+			)
+			if err = row.Scan(&posting.ID, &posting.CreatedAt); err != nil {
+				return err
+			}
+			posting.JournalEntryID = entry.ID
+
+			if accountType != core.AccountTypePoints {
+				continue
+			}
+
+			if posting.Amount > 0 {
+				err = l.grantLot(ctx, tx, entry, userID, posting.Amount)
+			} else {
+				err = l.consumeLots(ctx, tx, userID, -posting.Amount, entry.LotID)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (l *LedgerRepository) grantLot(ctx context.Context, tx Tx, entry *core.JournalEntry, userID string, points int) error {
+	_, err := tx.Exec(ctx,
+		"INSERT INTO point_lots (user_id, journal_entry_id, source, points, remaining, expires_at) VALUES ($1, $2, $3, $4, $4, $5)",
+		userID, entry.ID, entry.Type, points, entry.ExpiresAt,
+	)
+
+	return err
+}
+
+// consumeLots takes points out of the user's open lots, oldest first, or out of lotID alone when it's set.
+// Expired lots are only taken from by name, when they're written off, so they can't be spent before then.
+func (l *LedgerRepository) consumeLots(ctx context.Context, tx Tx, userID string, points int, lotID string) error {
+	rows, err := tx.Query(ctx, `SELECT id, remaining FROM point_lots WHERE user_id = $1 AND remaining > 0
+	AND ($2 = '' OR id::text = $2) AND ($2 <> '' OR expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+	ORDER BY created_at, seq FOR UPDATE`, userID, lotID)
+	if err != nil {
+		return err
+	}
+
+	type lot struct {
+		id        string
+		remaining int
+	}
+	lots := []lot{}
+	for rows.Next() {
+		var lt lot
+		if err = rows.Scan(&lt.id, &lt.remaining); err != nil {
+			rows.Close()
+			return err
+		}
+		lots = append(lots, lt)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, lt := range lots {
+		if points == 0 {
+			break
+		}
+
+		take := lt.remaining
+		if take > points {
+			take = points
+		}
+
+		if _, err = tx.Exec(ctx, "UPDATE point_lots SET remaining = remaining - $1 WHERE id = $2", take, lt.id); err != nil {
+			return err
+		}
+		points -= take
+	}
+
+	if points > 0 {
+		return errors.ErrInsufficientFunds
+	}
+	return nil
+}
+
+func (l *LedgerRepository) ListPostingsByAccount(ctx context.Context, code string) ([]*core.Posting, error) {
+	tx, err := l.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT postings.id, postings.journal_entry_id, postings.account_id, ledger_accounts.code, postings.amount,
+	postings.balance_after, postings.created_at FROM postings INNER JOIN ledger_accounts ON ledger_accounts.id = postings.account_id
+	WHERE ledger_accounts.code = $1 ORDER BY postings.seq`, code)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	postings := []*core.Posting{}
+	for rows.Next() {
+		posting := &core.Posting{}
+		err = rows.Scan(&posting.ID, &posting.JournalEntryID, &posting.AccountID, &posting.AccountCode, &posting.Amount, &posting.BalanceAfter, &posting.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		postings = append(postings, posting)
+	}
+
+	return postings, rows.Err()
+}
+
+func (l *LedgerRepository) ListDebtors(ctx context.Context, limit int) ([]string, error) {
+	tx, err := l.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT user_id FROM ledger_accounts WHERE type = $1 AND balance < 0
+	ORDER BY updated_at, id LIMIT $2`, core.AccountTypeDebt, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	debtors := []string{}
+	for rows.Next() {
+		var userID string
+		if err = rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		debtors = append(debtors, userID)
+	}
+
+	return debtors, rows.Err()
+}
@@ -0,0 +1,17 @@
+ALTER TABLE user_points ADD COLUMN IF NOT EXISTS points INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE user_points ADD COLUMN IF NOT EXISTS bonus INTEGER NOT NULL DEFAULT 0;
+
+UPDATE user_points SET
+    points = COALESCE((SELECT balance FROM ledger_accounts WHERE ledger_accounts.user_id = user_points.user_id AND type = 'POINTS'), 0),
+    bonus = COALESCE((SELECT balance FROM ledger_accounts WHERE ledger_accounts.user_id = user_points.user_id AND type = 'BONUS'), 0);
+
+DROP TABLE IF EXISTS postings;
+
+DROP TABLE IF EXISTS journal_entries;
+
+DROP TABLE IF EXISTS ledger_accounts;
+
+DROP FUNCTION IF EXISTS ledger_check_balanced;
+
+DROP FUNCTION IF EXISTS ledger_reject_mutation;
@@ -0,0 +1,98 @@
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid REFERENCES users(id),
+    code text NOT NULL UNIQUE,
+    type VARCHAR (20) NOT NULL,
+    balance BIGINT NOT NULL DEFAULT 0,
+    allow_negative BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT ledger_accounts_non_negative CHECK (allow_negative OR balance >= 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS ledger_accounts_user_type_idx ON ledger_accounts (user_id, type) WHERE user_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS journal_entries (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    type VARCHAR (20) NOT NULL,
+    reference_id uuid,
+    description text NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS postings (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    seq BIGSERIAL NOT NULL,
+    journal_entry_id uuid REFERENCES journal_entries(id) NOT NULL,
+    account_id uuid REFERENCES ledger_accounts(id) NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount <> 0),
+    balance_after BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS postings_journal_entry_idx ON postings (journal_entry_id);
+CREATE INDEX IF NOT EXISTS postings_account_idx ON postings (account_id, seq);
+
+-- journal entries and postings are append-only
+CREATE OR REPLACE FUNCTION ledger_reject_mutation() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'ledger rows are immutable: % on % is not allowed', TG_OP, TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER journal_entries_immutable BEFORE UPDATE OR DELETE ON journal_entries
+    FOR EACH ROW EXECUTE FUNCTION ledger_reject_mutation();
+
+CREATE TRIGGER postings_immutable BEFORE UPDATE OR DELETE ON postings
+    FOR EACH ROW EXECUTE FUNCTION ledger_reject_mutation();
+
+-- the postings of every journal entry must sum to zero by the time the transaction commits
+CREATE OR REPLACE FUNCTION ledger_check_balanced() RETURNS trigger AS $$
+DECLARE
+    total BIGINT;
+BEGIN
+    SELECT COALESCE(SUM(amount), 0) INTO total FROM postings WHERE journal_entry_id = NEW.journal_entry_id;
+    IF total <> 0 THEN
+        RAISE EXCEPTION 'journal entry % is unbalanced by %', NEW.journal_entry_id, total;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER postings_balanced AFTER INSERT ON postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION ledger_check_balanced();
+
+-- system accounts points are issued from
+INSERT INTO ledger_accounts (code, type, allow_negative) VALUES
+    ('system:opening_balance', 'SYSTEM', true),
+    ('system:referral_bonus', 'SYSTEM', true)
+ON CONFLICT (code) DO NOTHING;
+
+-- move the existing counters into the ledger as opening balances
+INSERT INTO ledger_accounts (user_id, code, type, balance)
+    SELECT user_id, 'user:' || user_id || ':points', 'POINTS', points FROM user_points WHERE deleted_at IS NULL;
+
+INSERT INTO ledger_accounts (user_id, code, type, balance)
+    SELECT user_id, 'user:' || user_id || ':bonus', 'BONUS', bonus FROM user_points WHERE deleted_at IS NULL;
+
+WITH opening AS (
+    INSERT INTO journal_entries (type, reference_id, description)
+        SELECT 'OPENING', a.id, 'opening balance migrated from user_points'
+        FROM ledger_accounts a WHERE a.user_id IS NOT NULL AND a.balance <> 0
+    RETURNING id, reference_id
+), legs AS (
+    SELECT o.id AS entry_id, a.id AS account_id, a.balance FROM opening o INNER JOIN ledger_accounts a ON a.id = o.reference_id
+)
+INSERT INTO postings (journal_entry_id, account_id, amount, balance_after)
+    SELECT entry_id, account_id, balance, balance FROM legs
+    UNION ALL
+    SELECT l.entry_id, s.id, -l.balance, -SUM(l.balance) OVER (ORDER BY l.account_id)
+        FROM legs l CROSS JOIN ledger_accounts s WHERE s.code = 'system:opening_balance';
+
+UPDATE ledger_accounts SET balance = (
+    SELECT -COALESCE(SUM(balance), 0) FROM ledger_accounts WHERE user_id IS NOT NULL
+) WHERE code = 'system:opening_balance';
+
+ALTER TABLE user_points DROP COLUMN IF EXISTS points;
+ALTER TABLE user_points DROP COLUMN IF EXISTS bonus;
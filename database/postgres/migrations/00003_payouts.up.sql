@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS payout_recipients (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid REFERENCES users(id) NOT NULL,
+    account_number VARCHAR (10) NOT NULL,
+    bank_code VARCHAR (10) NOT NULL,
+    account_name text NOT NULL,
+    recipient_code text NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, bank_code, account_number)
+);
+
+CREATE TABLE IF NOT EXISTS payouts (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid REFERENCES users(id) NOT NULL,
+    recipient_id uuid REFERENCES payout_recipients(id) NOT NULL,
+    points INTEGER NOT NULL CHECK (points > 0),
+    amount_kobo BIGINT NOT NULL CHECK (amount_kobo > 0),
+    currency VARCHAR (3) NOT NULL DEFAULT 'NGN',
+    status VARCHAR (10) NOT NULL DEFAULT 'PENDING',
+    reference text NOT NULL UNIQUE,
+    transfer_code text NOT NULL DEFAULT '',
+    failure_reason text NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS payouts_pending_idx ON payouts (user_id) WHERE status = 'PENDING';
+
+INSERT INTO ledger_accounts (code, type, allow_negative) VALUES ('system:payouts', 'SYSTEM', true)
+ON CONFLICT (code) DO NOTHING;
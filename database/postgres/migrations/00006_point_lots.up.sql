@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS point_lots (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    seq BIGSERIAL NOT NULL,
+    user_id uuid REFERENCES users(id) NOT NULL,
+    journal_entry_id uuid REFERENCES journal_entries(id),
+    source VARCHAR (20) NOT NULL,
+    points INTEGER NOT NULL CHECK (points > 0),
+    remaining INTEGER NOT NULL CHECK (remaining >= 0 AND remaining <= points),
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS point_lots_open_idx ON point_lots (user_id, seq) WHERE remaining > 0;
+CREATE INDEX IF NOT EXISTS point_lots_expiry_idx ON point_lots (expires_at) WHERE remaining > 0 AND expires_at IS NOT NULL;
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
+
+INSERT INTO ledger_accounts (code, type, allow_negative) VALUES
+    ('system:expired_points', 'SYSTEM', true),
+    ('system:promotions', 'SYSTEM', true)
+ON CONFLICT (code) DO NOTHING;
+
+-- points held before lots existed never expire
+INSERT INTO point_lots (user_id, source, points, remaining)
+    SELECT user_id, 'OPENING', balance, balance FROM ledger_accounts WHERE type = 'POINTS' AND balance > 0;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS transactions_sender_history_idx;
+
+DROP INDEX IF EXISTS transactions_recipient_history_idx;
@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS transactions_sender_history_idx ON transactions (sender_id, created_at DESC, id DESC) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS transactions_recipient_history_idx ON transactions (recipient_id, created_at DESC, id DESC) WHERE deleted_at IS NULL;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS journal_entries_reference_idx;
+
+DROP TABLE IF EXISTS reconciliation_items;
+
+DROP TABLE IF EXISTS reconciliation_runs;
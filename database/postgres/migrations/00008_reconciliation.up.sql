@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS reconciliation_runs (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    repair BOOLEAN NOT NULL DEFAULT false,
+    status VARCHAR (10) NOT NULL DEFAULT 'RUNNING',
+    users_checked INTEGER NOT NULL DEFAULT 0,
+    discrepancies INTEGER NOT NULL DEFAULT 0,
+    repaired INTEGER NOT NULL DEFAULT 0,
+    error text NOT NULL DEFAULT '',
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS reconciliation_items (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    run_id uuid REFERENCES reconciliation_runs(id) NOT NULL,
+    user_id uuid REFERENCES users(id) NOT NULL,
+    "check" VARCHAR (20) NOT NULL,
+    expected BIGINT NOT NULL,
+    actual BIGINT NOT NULL,
+    repaired BOOLEAN NOT NULL DEFAULT false,
+    note text NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS reconciliation_items_run_idx ON reconciliation_items (run_id);
+
+CREATE INDEX IF NOT EXISTS journal_entries_reference_idx ON journal_entries (reference_id) WHERE reference_id IS NOT NULL;
+
+INSERT INTO ledger_accounts (code, type, allow_negative) VALUES ('system:adjustments', 'SYSTEM', true)
+ON CONFLICT (code) DO NOTHING;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS holds (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    sender_id uuid REFERENCES users(id) NOT NULL,
+    recipient_id uuid REFERENCES users(id) NOT NULL,
+    points INTEGER NOT NULL CHECK (points > 0),
+    captured_points INTEGER NOT NULL DEFAULT 0 CHECK (captured_points >= 0 AND captured_points <= points),
+    status VARCHAR (10) NOT NULL DEFAULT 'ACTIVE',
+    transaction_id uuid REFERENCES transactions(id),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS holds_active_idx ON holds (sender_id, expires_at) WHERE status = 'ACTIVE';
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS scheduled_transfers (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    sender_id uuid REFERENCES users(id) NOT NULL,
+    recipient_id uuid REFERENCES users(id) NOT NULL,
+    points INTEGER NOT NULL CHECK (points > 0),
+    rule text NOT NULL DEFAULT '',
+    status VARCHAR (10) NOT NULL DEFAULT 'ACTIVE',
+    next_run_at TIMESTAMP WITH TIME ZONE,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS scheduled_transfers_due_idx ON scheduled_transfers (next_run_at) WHERE status = 'ACTIVE' AND deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS scheduled_transfers_sender_idx ON scheduled_transfers (sender_id) WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS scheduled_transfer_executions (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    scheduled_transfer_id uuid REFERENCES scheduled_transfers(id) NOT NULL,
+    transaction_id uuid REFERENCES transactions(id),
+    status VARCHAR (10) NOT NULL,
+    error text NOT NULL DEFAULT '',
+    scheduled_for TIMESTAMP WITH TIME ZONE NOT NULL,
+    executed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS scheduled_transfer_executions_schedule_idx ON scheduled_transfer_executions (scheduled_transfer_id, executed_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS referral_rewards;
+
+DROP TABLE IF EXISTS reward_rules;
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS reward_rules (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    name text NOT NULL,
+    kind VARCHAR (20) NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    active BOOLEAN NOT NULL DEFAULT true,
+    points INTEGER NOT NULL DEFAULT 0,
+    every INTEGER NOT NULL DEFAULT 0,
+    milestones jsonb NOT NULL DEFAULT '[]',
+    tiers jsonb NOT NULL DEFAULT '[]',
+    cap_points INTEGER NOT NULL DEFAULT 0,
+    cap_period VARCHAR (10) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS referral_rewards (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    referral_id uuid REFERENCES referrals(id) NOT NULL,
+    user_id uuid REFERENCES users(id) NOT NULL,
+    rule_id uuid REFERENCES reward_rules(id) NOT NULL,
+    rule_version INTEGER NOT NULL,
+    points INTEGER NOT NULL CHECK (points > 0),
+    journal_entry_id uuid REFERENCES journal_entries(id) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS referral_rewards_rule_user_idx ON referral_rewards (rule_id, user_id, created_at);
+
+-- the reward that used to be hard-coded: 50 points for every third referral
+INSERT INTO reward_rules (name, kind, points, every) VALUES ('Every third referral', 'MILESTONE', 50, 3);
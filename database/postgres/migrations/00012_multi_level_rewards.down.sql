@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS referrals_referee_idx;
+
+ALTER TABLE referral_rewards DROP COLUMN IF EXISTS level;
+
+ALTER TABLE reward_rules DROP COLUMN IF EXISTS level;
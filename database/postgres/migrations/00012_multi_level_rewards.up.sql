@@ -0,0 +1,5 @@
+ALTER TABLE reward_rules ADD COLUMN IF NOT EXISTS level INTEGER NOT NULL DEFAULT 1 CHECK (level > 0);
+
+ALTER TABLE referral_rewards ADD COLUMN IF NOT EXISTS level INTEGER NOT NULL DEFAULT 1;
+
+CREATE INDEX IF NOT EXISTS referrals_referee_idx ON referrals (referee_id) WHERE deleted_at IS NULL;
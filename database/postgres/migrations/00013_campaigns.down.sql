@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS campaign_redemptions;
+
+ALTER TABLE referrals DROP COLUMN IF EXISTS campaign_id;
+
+ALTER TABLE reward_rules DROP COLUMN IF EXISTS campaign_id;
+
+DROP TABLE IF EXISTS campaign_codes;
+
+DROP TABLE IF EXISTS campaigns;
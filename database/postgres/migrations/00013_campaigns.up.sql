@@ -0,0 +1,47 @@
+CREATE TABLE IF NOT EXISTS campaigns (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    name text NOT NULL,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    budget_points INTEGER NOT NULL CHECK (budget_points > 0),
+    spent_points INTEGER NOT NULL DEFAULT 0,
+    registrations INTEGER NOT NULL DEFAULT 0,
+    sponsor_user_id uuid REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT campaigns_window CHECK (ends_at > starts_at),
+    CONSTRAINT campaigns_budget CHECK (spent_points <= budget_points)
+);
+
+CREATE TABLE IF NOT EXISTS campaign_codes (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    campaign_id uuid REFERENCES campaigns(id) NOT NULL,
+    code VARCHAR (20) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- campaign codes are matched case-insensitively
+CREATE UNIQUE INDEX IF NOT EXISTS campaign_codes_code_idx ON campaign_codes (upper(code));
+
+ALTER TABLE reward_rules ADD COLUMN IF NOT EXISTS campaign_id uuid REFERENCES campaigns(id);
+
+ALTER TABLE referrals ADD COLUMN IF NOT EXISTS campaign_id uuid REFERENCES campaigns(id);
+
+CREATE TABLE IF NOT EXISTS campaign_redemptions (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    campaign_id uuid REFERENCES campaigns(id) NOT NULL,
+    code VARCHAR (20) NOT NULL,
+    user_id uuid REFERENCES users(id) NOT NULL,
+    beneficiary_id uuid REFERENCES users(id) NOT NULL,
+    referral_id uuid REFERENCES referrals(id),
+    rule_id uuid REFERENCES reward_rules(id),
+    rule_version INTEGER NOT NULL DEFAULT 0,
+    points INTEGER NOT NULL DEFAULT 0,
+    journal_entry_id uuid REFERENCES journal_entries(id),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS campaign_redemptions_campaign_idx ON campaign_redemptions (campaign_id, created_at);
+
+INSERT INTO ledger_accounts (code, type, allow_negative) VALUES ('system:campaigns', 'SYSTEM', true)
+ON CONFLICT (code) DO NOTHING;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS referral_codes_user_idx;
+
+DROP INDEX IF EXISTS referral_codes_vanity_idx;
+
+ALTER TABLE referral_codes DROP COLUMN IF EXISTS alias_expires_at;
+
+ALTER TABLE referral_codes DROP COLUMN IF EXISTS vanity;
@@ -0,0 +1,11 @@
+ALTER TABLE referral_codes ALTER COLUMN code TYPE VARCHAR (20);
+
+ALTER TABLE referral_codes ADD COLUMN IF NOT EXISTS vanity BOOLEAN NOT NULL DEFAULT false;
+
+-- a replaced code keeps referring new users to its owner until alias_expires_at
+ALTER TABLE referral_codes ADD COLUMN IF NOT EXISTS alias_expires_at TIMESTAMP WITH TIME ZONE;
+
+-- vanity codes are matched case-insensitively, retired ones are kept from reuse by the application
+CREATE UNIQUE INDEX IF NOT EXISTS referral_codes_vanity_idx ON referral_codes (upper(code)) WHERE vanity AND deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS referral_codes_user_idx ON referral_codes (user_id, created_at);
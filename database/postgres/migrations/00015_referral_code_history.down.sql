@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS referral_codes_active_user_idx;
+
+ALTER TABLE referral_codes DROP COLUMN IF EXISTS retired_reason;
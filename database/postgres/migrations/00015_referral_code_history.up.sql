@@ -0,0 +1,7 @@
+-- why a code stopped being the active code of its user: REPLACED by a vanity code, ROTATED or REVOKED
+ALTER TABLE referral_codes ADD COLUMN IF NOT EXISTS retired_reason VARCHAR (10);
+
+UPDATE referral_codes SET retired_reason = 'REPLACED' WHERE deleted_at IS NOT NULL AND retired_reason IS NULL;
+
+-- a user has at most one active code
+CREATE UNIQUE INDEX IF NOT EXISTS referral_codes_active_user_idx ON referral_codes (user_id) WHERE deleted_at IS NULL;
@@ -0,0 +1,7 @@
+ALTER TABLE referral_codes DROP COLUMN IF EXISTS allowed_domains;
+
+ALTER TABLE referral_codes DROP COLUMN IF EXISTS expires_at;
+
+ALTER TABLE referral_codes DROP COLUMN IF EXISTS redemptions;
+
+ALTER TABLE referral_codes DROP COLUMN IF EXISTS max_redemptions;
@@ -0,0 +1,12 @@
+-- limits on who may register with a code and how often, a zero max_redemptions is unlimited
+ALTER TABLE referral_codes ADD COLUMN IF NOT EXISTS max_redemptions INTEGER NOT NULL DEFAULT 0 CHECK (max_redemptions >= 0);
+
+ALTER TABLE referral_codes ADD COLUMN IF NOT EXISTS redemptions INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE referral_codes ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE referral_codes ADD COLUMN IF NOT EXISTS allowed_domains text[] NOT NULL DEFAULT '{}';
+
+UPDATE referral_codes SET redemptions = (SELECT COUNT(*) FROM referrals
+WHERE referrals.referrer_id = referral_codes.user_id AND referrals.campaign_id IS NULL)
+WHERE deleted_at IS NULL;
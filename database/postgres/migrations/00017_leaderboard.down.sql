@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS referral_rewards_created_at_idx;
+
+DROP INDEX IF EXISTS referrals_created_at_idx;
+
+DROP TABLE IF EXISTS leaderboard_refreshes;
+
+DROP TABLE IF EXISTS leaderboard_entries;
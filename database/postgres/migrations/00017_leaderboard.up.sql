@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS leaderboard_entries (
+    period VARCHAR (5) NOT NULL,
+    metric VARCHAR (10) NOT NULL,
+    user_id uuid REFERENCES users(id) NOT NULL,
+    rank INTEGER NOT NULL,
+    referrals INTEGER NOT NULL,
+    points INTEGER NOT NULL,
+    reached_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (period, metric, user_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS leaderboard_entries_rank_idx ON leaderboard_entries (period, metric, rank);
+
+CREATE TABLE IF NOT EXISTS leaderboard_refreshes (
+    period VARCHAR (5) PRIMARY KEY,
+    refreshed_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS referrals_created_at_idx ON referrals (created_at);
+
+CREATE INDEX IF NOT EXISTS referral_rewards_created_at_idx ON referral_rewards (created_at);
@@ -0,0 +1,17 @@
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
+
+DELETE FROM referral_rewards WHERE journal_entry_id IS NULL;
+
+ALTER TABLE referral_rewards DROP CONSTRAINT IF EXISTS referral_rewards_released_check;
+
+ALTER TABLE referral_rewards ALTER COLUMN journal_entry_id SET NOT NULL;
+
+ALTER TABLE referral_rewards DROP COLUMN IF EXISTS status;
+
+DROP INDEX IF EXISTS referrals_pending_idx;
+
+ALTER TABLE referrals DROP COLUMN IF EXISTS expires_at;
+
+ALTER TABLE referrals DROP COLUMN IF EXISTS qualified_at;
+
+ALTER TABLE referrals DROP COLUMN IF EXISTS status;
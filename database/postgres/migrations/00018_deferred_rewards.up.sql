@@ -0,0 +1,19 @@
+-- referrals made before rewards could be deferred were rewarded at registration, so they count as qualified
+ALTER TABLE referrals ADD COLUMN IF NOT EXISTS status VARCHAR (10) NOT NULL DEFAULT 'QUALIFIED';
+
+ALTER TABLE referrals ADD COLUMN IF NOT EXISTS qualified_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE referrals ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE referrals SET qualified_at = created_at WHERE qualified_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS referrals_pending_idx ON referrals (expires_at) WHERE status = 'PENDING' AND deleted_at IS NULL;
+
+-- pending rewards are only posted to the ledger once the referee qualifies
+ALTER TABLE referral_rewards ADD COLUMN IF NOT EXISTS status VARCHAR (10) NOT NULL DEFAULT 'RELEASED';
+
+ALTER TABLE referral_rewards ALTER COLUMN journal_entry_id DROP NOT NULL;
+
+ALTER TABLE referral_rewards ADD CONSTRAINT referral_rewards_released_check CHECK (status <> 'RELEASED' OR journal_entry_id IS NOT NULL);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE;
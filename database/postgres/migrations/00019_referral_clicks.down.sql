@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS referrals_referral_code_idx;
+
+ALTER TABLE referrals DROP COLUMN IF EXISTS referral_code_id;
+
+DROP TABLE IF EXISTS referral_clicks;
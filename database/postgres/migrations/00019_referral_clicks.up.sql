@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS referral_clicks (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    referral_code_id uuid REFERENCES referral_codes(id) NOT NULL,
+    ip_hash VARCHAR (64) NOT NULL,
+    user_agent text NOT NULL DEFAULT '',
+    utm_source text NOT NULL DEFAULT '',
+    utm_medium text NOT NULL DEFAULT '',
+    utm_campaign text NOT NULL DEFAULT '',
+    utm_term text NOT NULL DEFAULT '',
+    utm_content text NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS referral_clicks_code_idx ON referral_clicks (referral_code_id, created_at);
+
+-- the code a referee registered with, registrations are counted against it
+ALTER TABLE referrals ADD COLUMN IF NOT EXISTS referral_code_id uuid REFERENCES referral_codes(id);
+
+-- existing referrals are attributed to the code the referrer had when they were made
+UPDATE referrals SET referral_code_id = (
+    SELECT referral_codes.id FROM referral_codes WHERE referral_codes.user_id = referrals.referrer_id
+    AND referral_codes.created_at <= referrals.created_at ORDER BY referral_codes.created_at DESC LIMIT 1
+) WHERE campaign_id IS NULL AND referral_code_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS referrals_referral_code_idx ON referrals (referral_code_id) WHERE referral_code_id IS NOT NULL;
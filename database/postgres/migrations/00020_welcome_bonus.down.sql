@@ -0,0 +1,3 @@
+ALTER TABLE campaigns DROP COLUMN IF EXISTS welcome_points;
+
+ALTER TABLE users DROP COLUMN IF EXISTS country;
@@ -0,0 +1,8 @@
+-- ISO 3166-1 alpha-2, picks the welcome bonus for the user's country
+ALTER TABLE users ADD COLUMN IF NOT EXISTS country VARCHAR (2);
+
+-- overrides the configured welcome bonus for users registering with the campaign's codes, NULL keeps it
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS welcome_points INTEGER CHECK (welcome_points >= 0);
+
+INSERT INTO ledger_accounts (code, type, allow_negative) VALUES ('system:welcome', 'SYSTEM', true)
+ON CONFLICT (code) DO NOTHING;
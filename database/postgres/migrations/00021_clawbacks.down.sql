@@ -0,0 +1,5 @@
+DELETE FROM ledger_accounts WHERE type = 'DEBT' AND NOT EXISTS (SELECT 1 FROM postings WHERE postings.account_id = ledger_accounts.id);
+
+ALTER TABLE referral_rewards DROP COLUMN IF EXISTS clawback_id;
+
+DROP TABLE IF EXISTS clawbacks;
@@ -0,0 +1,21 @@
+-- CLAWED_BACK doesn't fit the original status columns
+ALTER TABLE referrals ALTER COLUMN status TYPE VARCHAR (20);
+
+ALTER TABLE referral_rewards ALTER COLUMN status TYPE VARCHAR (20);
+
+CREATE TABLE IF NOT EXISTS clawbacks (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    referral_id uuid NOT NULL UNIQUE REFERENCES referrals(id),
+    reason VARCHAR (20) NOT NULL,
+    note text NOT NULL DEFAULT '',
+    points INTEGER NOT NULL DEFAULT 0,
+    debt INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE referral_rewards ADD COLUMN IF NOT EXISTS clawback_id uuid REFERENCES clawbacks(id);
+
+-- what a user owes for clawed back rewards they already spent, settled out of the points they earn next
+INSERT INTO ledger_accounts (user_id, code, type, allow_negative)
+    SELECT user_id, 'user:' || user_id || ':debt', 'DEBT', true FROM user_points WHERE deleted_at IS NULL
+ON CONFLICT (code) DO NOTHING;
@@ -0,0 +1,9 @@
+DROP TRIGGER IF EXISTS referrals_no_cycle ON referrals;
+
+DROP FUNCTION IF EXISTS referrals_prevent_cycle();
+
+ALTER TABLE referrals DROP CONSTRAINT IF EXISTS referrals_no_self_referral;
+
+DROP INDEX IF EXISTS referrals_referee_unique_idx;
+
+CREATE INDEX IF NOT EXISTS referrals_referee_idx ON referrals (referee_id) WHERE deleted_at IS NULL;
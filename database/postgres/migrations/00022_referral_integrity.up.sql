@@ -0,0 +1,50 @@
+-- a user keeps their first referrer, self-referrals and later referrals are dropped
+UPDATE referrals SET deleted_at = CURRENT_TIMESTAMP
+WHERE deleted_at IS NULL AND (referrer_id = referee_id OR EXISTS (
+    SELECT 1 FROM referrals earlier WHERE earlier.referee_id = referrals.referee_id AND earlier.deleted_at IS NULL
+    AND earlier.referrer_id <> earlier.referee_id AND (earlier.created_at, earlier.id) < (referrals.created_at, referrals.id)
+));
+
+UPDATE user_points SET number_of_referred_users = referred.count
+FROM (SELECT referrer_id, COUNT(*) FILTER (WHERE deleted_at IS NULL) AS count FROM referrals GROUP BY referrer_id) referred
+WHERE referred.referrer_id = user_points.user_id AND user_points.number_of_referred_users <> referred.count;
+
+DROP INDEX IF EXISTS referrals_referee_idx;
+
+CREATE UNIQUE INDEX IF NOT EXISTS referrals_referee_unique_idx ON referrals (referee_id) WHERE deleted_at IS NULL;
+
+-- soft-deleted self-referrals are kept, so only new rows are checked
+ALTER TABLE referrals ADD CONSTRAINT referrals_no_self_referral CHECK (referrer_id <> referee_id) NOT VALID;
+
+-- referrals_prevent_cycle rejects a referral whose referee is already up its referrer's chain.
+-- Referrals are checked one at a time so two of them can't close a loop concurrently.
+CREATE OR REPLACE FUNCTION referrals_prevent_cycle() RETURNS trigger AS $$
+BEGIN
+    IF NEW.deleted_at IS NOT NULL OR NEW.referrer_id = NEW.referee_id THEN
+        RETURN NEW;
+    END IF;
+
+    PERFORM pg_advisory_xact_lock(hashtext('referrals_no_cycle'));
+
+    IF EXISTS (
+        WITH RECURSIVE ancestors (user_id, path) AS (
+            SELECT NEW.referrer_id, ARRAY[NEW.referrer_id]
+            UNION ALL
+            SELECT referrals.referrer_id, ancestors.path || referrals.referrer_id
+            FROM ancestors JOIN referrals ON referrals.referee_id = ancestors.user_id AND referrals.deleted_at IS NULL
+            WHERE NOT referrals.referrer_id = ANY (ancestors.path)
+        )
+        SELECT 1 FROM ancestors WHERE user_id = NEW.referee_id
+    ) THEN
+        RAISE EXCEPTION 'referring % to % would form a cycle', NEW.referee_id, NEW.referrer_id
+            USING ERRCODE = 'check_violation', CONSTRAINT = 'referrals_no_cycle';
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS referrals_no_cycle ON referrals;
+
+CREATE TRIGGER referrals_no_cycle BEFORE INSERT OR UPDATE OF referrer_id, referee_id, deleted_at ON referrals
+    FOR EACH ROW EXECUTE FUNCTION referrals_prevent_cycle();
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS registration_signals (
+    user_id uuid PRIMARY KEY REFERENCES users(id),
+    referrer_id uuid REFERENCES users(id),
+    referral_code_id uuid REFERENCES referral_codes(id),
+    ip_hash text NOT NULL DEFAULT '',
+    user_agent text NOT NULL DEFAULT '',
+    device_hash text NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS registration_signals_referrer_idx ON registration_signals (referrer_id, created_at) WHERE referrer_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS registration_signals_device_idx ON registration_signals (device_hash, created_at) WHERE device_hash <> '';
+
+CREATE TABLE IF NOT EXISTS fraud_reviews (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    referral_id uuid NOT NULL UNIQUE REFERENCES referrals(id),
+    score INTEGER NOT NULL,
+    hits jsonb NOT NULL DEFAULT '[]',
+    status VARCHAR (10) NOT NULL,
+    note text NOT NULL DEFAULT '',
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS fraud_reviews_status_idx ON fraud_reviews (status, created_at);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS users_duplicate_of_idx;
+
+DROP INDEX IF EXISTS users_canonical_email_unique_idx;
+
+ALTER TABLE users DROP COLUMN IF EXISTS duplicate_of;
+
+ALTER TABLE users DROP COLUMN IF EXISTS canonical_email;
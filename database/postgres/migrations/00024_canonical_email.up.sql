@@ -0,0 +1,47 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS canonical_email text;
+
+-- duplicate_of is set on the accounts that shared a mailbox with an earlier one when canonical emails were
+-- introduced, they're kept out of the uniqueness check and show up in the duplicate accounts report
+ALTER TABLE users ADD COLUMN IF NOT EXISTS duplicate_of uuid REFERENCES users(id);
+
+-- canonicalize_email follows core.CanonicalEmail, the application computes canonical emails from here on
+CREATE OR REPLACE FUNCTION canonicalize_email(email text) RETURNS text AS $$
+DECLARE
+    address text := lower(btrim(email));
+    sep integer;
+    local_part text;
+    domain_part text;
+BEGIN
+    IF strpos(address, '@') = 0 THEN
+        RETURN address;
+    END IF;
+
+    sep := length(address) - strpos(reverse(address), '@') + 1;
+    local_part := left(address, sep - 1);
+    domain_part := substr(address, sep + 1);
+
+    IF domain_part = 'googlemail.com' THEN
+        domain_part := 'gmail.com';
+    END IF;
+    local_part := split_part(local_part, '+', 1);
+    IF domain_part = 'gmail.com' THEN
+        local_part := replace(local_part, '.', '');
+    END IF;
+
+    RETURN local_part || '@' || domain_part;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+UPDATE users SET canonical_email = canonicalize_email(email) WHERE canonical_email IS NULL;
+
+UPDATE users SET duplicate_of = first.id
+FROM (SELECT DISTINCT ON (canonical_email) id, canonical_email FROM users ORDER BY canonical_email, created_at, id) first
+WHERE users.canonical_email = first.canonical_email AND users.id <> first.id;
+
+DROP FUNCTION canonicalize_email(text);
+
+ALTER TABLE users ALTER COLUMN canonical_email SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_canonical_email_unique_idx ON users (canonical_email) WHERE duplicate_of IS NULL;
+
+CREATE INDEX IF NOT EXISTS users_duplicate_of_idx ON users (duplicate_of) WHERE duplicate_of IS NOT NULL;
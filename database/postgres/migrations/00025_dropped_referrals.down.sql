@@ -0,0 +1,5 @@
+-- a dropped referral was last pending or qualified, those that were still pending had no released rewards
+UPDATE referrals SET status = CASE WHEN EXISTS (
+    SELECT 1 FROM referral_rewards WHERE referral_rewards.referral_id = referrals.id AND referral_rewards.status = 'RELEASED'
+) THEN 'QUALIFIED' ELSE 'PENDING' END
+WHERE status = 'DROPPED';
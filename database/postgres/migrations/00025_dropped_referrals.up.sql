@@ -0,0 +1,5 @@
+-- 00022 soft-deleted duplicate and self-referrals without taking back the rewards they released. They are
+-- the only referrals soft-deleted without being clawed back, DROPPED tells them apart so their rewards
+-- can be reported and clawed back by hand.
+UPDATE referrals SET status = 'DROPPED'
+WHERE deleted_at IS NOT NULL AND status <> 'CLAWED_BACK';
@@ -0,0 +1,39 @@
+CREATE OR REPLACE FUNCTION canonicalize_email(email text) RETURNS text AS $$
+DECLARE
+    address text := lower(btrim(email));
+    sep integer;
+    local_part text;
+    domain_part text;
+BEGIN
+    IF strpos(address, '@') = 0 THEN
+        RETURN address;
+    END IF;
+
+    sep := length(address) - strpos(reverse(address), '@') + 1;
+    local_part := left(address, sep - 1);
+    domain_part := substr(address, sep + 1);
+
+    IF domain_part = 'googlemail.com' THEN
+        domain_part := 'gmail.com';
+    END IF;
+    local_part := split_part(local_part, '+', 1);
+    IF domain_part = 'gmail.com' THEN
+        local_part := replace(local_part, '.', '');
+    END IF;
+
+    RETURN local_part || '@' || domain_part;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+-- dropping every tag again can make accounts share a mailbox, the later ones become duplicates
+DROP INDEX IF EXISTS users_canonical_email_unique_idx;
+
+UPDATE users SET canonical_email = canonicalize_email(email) WHERE canonical_email <> canonicalize_email(email);
+
+UPDATE users SET duplicate_of = NULLIF(first.id, users.id)
+FROM (SELECT DISTINCT ON (canonical_email) id, canonical_email FROM users ORDER BY canonical_email, created_at, id) first
+WHERE users.canonical_email = first.canonical_email AND users.duplicate_of IS DISTINCT FROM NULLIF(first.id, users.id);
+
+DROP FUNCTION canonicalize_email(text);
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_canonical_email_unique_idx ON users (canonical_email) WHERE duplicate_of IS NULL;
@@ -0,0 +1,42 @@
+-- 00024 dropped "+" tags from every address, they're now only dropped for the providers known to deliver
+-- tagged addresses to the untagged mailbox.
+-- canonicalize_email follows core.CanonicalEmail, the application computes canonical emails from here on
+CREATE OR REPLACE FUNCTION canonicalize_email(email text) RETURNS text AS $$
+DECLARE
+    address text := lower(btrim(email));
+    sep integer;
+    local_part text;
+    domain_part text;
+BEGIN
+    IF strpos(address, '@') = 0 THEN
+        RETURN address;
+    END IF;
+
+    sep := length(address) - strpos(reverse(address), '@') + 1;
+    local_part := left(address, sep - 1);
+    domain_part := substr(address, sep + 1);
+
+    IF domain_part = 'googlemail.com' THEN
+        domain_part := 'gmail.com';
+    END IF;
+    IF domain_part IN ('gmail.com', 'outlook.com', 'hotmail.com', 'live.com', 'icloud.com', 'me.com', 'mac.com',
+        'fastmail.com', 'protonmail.com', 'proton.me', 'pm.me') THEN
+        local_part := split_part(local_part, '+', 1);
+    END IF;
+    IF domain_part = 'gmail.com' THEN
+        local_part := replace(local_part, '.', '');
+    END IF;
+
+    RETURN local_part || '@' || domain_part;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+-- keeping tags only tells addresses apart, so no two accounts come to share a canonical email
+UPDATE users SET canonical_email = canonicalize_email(email) WHERE canonical_email <> canonicalize_email(email);
+
+-- accounts that no longer share their original's mailbox are the first of their own, or a duplicate of that
+UPDATE users SET duplicate_of = NULLIF(first.id, users.id)
+FROM (SELECT DISTINCT ON (canonical_email) id, canonical_email FROM users ORDER BY canonical_email, created_at, id) first
+WHERE users.canonical_email = first.canonical_email AND users.duplicate_of IS NOT NULL;
+
+DROP FUNCTION canonicalize_email(text);
@@ -0,0 +1 @@
+ALTER TABLE referral_rewards DROP COLUMN IF EXISTS milestone;
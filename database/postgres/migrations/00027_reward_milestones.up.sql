@@ -0,0 +1,10 @@
+-- the referral count a milestone reward was granted for, zero for rewards that don't depend on the count
+ALTER TABLE referral_rewards ADD COLUMN IF NOT EXISTS milestone INTEGER NOT NULL DEFAULT 0;
+
+-- the referrer's count was the position of the referral among theirs when it was made
+UPDATE referral_rewards SET milestone = positions.position
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY referrer_id ORDER BY created_at, id) AS position FROM referrals
+) positions, reward_rules
+WHERE positions.id = referral_rewards.referral_id AND reward_rules.id = referral_rewards.rule_id
+AND reward_rules.kind = 'MILESTONE' AND reward_rules.campaign_id IS NULL AND referral_rewards.level = 1;
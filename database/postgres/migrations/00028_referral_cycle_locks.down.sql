@@ -0,0 +1,27 @@
+CREATE OR REPLACE FUNCTION referrals_prevent_cycle() RETURNS trigger AS $$
+BEGIN
+    IF NEW.deleted_at IS NOT NULL OR NEW.referrer_id = NEW.referee_id THEN
+        RETURN NEW;
+    END IF;
+
+    PERFORM pg_advisory_xact_lock(hashtext('referrals_no_cycle'));
+
+    IF EXISTS (
+        WITH RECURSIVE ancestors (user_id, path) AS (
+            SELECT NEW.referrer_id, ARRAY[NEW.referrer_id]
+            UNION ALL
+            SELECT referrals.referrer_id, ancestors.path || referrals.referrer_id
+            FROM ancestors JOIN referrals ON referrals.referee_id = ancestors.user_id AND referrals.deleted_at IS NULL
+            WHERE NOT referrals.referrer_id = ANY (ancestors.path)
+        )
+        SELECT 1 FROM ancestors WHERE user_id = NEW.referee_id
+    ) THEN
+        RAISE EXCEPTION 'referring % to % would form a cycle', NEW.referee_id, NEW.referrer_id
+            USING ERRCODE = 'check_violation', CONSTRAINT = 'referrals_no_cycle';
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP FUNCTION IF EXISTS referrals_chain_root(uuid);
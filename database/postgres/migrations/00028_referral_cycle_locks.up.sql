@@ -0,0 +1,55 @@
+-- referrals_chain_root returns the top of the referral chain the user is in, the user themselves when nobody
+-- referred them.
+CREATE OR REPLACE FUNCTION referrals_chain_root(uuid) RETURNS uuid AS $$
+    WITH RECURSIVE ancestors (user_id, path) AS (
+        SELECT $1, ARRAY[$1]
+        UNION ALL
+        SELECT referrals.referrer_id, ancestors.path || referrals.referrer_id
+        FROM ancestors JOIN referrals ON referrals.referee_id = ancestors.user_id AND referrals.deleted_at IS NULL
+        WHERE NOT referrals.referrer_id = ANY (ancestors.path)
+    )
+    SELECT ancestors.user_id FROM ancestors ORDER BY array_length(ancestors.path, 1) DESC LIMIT 1;
+$$ LANGUAGE sql STABLE;
+
+-- referrals_prevent_cycle rejects a referral whose referee is already up its referrer's chain.
+-- A referral joins the referee's chain, topped by the referee, to the referrer's, so both tops are locked and
+-- referrals can only close a loop one at a time within the chains they join. The referrer's chain may be
+-- joined to another while its top is waited on, the lock follows it to the new top.
+CREATE OR REPLACE FUNCTION referrals_prevent_cycle() RETURNS trigger AS $$
+DECLARE
+    root uuid;
+    top uuid;
+BEGIN
+    IF NEW.deleted_at IS NOT NULL OR NEW.referrer_id = NEW.referee_id THEN
+        RETURN NEW;
+    END IF;
+
+    -- in the same order everywhere so referrals joining the same two chains don't deadlock
+    root := referrals_chain_root(NEW.referrer_id);
+    PERFORM pg_advisory_xact_lock(hashtext('referrals_no_cycle'), LEAST(hashtext(NEW.referee_id::text), hashtext(root::text)));
+    PERFORM pg_advisory_xact_lock(hashtext('referrals_no_cycle'), GREATEST(hashtext(NEW.referee_id::text), hashtext(root::text)));
+
+    LOOP
+        top := referrals_chain_root(NEW.referrer_id);
+        EXIT WHEN top = root;
+        root := top;
+        PERFORM pg_advisory_xact_lock(hashtext('referrals_no_cycle'), hashtext(root::text));
+    END LOOP;
+
+    IF EXISTS (
+        WITH RECURSIVE ancestors (user_id, path) AS (
+            SELECT NEW.referrer_id, ARRAY[NEW.referrer_id]
+            UNION ALL
+            SELECT referrals.referrer_id, ancestors.path || referrals.referrer_id
+            FROM ancestors JOIN referrals ON referrals.referee_id = ancestors.user_id AND referrals.deleted_at IS NULL
+            WHERE NOT referrals.referrer_id = ANY (ancestors.path)
+        )
+        SELECT 1 FROM ancestors WHERE user_id = NEW.referee_id
+    ) THEN
+        RAISE EXCEPTION 'referring % to % would form a cycle', NEW.referee_id, NEW.referrer_id
+            USING ERRCODE = 'check_violation', CONSTRAINT = 'referrals_no_cycle';
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
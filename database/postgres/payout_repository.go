@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+)
+
+type PayoutRepository struct {
+	client *Client
+}
+
+func NewPayoutRepository(client *Client) *PayoutRepository {
+	return &PayoutRepository{
+		client: client,
+	}
+}
+
+func (p *PayoutRepository) CreateRecipient(ctx context.Context, recipient *core.PayoutRecipient) error {
+	tx, err := p.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(ctx,
+		`INSERT INTO payout_recipients (user_id, account_number, bank_code, account_name, recipient_code) VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		recipient.UserID, recipient.AccountNumber, recipient.BankCode, recipient.AccountName, recipient.RecipientCode,
+	)
+
+	return row.Scan(&recipient.ID, &recipient.CreatedAt)
+}
+
+func (p *PayoutRepository) FindRecipient(ctx context.Context, userID, bankCode, accountNumber string) (*core.PayoutRecipient, error) {
+	tx, err := p.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	row := tx.QueryRow(ctx, `SELECT id, user_id, account_number, bank_code, account_name, recipient_code, created_at
+	FROM payout_recipients WHERE user_id = $1 AND bank_code = $2 AND account_number = $3`, userID, bankCode, accountNumber)
+
+	recipient := &core.PayoutRecipient{}
+	err = row.Scan(&recipient.ID, &recipient.UserID, &recipient.AccountNumber, &recipient.BankCode, &recipient.AccountName,
+		&recipient.RecipientCode, &recipient.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return recipient, nil
+}
+
+func (p *PayoutRepository) CreatePayout(ctx context.Context, payout *core.Payout) error {
+	tx, err := p.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(ctx,
+		`INSERT INTO payouts (user_id, recipient_id, points, amount_kobo, currency, status, reference) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at`,
+		payout.UserID, payout.RecipientID, payout.Points, payout.AmountKobo, payout.Currency, payout.Status, payout.Reference,
+	)
+
+	return row.Scan(&payout.ID, &payout.CreatedAt, &payout.UpdatedAt)
+}
+
+// FindPayoutByReference locks the payout for the rest of the surrounding transaction so
+// concurrent status updates for the same transfer are applied one after the other.
+func (p *PayoutRepository) FindPayoutByReference(ctx context.Context, reference string) (*core.Payout, error) {
+	tx, err := p.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	row := tx.QueryRow(ctx, `SELECT id, user_id, recipient_id, points, amount_kobo, currency, status, reference, transfer_code,
+	failure_reason, created_at, updated_at FROM payouts WHERE reference = $1 FOR UPDATE`, reference)
+
+	payout := &core.Payout{}
+	err = row.Scan(&payout.ID, &payout.UserID, &payout.RecipientID, &payout.Points, &payout.AmountKobo, &payout.Currency, &payout.Status,
+		&payout.Reference, &payout.TransferCode, &payout.FailureReason, &payout.CreatedAt, &payout.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return payout, nil
+}
+
+func (p *PayoutRepository) UpdatePayout(ctx context.Context, payout *core.Payout) error {
+	tx, err := p.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(ctx,
+		`UPDATE payouts SET status = $1, transfer_code = $2, failure_reason = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $4
+		RETURNING updated_at`,
+		payout.Status, payout.TransferCode, payout.FailureReason, payout.ID,
+	)
+
+	return row.Scan(&payout.UpdatedAt)
+}
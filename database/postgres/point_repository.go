@@ -2,42 +2,95 @@ package postgres
 
 import (
 	"context"
+	"time"
 
 	core "github.com/Qalifah/aboki-africa-assessment"
+
+	"github.com/jackc/pgx/v4"
 )
 
 type PointRepository struct {
 	client *Client
+	ledger *LedgerRepository
 }
 
 func NewPointRepository(client *Client) *PointRepository {
 	return &PointRepository{
 		client: client,
+		ledger: NewLedgerRepository(client),
 	}
 }
 
-func(p *PointRepository) CreatePoint(ctx context.Context, point *core.Point) error {
+// pointProjection reads a user's points and bonus from their ledger accounts.
+const pointProjection = `SELECT user_points.id, user_points.user_id, COALESCE(points.balance, 0), user_points.number_of_referred_users,
+	COALESCE(bonus.balance, 0), user_points.paid, user_points.created_at, user_points.updated_at FROM user_points
+	LEFT JOIN ledger_accounts points ON points.user_id = user_points.user_id AND points.type = 'POINTS'
+	LEFT JOIN ledger_accounts bonus ON bonus.user_id = user_points.user_id AND bonus.type = 'BONUS'`
+
+// CreatePoint opens the user's ledger accounts. Non-zero points or bonus on the input
+// are booked as opening balances.
+func (p *PointRepository) CreatePoint(ctx context.Context, point *core.Point) error {
+	return p.client.WithTx(ctx, func(ctx context.Context) error {
+		tx, err := p.client.GetTx(ctx)
+		if err != nil {
+			return err
+		}
+
+		row := tx.QueryRow(ctx,
+			"INSERT INTO user_points (user_id) VALUES ($1) RETURNING id", point.UserID,
+		)
+
+		if err = row.Scan(&point.ID); err != nil {
+			return err
+		}
+
+		if err = p.ledger.CreateUserAccounts(ctx, point.UserID); err != nil {
+			return err
+		}
+
+		if point.Points != 0 {
+			entry := core.NewJournalEntry(core.EntryTypeOpening, "", core.SystemOpeningBalanceAccount, core.UserPointsAccount(point.UserID), point.Points)
+			if err = p.ledger.PostEntry(ctx, entry); err != nil {
+				return err
+			}
+		}
+
+		if point.Bonus != 0 {
+			entry := core.NewJournalEntry(core.EntryTypeOpening, "", core.SystemOpeningBalanceAccount, core.UserBonusAccount(point.UserID), point.Bonus)
+			if err = p.ledger.PostEntry(ctx, entry); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (p *PointRepository) FindPointByUserID(ctx context.Context, userID string) (*core.Point, error) {
 	tx, err := p.client.GetTx(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	row := tx.QueryRow(ctx, 
-		"INSERT INTO user_points (user_id) VALUES ($1) RETURNING id", point.UserID,
-	)
+	row := tx.QueryRow(ctx, pointProjection+" WHERE user_points.user_id = $1 AND user_points.deleted_at IS NULL", userID)
 
-	err = row.Scan(&point.ID)
+	point := &core.Point{}
+	err = row.Scan(&point.ID, &point.UserID, &point.Points, &point.NumberOfReferredUsers, &point.Bonus, &point.Paid, &point.CreatedAt, &point.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
 
-	return err
+	return point, nil
 }
 
-func(p *PointRepository) FindPointByUserID(ctx context.Context, userID string) (*core.Point, error) {
+// LockPoint finds the user's point and locks it until the surrounding transaction ends.
+func (p *PointRepository) LockPoint(ctx context.Context, userID string) (*core.Point, error) {
 	tx, err := p.client.GetTx(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	row := tx.QueryRow(ctx, "SELECT * FROM user_points WHERE user_id = $1 AND deleted_at IS NULL", userID)
+	row := tx.QueryRow(ctx, pointProjection+" WHERE user_points.user_id = $1 AND user_points.deleted_at IS NULL FOR UPDATE OF user_points", userID)
 
 	point := &core.Point{}
 	err = row.Scan(&point.ID, &point.UserID, &point.Points, &point.NumberOfReferredUsers, &point.Bonus, &point.Paid, &point.CreatedAt, &point.UpdatedAt)
@@ -48,19 +101,35 @@ func(p *PointRepository) FindPointByUserID(ctx context.Context, userID string) (
 	return point, nil
 }
 
-func(p *PointRepository) UpdatePoint(ctx context.Context, point *core.Point) error {
+// UpdatePoint persists the referral counter and paid flag. Points and bonus are only ever
+// changed by posting journal entries.
+func (p *PointRepository) UpdatePoint(ctx context.Context, point *core.Point) error {
 	tx, err := p.client.GetTx(ctx)
 	if err != nil {
 		return err
 	}
 
-	_, err = tx.Exec(ctx, "UPDATE user_points SET updated_at = CURRENT_TIMESTAMP AND points = $1 AND bonus = $2 AND number_of_referred_users = $3 WHERE id = $4 AND deleted_at IS NULL",
-		point.Points, point.Bonus, point.NumberOfReferredUsers, point.ID,
+	_, err = tx.Exec(ctx, "UPDATE user_points SET updated_at = CURRENT_TIMESTAMP, number_of_referred_users = $1, paid = $2 WHERE id = $3 AND deleted_at IS NULL",
+		point.NumberOfReferredUsers, point.Paid, point.ID,
 	)
 
 	return err
 }
 
+// spendableBalance computes the balance GetPointsBalance returns for the owner of points_account, for
+// queries that filter users by it.
+const spendableBalance = `points_account.balance
+	- COALESCE((SELECT SUM(payouts.points) FROM payouts WHERE payouts.user_id = points_account.user_id AND payouts.status = 'PENDING'), 0)
+	- COALESCE((SELECT SUM(holds.points) FROM holds WHERE holds.sender_id = points_account.user_id AND holds.status = 'ACTIVE'
+	AND holds.expires_at > CURRENT_TIMESTAMP), 0)
+	- COALESCE((SELECT SUM(point_lots.remaining) FROM point_lots WHERE point_lots.user_id = points_account.user_id
+	AND point_lots.remaining > 0 AND point_lots.expires_at <= CURRENT_TIMESTAMP), 0)
+	+ COALESCE((SELECT debt.balance FROM ledger_accounts debt WHERE debt.user_id = points_account.user_id AND debt.type = 'DEBT'), 0)`
+
+// GetPointsBalance returns the spendable balance of the user's points account, leaving out
+// points that are on their way out in a pending payout or reserved by an active hold, lots that
+// expired but weren't written off yet and the points the user owes, which makes it negative while
+// they owe more than they have.
 func (u *PointRepository) GetPointsBalance(ctx context.Context, userID string) (int, error) {
 	tx, err := u.client.GetTx(ctx)
 	if err != nil {
@@ -68,9 +137,83 @@ func (u *PointRepository) GetPointsBalance(ctx context.Context, userID string) (
 	}
 
 	var balance int
-	row := tx.QueryRow(ctx, "SELECT points from user_points WHERE user_id = $1 AND deleted_at IS NULL", userID)
+	row := tx.QueryRow(ctx, "SELECT "+spendableBalance+" FROM ledger_accounts points_account WHERE points_account.code = $1",
+		core.UserPointsAccount(userID))
 	if err := row.Scan(&balance); err != nil {
 		return 0, err
 	}
 	return balance, nil
-}
\ No newline at end of file
+}
+
+const lotColumns = "id, user_id, COALESCE(journal_entry_id::text, ''), source, points, remaining, expires_at, created_at"
+
+func scanLot(row pgx.Row) (*core.PointLot, error) {
+	lot := &core.PointLot{}
+	err := row.Scan(&lot.ID, &lot.UserID, &lot.JournalEntryID, &lot.Source, &lot.Points, &lot.Remaining, &lot.ExpiresAt, &lot.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return lot, nil
+}
+
+// FindLotByID locks the lot until the surrounding transaction ends.
+func (p *PointRepository) FindLotByID(ctx context.Context, id string) (*core.PointLot, error) {
+	tx, err := p.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanLot(tx.QueryRow(ctx, "SELECT "+lotColumns+" FROM point_lots WHERE id = $1 FOR UPDATE", id))
+}
+
+// ListExpiredLots returns up to limit lots that expired before the given time with points left in them.
+func (p *PointRepository) ListExpiredLots(ctx context.Context, before time.Time, limit int) ([]*core.PointLot, error) {
+	tx, err := p.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, "SELECT "+lotColumns+` FROM point_lots WHERE remaining > 0 AND expires_at <= $1
+	ORDER BY expires_at LIMIT $2`, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lots := []*core.PointLot{}
+	for rows.Next() {
+		lot, err := scanLot(rows)
+		if err != nil {
+			return nil, err
+		}
+		lots = append(lots, lot)
+	}
+
+	return lots, rows.Err()
+}
+
+// GetExpiringPoints sums the points of the user that expire before the given time by expiry date.
+func (p *PointRepository) GetExpiringPoints(ctx context.Context, userID string, before time.Time) ([]*core.ExpiringPoints, error) {
+	tx, err := p.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT SUM(remaining), expires_at FROM point_lots WHERE user_id = $1 AND remaining > 0
+	AND expires_at IS NOT NULL AND expires_at <= $2 GROUP BY expires_at ORDER BY expires_at`, userID, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	expiring := []*core.ExpiringPoints{}
+	for rows.Next() {
+		e := &core.ExpiringPoints{}
+		if err = rows.Scan(&e.Points, &e.ExpiresAt); err != nil {
+			return nil, err
+		}
+		expiring = append(expiring, e)
+	}
+
+	return expiring, rows.Err()
+}
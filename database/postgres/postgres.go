@@ -3,16 +3,16 @@ package postgres
 import (
 	"context"
 	"fmt"
-	"time"
 	"log"
 	"strings"
+	"time"
 
 	core "github.com/Qalifah/aboki-africa-assessment"
 	"github.com/Qalifah/aboki-africa-assessment/config"
 
-	pool "github.com/jackc/pgx/v4/pgxpool"
-	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	pool "github.com/jackc/pgx/v4/pgxpool"
 	"github.com/pkg/errors"
 )
 
@@ -55,7 +55,7 @@ func New(ctx context.Context, config *config.PostgresConfig) (*Client, error) {
 	pool, err := pool.ConnectConfig(ctx, cfg)
 	if err != nil {
 		log.Fatalf("pgx pool failed to connect: %v", err)
-		return nil, err 
+		return nil, err
 	}
 
 	return &Client{
@@ -74,8 +74,7 @@ func (c *Client) QueryRow(ctx context.Context, query string, args ...interface{}
 }
 
 func (c *Client) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
-	_, err := c.pool.Exec(ctx, query, args...)
-	return nil, err
+	return c.pool.Exec(ctx, query, args...)
 }
 
 func (c *Client) Commit(ctx context.Context) error {
@@ -86,7 +85,6 @@ func (c *Client) Rollback(ctx context.Context) error {
 	return nil
 }
 
-
 func (c *Client) BeginTx() (pgx.Tx, error) {
 	tx, err := c.pool.BeginTx(context.Background(), defaultOptions)
 	if err != nil {
@@ -95,7 +93,6 @@ func (c *Client) BeginTx() (pgx.Tx, error) {
 	return tx, nil
 }
 
-
 func (c *Client) GetTx(ctx context.Context) (Tx, error) {
 	tx := ctx.Value(core.TxContextKey)
 	if tx != nil {
@@ -104,6 +101,31 @@ func (c *Client) GetTx(ctx context.Context) (Tx, error) {
 	return c, nil
 }
 
+// WithTx runs fn inside the transaction carried by ctx, or inside a new one
+// that is committed when fn succeeds if ctx doesn't carry any.
+func (c *Client) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(core.TxContextKey) != nil {
+		return fn(ctx)
+	}
+
+	tx, err := c.BeginTx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err = fn(context.WithValue(ctx, core.TxContextKey, tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
 func IsDuplicateError(err error) bool {
 	return strings.Contains(err.Error(), "duplicate key value violates unique constraint")
-}
\ No newline at end of file
+}
+
+// IsConstraintError reports whether err was raised by the named table constraint.
+func IsConstraintError(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.ConstraintName == constraint
+}
@@ -0,0 +1,235 @@
+package postgres
+
+import (
+	"context"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+
+	"github.com/jackc/pgx/v4"
+)
+
+type ReconciliationRepository struct {
+	client *Client
+}
+
+func NewReconciliationRepository(client *Client) *ReconciliationRepository {
+	return &ReconciliationRepository{
+		client: client,
+	}
+}
+
+const runColumns = "id, repair, status, users_checked, discrepancies, repaired, error, started_at, finished_at"
+
+func scanRun(row pgx.Row) (*core.ReconciliationRun, error) {
+	run := &core.ReconciliationRun{}
+	err := row.Scan(&run.ID, &run.Repair, &run.Status, &run.UsersChecked, &run.Discrepancies, &run.Repaired, &run.Error,
+		&run.StartedAt, &run.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+func (r *ReconciliationRepository) CreateRun(ctx context.Context, run *core.ReconciliationRun) error {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(ctx, "INSERT INTO reconciliation_runs (repair, status) VALUES ($1, $2) RETURNING id, started_at",
+		run.Repair, run.Status,
+	)
+
+	return row.Scan(&run.ID, &run.StartedAt)
+}
+
+func (r *ReconciliationRepository) FinishRun(ctx context.Context, run *core.ReconciliationRun) error {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(ctx, `UPDATE reconciliation_runs SET status = $1, users_checked = $2, discrepancies = $3, repaired = $4, error = $5,
+	finished_at = CURRENT_TIMESTAMP WHERE id = $6 RETURNING finished_at`,
+		run.Status, run.UsersChecked, run.Discrepancies, run.Repaired, run.Error, run.ID,
+	)
+
+	return row.Scan(&run.FinishedAt)
+}
+
+func (r *ReconciliationRepository) FindRunByID(ctx context.Context, id string) (*core.ReconciliationRun, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanRun(tx.QueryRow(ctx, "SELECT "+runColumns+" FROM reconciliation_runs WHERE id = $1", id))
+}
+
+func (r *ReconciliationRepository) ListRuns(ctx context.Context, limit int) ([]*core.ReconciliationRun, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, "SELECT "+runColumns+" FROM reconciliation_runs ORDER BY started_at DESC LIMIT $1", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []*core.ReconciliationRun{}
+	for rows.Next() {
+		run, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
+
+func (r *ReconciliationRepository) CreateItem(ctx context.Context, item *core.ReconciliationItem) error {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(ctx, `INSERT INTO reconciliation_items (run_id, user_id, "check", expected, actual, repaired, note)
+	VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`,
+		item.RunID, item.UserID, item.Check, item.Expected, item.Actual, item.Repaired, item.Note,
+	)
+
+	return row.Scan(&item.ID, &item.CreatedAt)
+}
+
+func (r *ReconciliationRepository) ListItems(ctx context.Context, runID string) ([]*core.ReconciliationItem, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT id, run_id, user_id, "check", expected, actual, repaired, note, created_at
+	FROM reconciliation_items WHERE run_id = $1 ORDER BY created_at, user_id`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*core.ReconciliationItem{}
+	for rows.Next() {
+		item := &core.ReconciliationItem{}
+		err = rows.Scan(&item.ID, &item.RunID, &item.UserID, &item.Check, &item.Expected, &item.Actual, &item.Repaired, &item.Note, &item.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// unpostedTransactions are transactions without a journal entry, and their effects the
+// points and bonus each of them should have moved.
+const unpostedTransactions = `WITH unposted AS (
+	SELECT * FROM transactions WHERE deleted_at IS NULL
+	AND NOT EXISTS (SELECT 1 FROM journal_entries WHERE journal_entries.reference_id = transactions.id)
+), effects AS (
+	SELECT recipient_id AS user_id, 'POINTS' AS type, points AS amount FROM unposted WHERE type IN ('TRANSFER', 'REVERSAL', 'BONUS', 'PROMO', 'WELCOME')
+	UNION ALL
+	SELECT sender_id, 'POINTS', -points FROM unposted WHERE type IN ('TRANSFER', 'REVERSAL', 'EXPIRY')
+	UNION ALL
+	SELECT sender_id, 'BONUS', -points FROM unposted WHERE type = 'BONUS'
+)`
+
+func (r *ReconciliationRepository) FindDiscrepancies(ctx context.Context) ([]*core.ReconciliationItem, int, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var usersChecked int
+	if err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM user_points WHERE deleted_at IS NULL").Scan(&usersChecked); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := tx.Query(ctx, unpostedTransactions+`, accounts AS (
+		SELECT ledger_accounts.user_id, ledger_accounts.type, ledger_accounts.balance AS actual,
+		COALESCE((SELECT SUM(amount) FROM postings WHERE postings.account_id = ledger_accounts.id), 0)
+		+ COALESCE((SELECT SUM(amount) FROM effects WHERE effects.user_id = ledger_accounts.user_id AND effects.type = ledger_accounts.type), 0) AS expected
+		FROM ledger_accounts WHERE ledger_accounts.user_id IS NOT NULL
+	)
+	SELECT user_id, type, expected, actual FROM accounts WHERE expected <> actual
+	UNION ALL
+	SELECT user_points.user_id, 'REFERRALS', referred.count, user_points.number_of_referred_users FROM user_points
+	CROSS JOIN LATERAL (SELECT COUNT(*) FROM referrals WHERE referrals.referrer_id = user_points.user_id AND referrals.deleted_at IS NULL) referred
+	WHERE user_points.deleted_at IS NULL AND referred.count <> user_points.number_of_referred_users`)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	items := []*core.ReconciliationItem{}
+	for rows.Next() {
+		item := &core.ReconciliationItem{}
+		if err = rows.Scan(&item.UserID, &item.Check, &item.Expected, &item.Actual); err != nil {
+			return nil, 0, err
+		}
+		items = append(items, item)
+	}
+
+	return items, usersChecked, rows.Err()
+}
+
+func (r *ReconciliationRepository) ListUnpostedTransactions(ctx context.Context, userID string) ([]*core.Transaction, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, unpostedTransactions+` SELECT id, sender_id, recipient_id, points, type, created_at FROM unposted
+	WHERE sender_id = $1 OR recipient_id = $1 ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := []*core.Transaction{}
+	for rows.Next() {
+		transaction := &core.Transaction{}
+		err = rows.Scan(&transaction.ID, &transaction.SenderID, &transaction.RecipientID, &transaction.Points, &transaction.Type, &transaction.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	return transactions, rows.Err()
+}
+
+// ResyncAccountBalance resets the cached balance of the account to the sum of its postings.
+func (r *ReconciliationRepository) ResyncAccountBalance(ctx context.Context, code string) error {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE ledger_accounts SET updated_at = CURRENT_TIMESTAMP,
+	balance = COALESCE((SELECT SUM(amount) FROM postings WHERE postings.account_id = ledger_accounts.id), 0) WHERE code = $1`, code)
+
+	return err
+}
+
+func (r *ReconciliationRepository) ResyncReferralCount(ctx context.Context, userID string) error {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE user_points SET updated_at = CURRENT_TIMESTAMP, number_of_referred_users = (
+	SELECT COUNT(*) FROM referrals WHERE referrals.referrer_id = user_points.user_id AND referrals.deleted_at IS NULL
+	) WHERE user_id = $1 AND deleted_at IS NULL`, userID)
+
+	return err
+}
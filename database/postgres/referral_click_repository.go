@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+)
+
+type ReferralClickRepository struct {
+	client *Client
+}
+
+func NewReferralClickRepository(client *Client) *ReferralClickRepository {
+	return &ReferralClickRepository{
+		client: client,
+	}
+}
+
+func (r *ReferralClickRepository) CreateClick(ctx context.Context, click *core.ReferralClick) error {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(ctx, `WITH code AS (
+		SELECT referral_codes.id, referral_codes.code FROM referral_codes WHERE `+referralCodeMatch+` AND `+liveReferralCode+`
+		`+referralCodeOrder+` LIMIT 1
+	)
+	INSERT INTO referral_clicks (referral_code_id, ip_hash, user_agent, utm_source, utm_medium, utm_campaign, utm_term, utm_content)
+	SELECT code.id, $2, $3, $4, $5, $6, $7, $8 FROM code RETURNING id, referral_code_id, (SELECT code FROM code), created_at`,
+		click.Code, click.IPHash, click.UserAgent, click.UTMSource, click.UTMMedium, click.UTMCampaign, click.UTMTerm, click.UTMContent,
+	)
+
+	return row.Scan(&click.ID, &click.ReferralCodeID, &click.Code, &click.CreatedAt)
+}
+
+func (r *ReferralClickRepository) ListFunnels(ctx context.Context, userID string) ([]*core.ReferralFunnel, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT referral_codes.id, referral_codes.code, referral_codes.deleted_at IS NULL,
+	clicks.count, clicks.visitors, registrations.count, registrations.qualified, referral_codes.created_at
+	FROM referral_codes
+	CROSS JOIN LATERAL (SELECT COUNT(*) AS count, COUNT(DISTINCT ip_hash) AS visitors FROM referral_clicks
+		WHERE referral_clicks.referral_code_id = referral_codes.id) clicks
+	CROSS JOIN LATERAL (SELECT COUNT(*) AS count, COUNT(*) FILTER (WHERE status = 'QUALIFIED') AS qualified FROM referrals
+		WHERE referrals.referral_code_id = referral_codes.id AND referrals.deleted_at IS NULL) registrations
+	WHERE referral_codes.user_id = $1 ORDER BY referral_codes.created_at DESC, referral_codes.id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	funnels := []*core.ReferralFunnel{}
+	for rows.Next() {
+		funnel := &core.ReferralFunnel{}
+		err = rows.Scan(&funnel.ReferralCodeID, &funnel.Code, &funnel.Active, &funnel.Clicks, &funnel.UniqueVisitors,
+			&funnel.Registrations, &funnel.Qualified, &funnel.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		funnels = append(funnels, funnel)
+	}
+
+	return funnels, rows.Err()
+}
@@ -2,8 +2,12 @@ package postgres
 
 import (
 	"context"
+	"time"
 
 	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/errors"
+
+	"github.com/jackc/pgx/v4"
 )
 
 type ReferralCodeRepository struct {
@@ -16,34 +20,175 @@ func NewReferralCodeRepository(client *Client) *ReferralCodeRepository {
 	}
 }
 
-func(rc *ReferralCodeRepository) CreateReferralCode(ctx context.Context, uRefCode *core.ReferralCode) error {
+const referralCodeColumns = `referral_codes.id, referral_codes.user_id, referral_codes.code, referral_codes.vanity,
+	referral_codes.alias_expires_at, COALESCE(referral_codes.retired_reason, ''), referral_codes.max_redemptions, referral_codes.redemptions,
+	referral_codes.expires_at, referral_codes.allowed_domains, referral_codes.created_at, referral_codes.deleted_at`
+
+// referralCodeMatch matches the referral codes a code given at registration could be: generated codes
+// exactly and vanity codes regardless of case.
+const referralCodeMatch = `(referral_codes.code = $1 OR (referral_codes.vanity AND upper(referral_codes.code) = upper($1)))`
+
+// liveReferralCode holds for codes that still refer new users: active codes, and retired ones until their alias expires.
+const liveReferralCode = `(referral_codes.deleted_at IS NULL OR referral_codes.alias_expires_at > CURRENT_TIMESTAMP)`
+
+// referralCodeOrder puts live codes first, then exact matches, then the most recently active.
+const referralCodeOrder = `ORDER BY ` + liveReferralCode + ` DESC, referral_codes.code = $1 DESC, referral_codes.deleted_at IS NULL DESC,
+	referral_codes.deleted_at DESC`
+
+func scanReferralCode(row pgx.Row) (*core.ReferralCode, error) {
+	code := &core.ReferralCode{}
+	err := row.Scan(&code.ID, &code.UserID, &code.Code, &code.Vanity, &code.AliasExpiresAt, &code.RetiredReason, &code.MaxRedemptions,
+		&code.Redemptions, &code.ExpiresAt, &code.AllowedDomains, &code.CreatedAt, &code.DeletedAt)
+	if err != nil {
+		return nil, err
+	}
+	return code, nil
+}
+
+func (rc *ReferralCodeRepository) CreateReferralCode(ctx context.Context, uRefCode *core.ReferralCode) error {
 	tx, err := rc.client.GetTx(ctx)
 	if err != nil {
 		return err
 	}
 
-	row := tx.QueryRow(ctx, 
-		"INSERT INTO referral_codes (user_id, code) VALUES ($1, $2) RETURNING id", uRefCode.UserID, uRefCode.Code,
+	row := tx.QueryRow(ctx, `INSERT INTO referral_codes (user_id, code, vanity, max_redemptions, redemptions, expires_at, allowed_domains)
+	VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`,
+		uRefCode.UserID, uRefCode.Code, uRefCode.Vanity, uRefCode.MaxRedemptions, uRefCode.Redemptions, uRefCode.ExpiresAt,
+		domainList(uRefCode.AllowedDomains),
 	)
 
-	err = row.Scan(&uRefCode.ID)
+	err = row.Scan(&uRefCode.ID, &uRefCode.CreatedAt)
+	if err != nil && IsDuplicateError(err) {
+		return errors.ErrReferralCodeTaken
+	}
 
 	return err
 }
 
-func(rc *ReferralCodeRepository) FindReferralCodeByUserID(ctx context.Context, userID string) (*core.ReferralCode, error) {
+func (rc *ReferralCodeRepository) FindReferralCodeByUserID(ctx context.Context, userID string) (*core.ReferralCode, error) {
 	tx, err := rc.client.GetTx(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	row := tx.QueryRow(ctx, "SELECT * FROM referral_codes WHERE user_id = $1 AND deleted_at IS NULL", userID)
+	return scanReferralCode(tx.QueryRow(ctx, "SELECT "+referralCodeColumns+" FROM referral_codes WHERE user_id = $1 AND deleted_at IS NULL FOR UPDATE", userID))
+}
 
-	rCode := &core.ReferralCode{}
-	err = row.Scan(&rCode.ID, &rCode.UserID, &rCode.Code, &rCode.CreatedAt)
+func (rc *ReferralCodeRepository) FindReferralCodeByCode(ctx context.Context, code string) (*core.ReferralCode, error) {
+	tx, err := rc.client.GetTx(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	rCode, err := scanReferralCode(tx.QueryRow(ctx, "SELECT "+referralCodeColumns+" FROM referral_codes WHERE "+referralCodeMatch+" "+
+		referralCodeOrder+" LIMIT 1 FOR UPDATE", code))
+	if err != nil {
+		return nil, err
+	}
+
+	if rCode.DeletedAt != nil && (rCode.AliasExpiresAt == nil || !rCode.AliasExpiresAt.After(time.Now())) {
+		if rCode.RetiredReason == core.CodeRetiredRevoked {
+			return nil, errors.ErrReferralCodeRevoked
+		}
+		return nil, pgx.ErrNoRows
+	}
 	return rCode, nil
-}
\ No newline at end of file
+}
+
+func (rc *ReferralCodeRepository) IncrementRedemptions(ctx context.Context, uRefCode *core.ReferralCode) error {
+	tx, err := rc.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(ctx, "UPDATE referral_codes SET redemptions = redemptions + 1 WHERE id = $1 RETURNING redemptions", uRefCode.ID)
+
+	return row.Scan(&uRefCode.Redemptions)
+}
+
+func (rc *ReferralCodeRepository) UpdateReferralCodeLimits(ctx context.Context, uRefCode *core.ReferralCode) error {
+	tx, err := rc.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE referral_codes SET max_redemptions = $1, expires_at = $2, allowed_domains = $3 WHERE id = $4",
+		uRefCode.MaxRedemptions, uRefCode.ExpiresAt, domainList(uRefCode.AllowedDomains), uRefCode.ID,
+	)
+
+	return err
+}
+
+func (rc *ReferralCodeRepository) RetireReferralCode(ctx context.Context, uRefCode *core.ReferralCode, reason string, aliasExpiresAt *time.Time) error {
+	tx, err := rc.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(ctx, `UPDATE referral_codes SET deleted_at = CURRENT_TIMESTAMP, retired_reason = $1, alias_expires_at = $2
+	WHERE id = $3 RETURNING deleted_at`, reason, aliasExpiresAt, uRefCode.ID)
+
+	uRefCode.RetiredReason = reason
+	uRefCode.AliasExpiresAt = aliasExpiresAt
+	return row.Scan(&uRefCode.DeletedAt)
+}
+
+func (rc *ReferralCodeRepository) ListReferralCodes(ctx context.Context, userID string) ([]*core.ReferralCode, error) {
+	tx, err := rc.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, "SELECT "+referralCodeColumns+" FROM referral_codes WHERE user_id = $1 ORDER BY created_at, id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	codes := []*core.ReferralCode{}
+	for rows.Next() {
+		code, err := scanReferralCode(rows)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, rows.Err()
+}
+
+func (rc *ReferralCodeRepository) CountRedemptions(ctx context.Context, userID string) (int, error) {
+	tx, err := rc.client.GetTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	row := tx.QueryRow(ctx, "SELECT COALESCE(SUM(redemptions), 0) FROM referral_codes WHERE user_id = $1", userID)
+	if err = row.Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (rc *ReferralCodeRepository) CountVanityCodesSince(ctx context.Context, userID string, since time.Time) (int, error) {
+	tx, err := rc.client.GetTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	row := tx.QueryRow(ctx, "SELECT COUNT(*) FROM referral_codes WHERE user_id = $1 AND vanity AND created_at >= $2", userID, since)
+	if err = row.Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// domainList stores a nil list of domains as an empty array rather than null.
+func domainList(domains []string) []string {
+	if domains == nil {
+		return []string{}
+	}
+	return domains
+}
@@ -2,8 +2,20 @@ package postgres
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/errors"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Constraints keeping referrals consistent: one live referrer per user, no self-referrals and no loops.
+const (
+	refereeUniqueIndex     = "referrals_referee_unique_idx"
+	selfReferralConstraint = "referrals_no_self_referral"
+	referralCycleTrigger   = "referrals_no_cycle"
 )
 
 type ReferralRepository struct {
@@ -22,11 +34,326 @@ func (r *ReferralRepository) CreateReferral(ctx context.Context, referral *core.
 		return err
 	}
 
-	row := tx.QueryRow(ctx, 
-		"INSERT INTO referrals (referrer_id, referee_id) VALUES ($1, $2) RETURNING id", referral.ReferrerID, referral.RefereeID,
+	row := tx.QueryRow(ctx,
+		`INSERT INTO referrals (referrer_id, referee_id, campaign_id, referral_code_id, status, qualified_at, expires_at)
+		VALUES ($1, $2, NULLIF($3, '')::uuid, NULLIF($4, '')::uuid, $5, CASE WHEN $5 = 'QUALIFIED' THEN CURRENT_TIMESTAMP END, $6)
+		RETURNING id, qualified_at, created_at`,
+		referral.ReferrerID, referral.RefereeID, referral.CampaignID, referral.ReferralCodeID, referral.Status, referral.ExpiresAt,
 	)
 
-	err = row.Scan(&referral.ID)
+	err = row.Scan(&referral.ID, &referral.QualifiedAt, &referral.CreatedAt)
+	switch {
+	case IsConstraintError(err, refereeUniqueIndex):
+		return errors.ErrAlreadyReferred
+	case IsConstraintError(err, selfReferralConstraint):
+		return errors.ErrSelfReferral
+	case IsConstraintError(err, referralCycleTrigger):
+		return errors.ErrReferralCycle
+	}
+
+	return err
+}
+
+func (r *ReferralRepository) ListAncestors(ctx context.Context, userID string, maxDepth int) ([]*core.ReferralAncestor, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `WITH RECURSIVE ancestors AS (
+		SELECT referrer_id AS user_id, 1 AS level, ARRAY[referee_id, referrer_id] AS path
+		FROM referrals WHERE referee_id = $1 AND deleted_at IS NULL
+		UNION ALL
+		SELECT referrals.referrer_id, ancestors.level + 1, ancestors.path || referrals.referrer_id
+		FROM ancestors JOIN referrals ON referrals.referee_id = ancestors.user_id AND referrals.deleted_at IS NULL
+		WHERE ancestors.level < $2 AND NOT referrals.referrer_id = ANY(ancestors.path)
+	)
+	SELECT user_id, level FROM ancestors ORDER BY level`, userID, maxDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ancestors := []*core.ReferralAncestor{}
+	for rows.Next() {
+		ancestor := &core.ReferralAncestor{}
+		if err = rows.Scan(&ancestor.UserID, &ancestor.Level); err != nil {
+			return nil, err
+		}
+		ancestors = append(ancestors, ancestor)
+	}
+
+	return ancestors, rows.Err()
+}
+
+// activeReferee holds for referees who made at least one transfer.
+const activeReferee = `EXISTS (SELECT 1 FROM transactions WHERE transactions.sender_id = referrals.referee_id
+	AND transactions.type = 'TRANSFER' AND transactions.deleted_at IS NULL)`
+
+func (r *ReferralRepository) ListReferees(ctx context.Context, userID string, after *core.RefereeCursor, limit int) ([]*core.Referee, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{userID, limit}
+	cond := ""
+	if after != nil {
+		args = append(args, after.ReferredAt, after.ReferralID)
+		cond = "AND (referrals.created_at, referrals.id) < ($3, $4::uuid)"
+	}
+
+	rows, err := tx.Query(ctx, fmt.Sprintf(`SELECT referrals.id, users.id, users.name, COALESCE(referrals.campaign_id::text, ''), referrals.status, %s,
+	COALESCE((SELECT SUM(points) FROM referral_rewards WHERE referral_rewards.referral_id = referrals.id AND referral_rewards.user_id = $1
+	AND referral_rewards.status = 'RELEASED'), 0),
+	referrals.created_at FROM referrals INNER JOIN users ON users.id = referrals.referee_id
+	WHERE referrals.referrer_id = $1 AND referrals.deleted_at IS NULL %s
+	ORDER BY referrals.created_at DESC, referrals.id DESC LIMIT $2`, activeReferee, cond), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	referees := []*core.Referee{}
+	for rows.Next() {
+		referee := &core.Referee{}
+		err = rows.Scan(&referee.ReferralID, &referee.UserID, &referee.Name, &referee.CampaignID, &referee.Status, &referee.Active, &referee.PointsEarned,
+			&referee.ReferredAt)
+		if err != nil {
+			return nil, err
+		}
+		referees = append(referees, referee)
+	}
+
+	return referees, rows.Err()
+}
+
+func (r *ReferralRepository) ListDescendants(ctx context.Context, userID string, maxDepth int) ([]*core.ReferralDescendant, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `WITH RECURSIVE descendants AS (
+		SELECT referee_id AS user_id, referrer_id, 1 AS level, created_at, ARRAY[referrer_id, referee_id] AS path
+		FROM referrals WHERE referrer_id = $1 AND deleted_at IS NULL
+		UNION ALL
+		SELECT referrals.referee_id, referrals.referrer_id, descendants.level + 1, referrals.created_at, descendants.path || referrals.referee_id
+		FROM descendants JOIN referrals ON referrals.referrer_id = descendants.user_id AND referrals.deleted_at IS NULL
+		WHERE descendants.level < $2 AND NOT referrals.referee_id = ANY(descendants.path)
+	)
+	SELECT descendants.user_id, descendants.referrer_id, users.name, descendants.level, descendants.created_at
+	FROM descendants INNER JOIN users ON users.id = descendants.user_id
+	ORDER BY descendants.level, descendants.created_at, descendants.user_id`, userID, maxDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	descendants := []*core.ReferralDescendant{}
+	for rows.Next() {
+		descendant := &core.ReferralDescendant{}
+		if err = rows.Scan(&descendant.UserID, &descendant.ReferrerID, &descendant.Name, &descendant.Level, &descendant.ReferredAt); err != nil {
+			return nil, err
+		}
+		descendants = append(descendants, descendant)
+	}
+
+	return descendants, rows.Err()
+}
+
+func (r *ReferralRepository) GetReferralStats(ctx context.Context, userID string) (*core.ReferralStats, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &core.ReferralStats{UserID: userID, ByMonth: []*core.ReferralMonth{}}
+	row := tx.QueryRow(ctx, `SELECT COALESCE(SUM(points), 0) FROM referral_rewards WHERE user_id = $1 AND status = 'RELEASED'`, userID)
+	if err = row.Scan(&stats.PointsEarned); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT to_char(date_trunc('month', referrals.created_at AT TIME ZONE 'UTC'), 'YYYY-MM') AS month,
+	COUNT(*), COUNT(*) FILTER (WHERE `+activeReferee+`)
+	FROM referrals WHERE referrals.referrer_id = $1 AND referrals.deleted_at IS NULL GROUP BY month ORDER BY month`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		month := &core.ReferralMonth{}
+		if err = rows.Scan(&month.Month, &month.Referees, &month.Active); err != nil {
+			return nil, err
+		}
+		month.ConversionRate = float64(month.Active) / float64(month.Referees)
+		stats.TotalReferees += month.Referees
+		stats.ActiveReferees += month.Active
+		stats.ByMonth = append(stats.ByMonth, month)
+	}
+
+	return stats, rows.Err()
+}
+
+func (r *ReferralRepository) FindPendingReferral(ctx context.Context, refereeID string) (*core.Referral, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanReferral(tx.QueryRow(ctx, `SELECT `+referralColumns+` FROM referrals WHERE referee_id = $1 AND status = 'PENDING'
+	AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP) FOR UPDATE`, refereeID))
+}
+
+func (r *ReferralRepository) UpdateReferralStatus(ctx context.Context, referral *core.Referral) error {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE referrals SET status = $1, qualified_at = $2 WHERE id = $3", referral.Status, referral.QualifiedAt, referral.ID)
 
 	return err
-}
\ No newline at end of file
+}
+
+// qualifyingActions are the conditions, on the referee of a referral, under which each qualifying action is complete.
+var qualifyingActions = map[string]string{
+	core.QualifyNone:          "TRUE",
+	core.QualifyFirstTransfer: activeReferee,
+	core.QualifyMinPoints: `EXISTS (SELECT 1 FROM ledger_accounts points_account WHERE points_account.user_id = referrals.referee_id
+	AND points_account.type = 'POINTS' AND ` + spendableBalance + ` >= $2)`,
+	core.QualifyEmailVerified: `EXISTS (SELECT 1 FROM users WHERE users.id = referrals.referee_id AND users.email_verified_at IS NOT NULL)`,
+}
+
+func (r *ReferralRepository) ListQualifiedReferees(ctx context.Context, action string, minPoints int, limit int) ([]string, error) {
+	cond, ok := qualifyingActions[action]
+	if !ok {
+		return nil, fmt.Errorf("unknown qualifying action %q", action)
+	}
+
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{limit}
+	if action == core.QualifyMinPoints {
+		args = append(args, minPoints)
+	}
+
+	rows, err := tx.Query(ctx, `SELECT referee_id FROM referrals WHERE status = 'PENDING' AND deleted_at IS NULL
+	AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP) AND `+cond+` ORDER BY created_at LIMIT $1`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	referees := []string{}
+	for rows.Next() {
+		var refereeID string
+		if err = rows.Scan(&refereeID); err != nil {
+			return nil, err
+		}
+		referees = append(referees, refereeID)
+	}
+
+	return referees, rows.Err()
+}
+
+func (r *ReferralRepository) ExpirePendingReferrals(ctx context.Context, before time.Time) (int, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var expired int
+	row := tx.QueryRow(ctx, `WITH expired AS (
+		UPDATE referrals SET status = 'EXPIRED' WHERE status = 'PENDING' AND expires_at <= $1 RETURNING id
+	), rewards AS (
+		UPDATE referral_rewards SET status = 'EXPIRED' FROM expired
+		WHERE referral_rewards.referral_id = expired.id AND referral_rewards.status = 'PENDING'
+	)
+	SELECT COUNT(*) FROM expired`, before)
+	if err = row.Scan(&expired); err != nil {
+		return 0, err
+	}
+	return expired, nil
+}
+
+func (r *ReferralRepository) ListDroppedReferrals(ctx context.Context, limit int) ([]*core.DroppedReferral, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT `+referralColumns+`, released FROM (
+		SELECT referrals.*, (SELECT COALESCE(SUM(points), 0) FROM referral_rewards
+		WHERE referral_rewards.referral_id = referrals.id AND referral_rewards.status = 'RELEASED') AS released
+		FROM referrals WHERE status = $1
+	) dropped WHERE released > 0 ORDER BY created_at, id LIMIT $2`, core.ReferralStatusDropped, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dropped := []*core.DroppedReferral{}
+	for rows.Next() {
+		referral := &core.DroppedReferral{Referral: &core.Referral{}}
+		err = rows.Scan(&referral.ID, &referral.ReferrerID, &referral.RefereeID, &referral.CampaignID, &referral.ReferralCodeID,
+			&referral.Status, &referral.QualifiedAt, &referral.ExpiresAt, &referral.CreatedAt, &referral.ReleasedPoints)
+		if err != nil {
+			return nil, err
+		}
+		dropped = append(dropped, referral)
+	}
+	return dropped, rows.Err()
+}
+
+const referralColumns = `id, referrer_id, referee_id, COALESCE(campaign_id::text, ''), COALESCE(referral_code_id::text, ''),
+	status, qualified_at, expires_at, created_at`
+
+func scanReferral(row pgx.Row) (*core.Referral, error) {
+	referral := &core.Referral{}
+	err := row.Scan(&referral.ID, &referral.ReferrerID, &referral.RefereeID, &referral.CampaignID, &referral.ReferralCodeID, &referral.Status,
+		&referral.QualifiedAt, &referral.ExpiresAt, &referral.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return referral, nil
+}
+
+func (r *ReferralRepository) FindReferralByID(ctx context.Context, id string) (*core.Referral, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanReferral(tx.QueryRow(ctx, "SELECT "+referralColumns+" FROM referrals WHERE id = $1 FOR UPDATE", id))
+}
+
+func (r *ReferralRepository) FindReferralByReferee(ctx context.Context, refereeID string) (*core.Referral, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanReferral(tx.QueryRow(ctx, `SELECT `+referralColumns+` FROM referrals WHERE referee_id = $1 AND deleted_at IS NULL
+	ORDER BY created_at DESC LIMIT 1 FOR UPDATE`, refereeID))
+}
+
+func (r *ReferralRepository) ClawBackReferral(ctx context.Context, referral *core.Referral) error {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE referrals SET status = $1, deleted_at = CURRENT_TIMESTAMP WHERE id = $2",
+		core.ReferralStatusClawedBack, referral.ID)
+	if err != nil {
+		return err
+	}
+
+	referral.Status = core.ReferralStatusClawedBack
+	return nil
+}
@@ -0,0 +1,213 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+
+	"github.com/jackc/pgx/v4"
+)
+
+type RewardRuleRepository struct {
+	client *Client
+}
+
+func NewRewardRuleRepository(client *Client) *RewardRuleRepository {
+	return &RewardRuleRepository{
+		client: client,
+	}
+}
+
+const ruleColumns = `id, name, kind, version, active, level, points, every, milestones, tiers, cap_points, cap_period,
+	COALESCE(campaign_id::text, ''), created_at, updated_at`
+
+func scanRule(row pgx.Row) (*core.RewardRule, error) {
+	rule := &core.RewardRule{}
+	err := row.Scan(&rule.ID, &rule.Name, &rule.Kind, &rule.Version, &rule.Active, &rule.Level, &rule.Points, &rule.Every, &rule.Milestones,
+		&rule.Tiers, &rule.CapPoints, &rule.CapPeriod, &rule.CampaignID, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (r *RewardRuleRepository) CreateRewardRule(ctx context.Context, rule *core.RewardRule) error {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(ctx, `INSERT INTO reward_rules (name, kind, active, level, points, every, milestones, tiers, cap_points, cap_period,
+	campaign_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, '')::uuid) RETURNING id, version, created_at, updated_at`,
+		rule.Name, rule.Kind, rule.Active, rule.Level, rule.Points, rule.Every, jsonList(rule.Milestones), jsonList(rule.Tiers), rule.CapPoints, rule.CapPeriod,
+		rule.CampaignID,
+	)
+
+	return row.Scan(&rule.ID, &rule.Version, &rule.CreatedAt, &rule.UpdatedAt)
+}
+
+func (r *RewardRuleRepository) FindRewardRuleByID(ctx context.Context, id string) (*core.RewardRule, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanRule(tx.QueryRow(ctx, "SELECT "+ruleColumns+" FROM reward_rules WHERE id = $1", id))
+}
+
+func (r *RewardRuleRepository) ListRewardRules(ctx context.Context, activeOnly bool) ([]*core.RewardRule, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, "SELECT "+ruleColumns+" FROM reward_rules WHERE active OR NOT $1 ORDER BY created_at, id", activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []*core.RewardRule{}
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
+
+func (r *RewardRuleRepository) UpdateRewardRule(ctx context.Context, rule *core.RewardRule) error {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(ctx, `UPDATE reward_rules SET name = $1, kind = $2, active = $3, level = $4, points = $5, every = $6, milestones = $7,
+	tiers = $8, cap_points = $9, cap_period = $10, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $11
+	RETURNING version, updated_at`,
+		rule.Name, rule.Kind, rule.Active, rule.Level, rule.Points, rule.Every, jsonList(rule.Milestones), jsonList(rule.Tiers), rule.CapPoints, rule.CapPeriod,
+		rule.ID,
+	)
+
+	return row.Scan(&rule.Version, &rule.UpdatedAt)
+}
+
+func (r *RewardRuleRepository) CreateReferralReward(ctx context.Context, reward *core.ReferralReward) error {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(ctx, `INSERT INTO referral_rewards (referral_id, user_id, rule_id, rule_version, level, points, status, journal_entry_id, milestone)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, '')::uuid, $9) RETURNING id, created_at`,
+		reward.ReferralID, reward.UserID, reward.RuleID, reward.RuleVersion, reward.Level, reward.Points, reward.Status, reward.JournalEntryID,
+		reward.Milestone,
+	)
+
+	return row.Scan(&reward.ID, &reward.CreatedAt)
+}
+
+const referralRewardColumns = `id, referral_id, user_id, rule_id, rule_version, level, points, status,
+	COALESCE(journal_entry_id::text, ''),
+	COALESCE((SELECT campaign_id::text FROM reward_rules WHERE reward_rules.id = referral_rewards.rule_id), ''), milestone, created_at`
+
+func (r *RewardRuleRepository) ListReferralRewards(ctx context.Context, referralID string) ([]*core.ReferralReward, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, "SELECT "+referralRewardColumns+" FROM referral_rewards WHERE referral_id = $1 ORDER BY created_at, id", referralID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanReferralRewards(rows)
+}
+
+func (r *RewardRuleRepository) ListMilestoneRewards(ctx context.Context, userID string, above int) ([]*core.ReferralReward, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT `+referralRewardColumns+` FROM referral_rewards WHERE user_id = $1 AND milestone > $2
+	AND status IN ('PENDING', 'RELEASED') ORDER BY created_at, id FOR UPDATE`, userID, above)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanReferralRewards(rows)
+}
+
+func scanReferralRewards(rows pgx.Rows) ([]*core.ReferralReward, error) {
+	defer rows.Close()
+
+	rewards := []*core.ReferralReward{}
+	for rows.Next() {
+		reward := &core.ReferralReward{}
+		err := rows.Scan(&reward.ID, &reward.ReferralID, &reward.UserID, &reward.RuleID, &reward.RuleVersion, &reward.Level, &reward.Points,
+			&reward.Status, &reward.JournalEntryID, &reward.CampaignID, &reward.Milestone, &reward.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		rewards = append(rewards, reward)
+	}
+
+	return rewards, rows.Err()
+}
+
+func (r *RewardRuleRepository) ReleaseReferralReward(ctx context.Context, reward *core.ReferralReward) error {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE referral_rewards SET status = $1, journal_entry_id = $2 WHERE id = $3 AND status = 'PENDING'",
+		core.RewardStatusReleased, reward.JournalEntryID, reward.ID)
+	if err != nil {
+		return err
+	}
+
+	reward.Status = core.RewardStatusReleased
+	return nil
+}
+
+func (r *RewardRuleRepository) SumRewardPoints(ctx context.Context, ruleID, userID string, since time.Time) (int, error) {
+	tx, err := r.client.GetTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var sum int
+	row := tx.QueryRow(ctx, `SELECT COALESCE(SUM(points), 0) FROM referral_rewards WHERE rule_id = $1 AND user_id = $2 AND created_at >= $3
+	AND status <> 'EXPIRED'`,
+		ruleID, userID, since)
+	if err = row.Scan(&sum); err != nil {
+		return 0, err
+	}
+	return sum, nil
+}
+
+// jsonList stores nil lists as an empty JSON array rather than null.
+func jsonList(list interface{}) interface{} {
+	switch l := list.(type) {
+	case []int:
+		if l == nil {
+			return []int{}
+		}
+	case []core.RewardTier:
+		if l == nil {
+			return []core.RewardTier{}
+		}
+	case []core.FraudHit:
+		if l == nil {
+			return []core.FraudHit{}
+		}
+	}
+	return list
+}
@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+
+	"github.com/jackc/pgx/v4"
+)
+
+type ScheduledTransferRepository struct {
+	client *Client
+}
+
+func NewScheduledTransferRepository(client *Client) *ScheduledTransferRepository {
+	return &ScheduledTransferRepository{
+		client: client,
+	}
+}
+
+const scheduleColumns = "id, sender_id, recipient_id, points, rule, status, next_run_at, consecutive_failures, created_at, updated_at"
+
+func scanSchedule(row pgx.Row) (*core.ScheduledTransfer, error) {
+	schedule := &core.ScheduledTransfer{}
+	err := row.Scan(&schedule.ID, &schedule.SenderID, &schedule.RecipientID, &schedule.Points, &schedule.Rule, &schedule.Status,
+		&schedule.NextRunAt, &schedule.ConsecutiveFailures, &schedule.CreatedAt, &schedule.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+func (s *ScheduledTransferRepository) CreateScheduledTransfer(ctx context.Context, schedule *core.ScheduledTransfer) error {
+	tx, err := s.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(ctx, `INSERT INTO scheduled_transfers (sender_id, recipient_id, points, rule, status, next_run_at)
+	VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at`,
+		schedule.SenderID, schedule.RecipientID, schedule.Points, schedule.Rule, schedule.Status, schedule.NextRunAt,
+	)
+
+	return row.Scan(&schedule.ID, &schedule.CreatedAt, &schedule.UpdatedAt)
+}
+
+func (s *ScheduledTransferRepository) FindScheduledTransferByID(ctx context.Context, id string) (*core.ScheduledTransfer, error) {
+	tx, err := s.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanSchedule(tx.QueryRow(ctx, "SELECT "+scheduleColumns+" FROM scheduled_transfers WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id))
+}
+
+func (s *ScheduledTransferRepository) ListScheduledTransfers(ctx context.Context, senderID string) ([]*core.ScheduledTransfer, error) {
+	tx, err := s.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, "SELECT "+scheduleColumns+` FROM scheduled_transfers WHERE sender_id = $1 AND deleted_at IS NULL
+	ORDER BY created_at DESC`, senderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := []*core.ScheduledTransfer{}
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules, rows.Err()
+}
+
+func (s *ScheduledTransferRepository) ListDueScheduledTransfers(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	tx, err := s.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT id FROM scheduled_transfers WHERE status = 'ACTIVE' AND deleted_at IS NULL AND next_run_at <= $1
+	ORDER BY next_run_at LIMIT $2`, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func (s *ScheduledTransferRepository) UpdateScheduledTransfer(ctx context.Context, schedule *core.ScheduledTransfer) error {
+	tx, err := s.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(ctx, `UPDATE scheduled_transfers SET points = $1, rule = $2, status = $3, next_run_at = $4, consecutive_failures = $5,
+	updated_at = CURRENT_TIMESTAMP WHERE id = $6 RETURNING updated_at`,
+		schedule.Points, schedule.Rule, schedule.Status, schedule.NextRunAt, schedule.ConsecutiveFailures, schedule.ID,
+	)
+
+	return row.Scan(&schedule.UpdatedAt)
+}
+
+func (s *ScheduledTransferRepository) DeleteScheduledTransfer(ctx context.Context, id string) error {
+	tx, err := s.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, "UPDATE scheduled_transfers SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (s *ScheduledTransferRepository) CreateExecution(ctx context.Context, execution *core.ScheduledTransferExecution) error {
+	tx, err := s.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(ctx, `INSERT INTO scheduled_transfer_executions (scheduled_transfer_id, transaction_id, status, error, scheduled_for)
+	VALUES ($1, NULLIF($2, '')::uuid, $3, $4, $5) RETURNING id, executed_at`,
+		execution.ScheduledTransferID, execution.TransactionID, execution.Status, execution.Error, execution.ScheduledFor,
+	)
+
+	return row.Scan(&execution.ID, &execution.ExecutedAt)
+}
+
+func (s *ScheduledTransferRepository) ListExecutions(ctx context.Context, scheduleID string, limit int) ([]*core.ScheduledTransferExecution, error) {
+	tx, err := s.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT id, scheduled_transfer_id, COALESCE(transaction_id::text, ''), status, error, scheduled_for, executed_at
+	FROM scheduled_transfer_executions WHERE scheduled_transfer_id = $1 ORDER BY executed_at DESC LIMIT $2`, scheduleID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	executions := []*core.ScheduledTransferExecution{}
+	for rows.Next() {
+		execution := &core.ScheduledTransferExecution{}
+		err = rows.Scan(&execution.ID, &execution.ScheduledTransferID, &execution.TransactionID, &execution.Status, &execution.Error,
+			&execution.ScheduledFor, &execution.ExecutedAt)
+		if err != nil {
+			return nil, err
+		}
+		executions = append(executions, execution)
+	}
+
+	return executions, rows.Err()
+}
@@ -2,32 +2,154 @@ package postgres
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	core "github.com/Qalifah/aboki-africa-assessment"
 )
 
 type TransactionRepository struct {
 	client *Client
+	ledger *LedgerRepository
 }
 
 func NewTransactionRepository(client *Client) *TransactionRepository {
 	return &TransactionRepository{
 		client: client,
+		ledger: NewLedgerRepository(client),
 	}
 }
 
-func(t *TransactionRepository) CreateTransaction(ctx context.Context, transaction *core.Transaction) error {
+func (t *TransactionRepository) CreateTransaction(ctx context.Context, transaction *core.Transaction) error {
 	tx, err := t.client.GetTx(ctx)
 	if err != nil {
 		return err
 	}
 
-	row := tx.QueryRow(ctx, 
-		"INSERT INTO transactions (sender_id, recipient_id, points) VALUES ($1, $2, $3, $4) RETURNING id", 
-		transaction.SenderID, transaction.RecipientID, transaction.Points, transaction.Type,
+	row := tx.QueryRow(ctx,
+		`INSERT INTO transactions (sender_id, recipient_id, points, type, reversal_of, expires_at) VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, $6)
+		RETURNING id, created_at`,
+		transaction.SenderID, transaction.RecipientID, transaction.Points, transaction.Type, transaction.ReversalOf, transaction.ExpiresAt,
 	)
 
-	err = row.Scan(&transaction.ID)
-	
+	err = row.Scan(&transaction.ID, &transaction.CreatedAt)
+
 	return err
-}
\ No newline at end of file
+}
+
+// FindTransactionByID locks the transaction until the surrounding transaction ends, so reversals
+// of the same transaction are checked against each other's amounts.
+func (t *TransactionRepository) FindTransactionByID(ctx context.Context, id string) (*core.Transaction, error) {
+	tx, err := t.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	row := tx.QueryRow(ctx, `SELECT id, sender_id, recipient_id, points, type, COALESCE(reversal_of::text, ''),
+	(SELECT COALESCE(SUM(points), 0) FROM transactions reversals WHERE reversals.reversal_of = transactions.id AND reversals.deleted_at IS NULL),
+	expires_at, created_at FROM transactions WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id)
+
+	transaction := &core.Transaction{}
+	err = row.Scan(&transaction.ID, &transaction.SenderID, &transaction.RecipientID, &transaction.Points, &transaction.Type,
+		&transaction.ReversalOf, &transaction.ReversedPoints, &transaction.ExpiresAt, &transaction.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// ListTransactions returns the page of the user's transactions selected by filter, ordered by
+// (created_at, id) descending so the last row of a page is the cursor for the next one.
+func (t *TransactionRepository) ListTransactions(ctx context.Context, filter *core.TransactionFilter) ([]*core.Transaction, error) {
+	tx, err := t.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{filter.UserID}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conds := []string{"deleted_at IS NULL"}
+	switch filter.Direction {
+	case core.DirectionSent:
+		conds = append(conds, "sender_id = $1")
+	case core.DirectionReceived:
+		conds = append(conds, "recipient_id = $1")
+	default:
+		conds = append(conds, "(sender_id = $1 OR recipient_id = $1)")
+	}
+	if filter.Type != "" {
+		conds = append(conds, "type = "+arg(filter.Type))
+	}
+	if filter.MinPoints != nil {
+		conds = append(conds, "points >= "+arg(*filter.MinPoints))
+	}
+	if filter.MaxPoints != nil {
+		conds = append(conds, "points <= "+arg(*filter.MaxPoints))
+	}
+	if filter.From != nil {
+		conds = append(conds, "created_at >= "+arg(*filter.From))
+	}
+	if filter.To != nil {
+		conds = append(conds, "created_at < "+arg(*filter.To))
+	}
+	if filter.After != nil {
+		conds = append(conds, fmt.Sprintf("(created_at, id) < (%s, %s::uuid)", arg(filter.After.CreatedAt), arg(filter.After.ID)))
+	}
+
+	query := `SELECT id, sender_id, recipient_id, points, type, COALESCE(reversal_of::text, ''),
+	(SELECT COALESCE(SUM(points), 0) FROM transactions reversals WHERE reversals.reversal_of = transactions.id AND reversals.deleted_at IS NULL),
+	expires_at, created_at FROM transactions WHERE ` + strings.Join(conds, " AND ") + " ORDER BY created_at DESC, id DESC LIMIT " + arg(filter.Limit)
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := []*core.Transaction{}
+	for rows.Next() {
+		transaction := &core.Transaction{}
+		err = rows.Scan(&transaction.ID, &transaction.SenderID, &transaction.RecipientID, &transaction.Points, &transaction.Type,
+			&transaction.ReversalOf, &transaction.ReversedPoints, &transaction.ExpiresAt, &transaction.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	return transactions, rows.Err()
+}
+
+// ClaimReferrerBonus records the claim, moves the claimed points from the recipient's bonus account
+// into their points account and marks the bonus as paid once nothing is left to claim.
+func (t *TransactionRepository) ClaimReferrerBonus(ctx context.Context, transaction *core.Transaction) error {
+	return t.client.WithTx(ctx, func(ctx context.Context) error {
+		tx, err := t.client.GetTx(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err = t.CreateTransaction(ctx, transaction); err != nil {
+			return err
+		}
+
+		entry := core.NewJournalEntry(core.EntryTypeBonusClaim, transaction.ID, core.UserBonusAccount(transaction.RecipientID),
+			core.UserPointsAccount(transaction.RecipientID), transaction.Points)
+		entry.ExpiresAt = transaction.ExpiresAt
+		if err = t.ledger.PostEntry(ctx, entry); err != nil {
+			return err
+		}
+
+		remaining := entry.Postings[0].BalanceAfter
+		_, err = tx.Exec(ctx, "UPDATE user_points SET updated_at = CURRENT_TIMESTAMP, paid = $1 WHERE user_id = $2 AND deleted_at IS NULL",
+			remaining == 0, transaction.RecipientID,
+		)
+
+		return err
+	})
+}
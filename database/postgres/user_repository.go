@@ -4,8 +4,14 @@ import (
 	"context"
 
 	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/errors"
+
+	"github.com/jackc/pgx/v4"
 )
 
+// canonicalEmailUniqueIndex keeps users from registering more than one account per mailbox.
+const canonicalEmailUniqueIndex = "users_canonical_email_unique_idx"
+
 type UserRepository struct {
 	client *Client
 }
@@ -14,50 +20,128 @@ func NewUserRepository(client *Client) *UserRepository {
 	return &UserRepository{client: client}
 }
 
-func(u *UserRepository) CreateUser(ctx context.Context, user *core.User) error {
+func (u *UserRepository) CreateUser(ctx context.Context, user *core.User) error {
 	tx, err := u.client.GetTx(ctx)
 	if err != nil {
 		return err
 	}
 
-	row := tx.QueryRow(ctx, 
-		"INSERT INTO users (name, email) VALUES ($1, $2) RETURNING id", user.Name, user.Email,
+	row := tx.QueryRow(ctx,
+		"INSERT INTO users (name, email, canonical_email, country) VALUES ($1, $2, $3, NULLIF($4, '')) RETURNING id",
+		user.Name, user.Email, core.CanonicalEmail(user.Email), user.Country,
 	)
 
 	err = row.Scan(&user.ID)
+	if IsConstraintError(err, canonicalEmailUniqueIndex) {
+		return errors.ErrDuplicateAccount
+	}
 
 	return err
 }
 
-func(u *UserRepository) FindUserByID(ctx context.Context, id string) (*core.User, error) {
+func (u *UserRepository) FindUserByID(ctx context.Context, id string) (*core.User, error) {
 	tx, err := u.client.GetTx(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	row := tx.QueryRow(ctx, "SELECT * FROM users WHERE id = $1 AND deleted_at IS NULL", id)
+	row := tx.QueryRow(ctx, `SELECT id, name, email, email_verified_at, COALESCE(country, ''), created_at, updated_at FROM users
+	WHERE id = $1 AND deleted_at IS NULL`, id)
 
 	user := &core.User{}
-	err = row.Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+	err = row.Scan(&user.ID, &user.Name, &user.Email, &user.EmailVerifiedAt, &user.Country, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func(u *UserRepository) FindUserByReferralCode(ctx context.Context, code string) (*core.User, error) {
+func (u *UserRepository) FindUserByReferralCode(ctx context.Context, code string) (*core.User, error) {
 	tx, err := u.client.GetTx(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	row := tx.QueryRow(ctx, `SELECT users.id, users.name, users.email, users.created_at, users.updated_at FROM users 
-	INNER JOIN referral_codes ON users.id = referral_codes.user_id WHERE referral_codes.code = $1 AND referral_codes.deleted_at IS NULL`, code)
+	// retired codes are only looked at to tell revoked codes apart
+	row := tx.QueryRow(ctx, `SELECT users.id, users.name, users.email, users.created_at, users.updated_at, `+liveReferralCode+`,
+	COALESCE(referral_codes.retired_reason, '') FROM users 
+	INNER JOIN referral_codes ON users.id = referral_codes.user_id WHERE `+referralCodeMatch+" "+referralCodeOrder+" LIMIT 1", code)
 
 	user := &core.User{}
-	err = row.Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+	var live bool
+	var retiredReason string
+	err = row.Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt, &live, &retiredReason)
 	if err != nil {
 		return nil, err
 	}
+
+	if !live {
+		if retiredReason == core.CodeRetiredRevoked {
+			return nil, errors.ErrReferralCodeRevoked
+		}
+		return nil, pgx.ErrNoRows
+	}
 	return user, nil
-}
\ No newline at end of file
+}
+
+func (u *UserRepository) VerifyEmail(ctx context.Context, user *core.User) error {
+	tx, err := u.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(ctx, `UPDATE users SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP), updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1 RETURNING email_verified_at, updated_at`, user.ID)
+
+	return row.Scan(&user.EmailVerifiedAt, &user.UpdatedAt)
+}
+
+func (u *UserRepository) DeleteUser(ctx context.Context, user *core.User) error {
+	tx, err := u.client.GetTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE users SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = $1", user.ID)
+
+	return err
+}
+
+func (u *UserRepository) ListDuplicateAccounts(ctx context.Context, limit int) ([]*core.DuplicateAccounts, error) {
+	tx, err := u.client.GetTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the mailboxes with the most recent registrations come first
+	rows, err := tx.Query(ctx, `SELECT users.canonical_email, users.id, users.name, users.email, users.email_verified_at,
+	COALESCE(users.country, ''), users.created_at, users.updated_at FROM users
+	INNER JOIN (
+		SELECT canonical_email, MAX(created_at) AS latest FROM users WHERE deleted_at IS NULL
+		GROUP BY canonical_email HAVING COUNT(*) > 1 ORDER BY latest DESC, canonical_email LIMIT $1
+	) duplicates ON duplicates.canonical_email = users.canonical_email
+	WHERE users.deleted_at IS NULL ORDER BY duplicates.latest DESC, users.canonical_email, users.created_at, users.id`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	duplicates := []*core.DuplicateAccounts{}
+	var last *core.DuplicateAccounts
+	for rows.Next() {
+		var canonicalEmail string
+		user := &core.User{}
+		err = rows.Scan(&canonicalEmail, &user.ID, &user.Name, &user.Email, &user.EmailVerifiedAt, &user.Country, &user.CreatedAt, &user.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if last == nil || last.CanonicalEmail != canonicalEmail {
+			last = &core.DuplicateAccounts{CanonicalEmail: canonicalEmail, Users: []*core.User{}}
+			duplicates = append(duplicates, last)
+		}
+		last.Users = append(last.Users, user)
+	}
+
+	return duplicates, rows.Err()
+}
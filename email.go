@@ -0,0 +1,74 @@
+package aboki_africa_assessment
+
+import "strings"
+
+// mailboxRule is how a provider maps addresses to mailboxes.
+type mailboxRule struct {
+	// Domain replaces the address's domain, for providers serving one mailbox under several domains.
+	Domain string
+	// IgnoreDots is set for providers ignoring dots in the local part.
+	IgnoreDots bool
+	// TagSeparator starts the tag providers drop to deliver tagged addresses, empty for none.
+	TagSeparator string
+}
+
+// defaultMailboxRule is followed for providers without a rule of their own. Whether they deliver tagged
+// addresses to the untagged mailbox is unknown, "+" may just be part of the address, so nothing is dropped.
+var defaultMailboxRule = mailboxRule{}
+
+// mailboxRules are the rules of the providers known to deliver plus-addressed mail.
+var mailboxRules = map[string]mailboxRule{
+	"gmail.com":      {IgnoreDots: true, TagSeparator: "+"},
+	"googlemail.com": {Domain: "gmail.com", IgnoreDots: true, TagSeparator: "+"},
+	"outlook.com":    {TagSeparator: "+"},
+	"hotmail.com":    {TagSeparator: "+"},
+	"live.com":       {TagSeparator: "+"},
+	"icloud.com":     {TagSeparator: "+"},
+	"me.com":         {TagSeparator: "+"},
+	"mac.com":        {TagSeparator: "+"},
+	"fastmail.com":   {TagSeparator: "+"},
+	"protonmail.com": {TagSeparator: "+"},
+	"proton.me":      {TagSeparator: "+"},
+	"pm.me":          {TagSeparator: "+"},
+}
+
+// CanonicalEmail returns the address the email is delivered to: lowercased, without tags for the providers
+// known to drop them and, for Gmail, without dots. Addresses sharing a canonical email belong to the same person.
+// Migration 00026_canonical_email_tags recomputed existing users' by the same rules, they must be changed together.
+func CanonicalEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+
+	local, domain := email[:at], email[at+1:]
+	rule, ok := mailboxRules[domain]
+	if !ok {
+		rule = defaultMailboxRule
+	}
+
+	if rule.Domain != "" {
+		domain = rule.Domain
+	}
+	if rule.TagSeparator != "" {
+		if i := strings.Index(local, rule.TagSeparator); i >= 0 {
+			local = local[:i]
+		}
+	}
+	if rule.IgnoreDots {
+		local = strings.Replace(local, ".", "", -1)
+	}
+	return local + "@" + domain
+}
+
+// SameMailbox reports whether the two addresses deliver to the same mailbox.
+func SameMailbox(a, b string) bool {
+	return CanonicalEmail(a) == CanonicalEmail(b)
+}
+
+// DuplicateAccounts are the users registered with addresses of the same mailbox.
+type DuplicateAccounts struct {
+	CanonicalEmail string  `json:"canonical_email"`
+	Users          []*User `json:"users"`
+}
@@ -1,14 +1,55 @@
-package errors 
+package errors
 
 import (
 	"github.com/pkg/errors"
 )
 
 var (
-	ErrGeneric           	   = errors.New("something went wrong")
-	ErrCreateUserFailed  	   = errors.New("failed to create user")
+	ErrGeneric                   = errors.New("something went wrong")
+	ErrCreateUserFailed          = errors.New("failed to create user")
 	ErrTransactionFailed         = errors.New("transaction failed")
-	ErrInsufficientFunds 	   = errors.New("insufficient funds for the operation you're trying to perform")
+	ErrInsufficientFunds         = errors.New("insufficient funds for the operation you're trying to perform")
+	ErrInvalidAmount             = errors.New("points must be greater than zero")
+	ErrBelowMinimumClaim         = errors.New("claim amount is below the minimum bonus claim")
+	ErrBelowMinimumPayout        = errors.New("payout amount is below the minimum payout")
+	ErrPayoutFailed              = errors.New("payout failed")
+	ErrPayoutNotFound            = errors.New("payout not found")
+	ErrInvalidSignature          = errors.New("invalid webhook signature")
+	ErrInvalidVerificationToken  = errors.New("invalid or expired email verification token")
+	ErrTransactionNotFound       = errors.New("transaction not found")
+	ErrNotReversible             = errors.New("only transfers can be reversed")
+	ErrReversalExceedsAmount     = errors.New("reversal exceeds the amount left to reverse on the transaction")
+	ErrInvalidCursor             = errors.New("invalid pagination cursor")
+	ErrUnbalancedEntry           = errors.New("journal entry postings must sum to zero")
+	ErrReconciliationRunNotFound = errors.New("reconciliation run not found")
+	ErrHoldNotFound              = errors.New("hold not found")
+	ErrHoldNotActive             = errors.New("hold was already captured, voided or expired")
+	ErrHoldExpired               = errors.New("hold has expired")
+	ErrCaptureExceedsHold        = errors.New("capture exceeds the points held")
+	ErrScheduledTransferNotFound = errors.New("scheduled transfer not found")
+	ErrInvalidSchedule           = errors.New("a schedule needs either a future run_at or a valid cron rule")
+	ErrScheduleCompleted         = errors.New("scheduled transfer has already run")
+	ErrRewardRuleNotFound        = errors.New("reward rule not found")
+	ErrCampaignNotFound          = errors.New("campaign not found")
+	ErrCampaignCodeTaken         = errors.New("campaign code is already in use")
+	ErrCampaignSponsorNotFound   = errors.New("campaign sponsor not found")
+	ErrUserNotFound              = errors.New("user not found")
+	ErrReferralCodeTaken         = errors.New("referral code is already in use")
+	ErrReferralCodeChangeLimit   = errors.New("referral code was changed too many times recently, try again later")
+	ErrReferralCodeNotFound      = errors.New("referral code not found")
+	ErrReferralCodeRevoked       = errors.New("referral code has been revoked")
+	ErrReferralCodeExhausted     = errors.New("referral code has reached its maximum number of redemptions")
+	ErrReferralCodeExpired       = errors.New("referral code has expired")
+	ErrReferralCodeLimitTooHigh  = errors.New("max_redemptions is above the cap on referral codes")
+	ErrEmailDomainNotAllowed     = errors.New("referral code is not valid for this email domain")
+	ErrReferralNotFound          = errors.New("referral not found")
+	ErrReferralClawedBack        = errors.New("referral has already been clawed back")
+	ErrSelfReferral              = errors.New("users can't refer themselves")
+	ErrAlreadyReferred           = errors.New("user already has a referrer")
+	ErrReferralCycle             = errors.New("referral would make the referral chain loop back on itself")
+	ErrFraudReviewNotFound       = errors.New("fraud review not found")
+	ErrFraudReviewClosed         = errors.New("fraud review is not held")
+	ErrDuplicateAccount          = errors.New("an account already exists for this email address")
 )
 
 func New(message string) error {
@@ -0,0 +1,65 @@
+package aboki_africa_assessment
+
+import (
+	"context"
+	"time"
+)
+
+// Fraud review states. CLEAR referrals scored below the hold threshold, HELD ones wait in the review
+// queue until a reviewer APPROVEs or REJECTs them.
+const (
+	FraudStatusClear    = "CLEAR"
+	FraudStatusHeld     = "HELD"
+	FraudStatusApproved = "APPROVED"
+	FraudStatusRejected = "REJECTED"
+)
+
+// RegistrationSignals is what was seen of a user's registration. The IP and device fingerprint are only
+// kept hashed, Email and Name are read from the user.
+type RegistrationSignals struct {
+	UserID         string    `json:"user_id"`
+	ReferrerID     string    `json:"referrer_id,omitempty"`
+	ReferralCodeID string    `json:"referral_code_id,omitempty"`
+	IPHash         string    `json:"ip_hash"`
+	UserAgent      string    `json:"user_agent"`
+	DeviceHash     string    `json:"device_hash"`
+	Email          string    `json:"email"`
+	Name           string    `json:"name"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// FraudHit is a fraud rule matching a referral and what it added to the referral's score.
+type FraudHit struct {
+	Rule   string `json:"rule"`
+	Score  int    `json:"score"`
+	Detail string `json:"detail"`
+}
+
+// FraudReview is the fraud score of a referral, along with the review of referrals held for scoring too high.
+type FraudReview struct {
+	ID         string     `json:"id"`
+	ReferralID string     `json:"referral_id"`
+	ReferrerID string     `json:"referrer_id"`
+	RefereeID  string     `json:"referee_id"`
+	Score      int        `json:"score"`
+	Hits       []FraudHit `json:"hits"`
+	Status     string     `json:"status"`
+	Note       string     `json:"note"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type FraudRepository interface {
+	CreateSignals(ctx context.Context, signals *RegistrationSignals) error
+	FindSignals(ctx context.Context, userID string) (*RegistrationSignals, error)
+	// ListReferredSignals returns the signals of the users the referrer referred since the given time, newest first.
+	ListReferredSignals(ctx context.Context, referrerID string, since time.Time) ([]*RegistrationSignals, error)
+	// CountDeviceRegistrations returns how many users registered from the device since the given time.
+	CountDeviceRegistrations(ctx context.Context, deviceHash string, since time.Time) (int, error)
+	CreateFraudReview(ctx context.Context, review *FraudReview) error
+	// FindFraudReviewByID returns the review locked for the rest of the surrounding transaction.
+	FindFraudReviewByID(ctx context.Context, id string) (*FraudReview, error)
+	// ListFraudReviews returns the reviews in the given status, oldest first so the queue is worked in order.
+	ListFraudReviews(ctx context.Context, status string, limit int) ([]*FraudReview, error)
+	UpdateFraudReview(ctx context.Context, review *FraudReview) error
+}
@@ -0,0 +1,135 @@
+// Package fraud scores referrals for signs of signup rings: referees registering from the referrer's or
+// each other's IP or device, and batches of look-alike email addresses. Each rule adds to the score of the
+// referrals it matches, referrals scoring too high have their rewards held for review.
+package fraud
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Rule is a check scoring referrals for one sign of fraud.
+type Rule interface {
+	Name() string
+	// Score returns what the rule adds to the referral's score, zero when it doesn't match, and why.
+	Score(in *Input) (int, string)
+}
+
+// Input is what rules know of a referral.
+type Input struct {
+	// Signals are the referee's.
+	Signals *core.RegistrationSignals
+	// Referrer are the referrer's signals, nil when they registered before signals were recorded.
+	Referrer *core.RegistrationSignals
+	// Referred are the signals of the other users the referrer referred within the window.
+	Referred []*core.RegistrationSignals
+	// DeviceRegistrations is how many other users registered from the referee's device within the window.
+	DeviceRegistrations int
+}
+
+type Engine struct {
+	repository core.FraudRepository
+	hashKey    []byte
+	window     time.Duration
+
+	mu    sync.RWMutex
+	rules []Rule
+}
+
+// New returns an engine scoring with the default rules, comparing referrals with the registrations of the
+// past window. hashKey keys the hashes kept of IPs and device fingerprints.
+func New(repository core.FraudRepository, hashKey string, window time.Duration) *Engine {
+	return &Engine{
+		repository: repository,
+		hashKey:    []byte(hashKey),
+		window:     window,
+		rules:      DefaultRules(),
+	}
+}
+
+// Register adds the rule to the ones referrals are scored with.
+func (e *Engine) Register(rule Rule) {
+	e.mu.Lock()
+	e.rules = append(e.rules, rule)
+	e.mu.Unlock()
+}
+
+// Rules returns the rules referrals are scored with.
+func (e *Engine) Rules() []Rule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.rules
+}
+
+// Signals returns the signals of the user's registration, with the IP and device fingerprint hashed.
+func (e *Engine) Signals(user *core.User, ip, userAgent, deviceID string) *core.RegistrationSignals {
+	return &core.RegistrationSignals{
+		UserID:     user.ID,
+		IPHash:     e.hash(ip),
+		UserAgent:  userAgent,
+		DeviceHash: e.hash(deviceID),
+		Email:      user.Email,
+		Name:       user.Name,
+	}
+}
+
+// Score scores the referral of the user the signals are of to their ReferrerID, returning the total along
+// with the rules that matched.
+func (e *Engine) Score(ctx context.Context, signals *core.RegistrationSignals) (int, []core.FraudHit, error) {
+	in := &Input{Signals: signals, Referred: []*core.RegistrationSignals{}}
+	referrer, err := e.repository.FindSignals(ctx, signals.ReferrerID)
+	if err != nil && err != pgx.ErrNoRows {
+		return 0, nil, err
+	}
+	in.Referrer = referrer
+
+	since := time.Now().Add(-e.window)
+	referred, err := e.repository.ListReferredSignals(ctx, signals.ReferrerID, since)
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, other := range referred {
+		if other.UserID != signals.UserID {
+			in.Referred = append(in.Referred, other)
+		}
+	}
+
+	if signals.DeviceHash != "" {
+		n, err := e.repository.CountDeviceRegistrations(ctx, signals.DeviceHash, since)
+		if err != nil {
+			return 0, nil, err
+		}
+		in.DeviceRegistrations = n
+	}
+
+	score, hits := 0, []core.FraudHit{}
+	for _, rule := range e.Rules() {
+		points, detail := rule.Score(in)
+		if points <= 0 {
+			continue
+		}
+		score += points
+		hits = append(hits, core.FraudHit{Rule: rule.Name(), Score: points, Detail: detail})
+	}
+
+	return score, hits, nil
+}
+
+// hash keys the hash so IPs and fingerprints can't be recovered by hashing every possible value. Missing
+// values stay empty so they never match each other.
+func (e *Engine) hash(value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, e.hashKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
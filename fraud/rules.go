@@ -0,0 +1,161 @@
+package fraud
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultRules returns the rules engines score with unless told otherwise.
+func DefaultRules() []Rule {
+	return []Rule{
+		ReferrerMatch{Points: 50},
+		SharedIP{Points: 20, MaxPoints: 60},
+		SharedDevice{Points: 30, MaxPoints: 90},
+		PlusAddressing{Points: 10},
+		SequentialNames{Points: 20, MaxPoints: 60},
+	}
+}
+
+// ReferrerMatch matches referees registering from the IP or device their referrer registered from.
+type ReferrerMatch struct {
+	Points int
+}
+
+func (ReferrerMatch) Name() string { return "referrer_match" }
+
+func (r ReferrerMatch) Score(in *Input) (int, string) {
+	if in.Referrer == nil {
+		return 0, ""
+	}
+
+	switch {
+	case sameHash(in.Signals.DeviceHash, in.Referrer.DeviceHash):
+		return r.Points, "registered from the referrer's device"
+	case sameHash(in.Signals.IPHash, in.Referrer.IPHash):
+		return r.Points, "registered from the referrer's IP"
+	}
+	return 0, ""
+}
+
+// SharedIP matches referees registering from the IP other users the referrer referred registered from,
+// scoring Points for each of them up to MaxPoints.
+type SharedIP struct {
+	Points    int
+	MaxPoints int
+}
+
+func (SharedIP) Name() string { return "shared_ip" }
+
+func (r SharedIP) Score(in *Input) (int, string) {
+	n := 0
+	for _, other := range in.Referred {
+		if sameHash(in.Signals.IPHash, other.IPHash) {
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, ""
+	}
+	return capPoints(n*r.Points, r.MaxPoints), fmt.Sprintf("%d other referees registered from the same IP", n)
+}
+
+// SharedDevice matches referees registering from a device other users registered from, whoever referred
+// them, scoring Points for each of them up to MaxPoints.
+type SharedDevice struct {
+	Points    int
+	MaxPoints int
+}
+
+func (SharedDevice) Name() string { return "shared_device" }
+
+func (r SharedDevice) Score(in *Input) (int, string) {
+	if in.DeviceRegistrations == 0 {
+		return 0, ""
+	}
+	return capPoints(in.DeviceRegistrations*r.Points, r.MaxPoints),
+		fmt.Sprintf("%d other users registered from the same device", in.DeviceRegistrations)
+}
+
+// PlusAddressing matches referees registering with a tagged address, e.g. jane+3@example.com, the
+// cheapest way to make up any number of addresses delivered to one mailbox.
+type PlusAddressing struct {
+	Points int
+}
+
+func (PlusAddressing) Name() string { return "plus_addressing" }
+
+func (r PlusAddressing) Score(in *Input) (int, string) {
+	local, _ := splitEmail(in.Signals.Email)
+	if !strings.Contains(local, "+") {
+		return 0, ""
+	}
+	return r.Points, "email address is tagged"
+}
+
+// SequentialNames matches referees whose email address or name only differs from other referees of the
+// referrer by a trailing number, e.g. jane1@example.com and jane2@example.com, scoring Points for each of
+// them up to MaxPoints.
+type SequentialNames struct {
+	Points    int
+	MaxPoints int
+}
+
+func (SequentialNames) Name() string { return "sequential_names" }
+
+func (r SequentialNames) Score(in *Input) (int, string) {
+	local, domain := splitEmail(in.Signals.Email)
+	emailStem, numberedEmail := numberStem(local)
+	nameStem, numberedName := numberStem(in.Signals.Name)
+	if !numberedEmail && !numberedName {
+		return 0, ""
+	}
+
+	n := 0
+	for _, other := range in.Referred {
+		otherLocal, otherDomain := splitEmail(other.Email)
+		if stem, ok := numberStem(otherLocal); numberedEmail && ok && stem == emailStem && otherDomain == domain {
+			n++
+			continue
+		}
+		if stem, ok := numberStem(other.Name); numberedName && ok && stem == nameStem {
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, ""
+	}
+	return capPoints(n*r.Points, r.MaxPoints), fmt.Sprintf("%d other referees are numbered the same way", n)
+}
+
+// splitEmail returns the lowercased local part and domain of the address.
+func splitEmail(email string) (string, string) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email, ""
+	}
+	return email[:at], email[at+1:]
+}
+
+// numberStem returns what is left of s, lowercased, without its trailing number and whether it had one.
+// A bare number has no stem to compare.
+func numberStem(s string) (string, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	stem := strings.TrimRight(s, "0123456789")
+	if len(stem) == len(s) {
+		return s, false
+	}
+	stem = strings.TrimRight(stem, " ._-")
+	return stem, stem != ""
+}
+
+func sameHash(a, b string) bool {
+	return a != "" && a == b
+}
+
+func capPoints(points, max int) int {
+	if max > 0 && points > max {
+		return max
+	}
+	return points
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// redeemCampaignCode registers the user with the campaign the code belongs to, referring them to the
+// campaign's sponsor if it has one. While the campaign runs and has budget left, the campaign's rule
+// rewards the sponsor, or the user for campaigns without a sponsor, out of that budget. Registrations
+// outside the window or past the budget still go through, they just earn nothing.
+// Sponsor referrals go the way of any other referral: they're scored for fraud and the sponsor's reward is
+// held until the user completes the qualifying action, and taken back if the referral is clawed back. The
+// budget is spent as the code is redeemed, whether or not the reward is ever released.
+func (h *Handler) redeemCampaignCode(ctx context.Context, campaign *core.Campaign, code string, user *core.User, signals *core.RegistrationSignals, logger *log.Entry) error {
+	logger = logger.WithField("campaign_id", campaign.ID)
+	redemption := &core.CampaignRedemption{
+		CampaignID:    campaign.ID,
+		Code:          code,
+		UserID:        user.ID,
+		BeneficiaryID: user.ID,
+	}
+
+	var referral *core.Referral
+	var sponsorPoint *core.Point
+	if campaign.SponsorUserID != "" {
+		err := h.checkReferral(ctx, campaign.SponsorUserID, user, logger)
+		if err == errors.ErrUserNotFound {
+			return errors.ErrCampaignSponsorNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		signals.ReferrerID = campaign.SponsorUserID
+		review, err := h.scoreReferral(ctx, signals, logger)
+		if err != nil {
+			return err
+		}
+
+		referral = h.newReferral(campaign.SponsorUserID, user.ID)
+		referral.CampaignID = campaign.ID
+		if review.Status == core.FraudStatusHeld {
+			referral.Status = core.ReferralStatusHeld
+		}
+
+		err = h.referralRepository.CreateReferral(ctx, referral)
+		if isReferralIntegrityError(err) {
+			return err
+		}
+		if err != nil {
+			logger.WithError(err).Error("failed to create user referral")
+			return errors.ErrGeneric
+		}
+
+		review.ReferralID = referral.ID
+		if err = h.fraudRepository.CreateFraudReview(ctx, review); err != nil {
+			logger.WithError(err).Error("failed to record referral fraud score")
+			return errors.ErrGeneric
+		}
+
+		sponsorPoint, err = h.pointRepository.LockPoint(ctx, campaign.SponsorUserID)
+		if err != nil {
+			logger.WithError(err).Error("failed to lock user point")
+			return errors.ErrGeneric
+		}
+
+		sponsorPoint.IncreaseUserReferrals()
+		redemption.ReferralID = referral.ID
+		redemption.BeneficiaryID = campaign.SponsorUserID
+	}
+
+	campaign.Registrations++
+	rule := h.rewardRules.CampaignRule(campaign.ID)
+	if rule != nil && campaign.IsRunning(time.Now()) {
+		points := rule.Reward(campaign.Registrations)
+		if left := campaign.RemainingBudget(); left < points {
+			points = left
+		}
+
+		if points > 0 {
+			var err error
+			if referral != nil {
+				err = h.grantCampaignReward(ctx, referral, rule, points, sponsorPoint, redemption, logger)
+			} else {
+				err = h.postCampaignReward(ctx, campaign, rule, points, redemption, logger)
+			}
+			if err != nil {
+				return err
+			}
+
+			campaign.SpentPoints += points
+			redemption.RuleID = rule.ID
+			redemption.RuleVersion = rule.Version
+			redemption.Points = points
+		}
+	}
+
+	if err := h.campaignRepository.UpdateCampaignUsage(ctx, campaign); err != nil {
+		logger.WithError(err).Error("failed to update campaign usage")
+		return errors.ErrGeneric
+	}
+
+	if err := h.campaignRepository.CreateRedemption(ctx, redemption); err != nil {
+		logger.WithError(err).Error("failed to record campaign redemption")
+		return errors.ErrGeneric
+	}
+
+	if sponsorPoint != nil {
+		if err := h.pointRepository.UpdatePoint(ctx, sponsorPoint); err != nil {
+			logger.WithError(err).Error("failed to update user point")
+			return errors.ErrGeneric
+		}
+	}
+
+	return nil
+}
+
+// grantCampaignReward records the sponsor's reward for the campaign referral as a referral reward, posting it
+// unless the referral is pending or held, in which case it's posted once the referee qualifies.
+func (h *Handler) grantCampaignReward(ctx context.Context, referral *core.Referral, rule *core.RewardRule, points int,
+	sponsorPoint *core.Point, redemption *core.CampaignRedemption, logger *log.Entry) error {
+	reward := &core.ReferralReward{
+		ReferralID:  referral.ID,
+		UserID:      referral.ReferrerID,
+		RuleID:      rule.ID,
+		RuleVersion: rule.Version,
+		Level:       1,
+		Points:      points,
+		Status:      core.RewardStatusPending,
+		CampaignID:  rule.CampaignID,
+	}
+
+	if referral.Status == core.ReferralStatusQualified {
+		if err := h.postReferralReward(ctx, referral, reward, logger); err != nil {
+			return err
+		}
+		reward.Status = core.RewardStatusReleased
+		redemption.JournalEntryID = reward.JournalEntryID
+		sponsorPoint.AddBonus(points)
+		sponsorPoint.Paid = false
+	}
+
+	if err := h.rewardRuleRepository.CreateReferralReward(ctx, reward); err != nil {
+		logger.WithError(err).Error("failed to record referral reward")
+		return errors.ErrGeneric
+	}
+
+	return nil
+}
+
+// postCampaignReward pays the user of a campaign without a sponsor their reward right away, there's no
+// referral for it to wait on.
+func (h *Handler) postCampaignReward(ctx context.Context, campaign *core.Campaign, rule *core.RewardRule, points int,
+	redemption *core.CampaignRedemption, logger *log.Entry) error {
+	entry := core.NewJournalEntry(core.EntryTypeCampaign, "", core.SystemCampaignsAccount,
+		core.UserBonusAccount(redemption.BeneficiaryID), points)
+	entry.Description = fmt.Sprintf("campaign %s, reward rule %s v%d", campaign.ID, rule.ID, rule.Version)
+	if err := h.ledgerRepository.PostEntry(ctx, entry); err != nil {
+		logger.WithError(err).Error("failed to post campaign reward")
+		return errors.ErrGeneric
+	}
+
+	redemption.JournalEntryID = entry.ID
+	return nil
+}
@@ -0,0 +1,389 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/errors"
+
+	"github.com/jackc/pgx/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+// debtorsBatch is how many debts SettleDebts settles per run.
+const debtorsBatch = 100
+
+func (r *ClawbackRequest) Validate() error {
+	if !core.ClawbackReasons[r.Reason] {
+		return fmt.Errorf("reason must be one of %s, %s or %s", core.ClawbackReasonFraud, core.ClawbackReasonAccountDeleted, core.ClawbackReasonOther)
+	}
+
+	if r.Reason == core.ClawbackReasonOther && r.Note == "" {
+		return fmt.Errorf("a note is required for %s clawbacks", core.ClawbackReasonOther)
+	}
+
+	return nil
+}
+
+// ClawbackReferral takes back the rewards granted for the referral, e.g. once its referee turned out to be fake.
+func (h *Handler) ClawbackReferral(ctx context.Context, input *ClawbackRequest, logger *log.Entry) (*core.Clawback, error) {
+	tx, err := h.beginTxFunc()
+	if err != nil {
+		logger.WithError(err).Error("failed to start transaction")
+		return nil, errors.ErrGeneric
+	}
+	defer tx.Rollback(ctx)
+
+	ctx = context.WithValue(ctx, core.TxContextKey, tx)
+	referral, err := h.referralRepository.FindReferralByID(ctx, input.ReferralID)
+	if err == pgx.ErrNoRows {
+		return nil, errors.ErrReferralNotFound
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to find referral")
+		return nil, errors.ErrGeneric
+	}
+
+	clawback, err := h.clawBack(ctx, referral, input.Reason, input.Note, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.WithError(err).Error("failed to commit transaction")
+		return nil, errors.ErrGeneric
+	}
+
+	return clawback, nil
+}
+
+// ListClawbacks returns the most recent clawbacks, newest first.
+func (h *Handler) ListClawbacks(ctx context.Context, limit int, logger *log.Entry) ([]*core.Clawback, error) {
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	clawbacks, err := h.clawbackRepository.ListClawbacks(ctx, limit)
+	if err != nil {
+		logger.WithError(err).Error("failed to list clawbacks")
+		return nil, errors.ErrGeneric
+	}
+	return clawbacks, nil
+}
+
+// ListDroppedReferrals reports the referrals dropped as duplicate or self-referrals that still have released
+// rewards to claw back, oldest first.
+func (h *Handler) ListDroppedReferrals(ctx context.Context, limit int, logger *log.Entry) ([]*core.DroppedReferral, error) {
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	dropped, err := h.referralRepository.ListDroppedReferrals(ctx, limit)
+	if err != nil {
+		logger.WithError(err).Error("failed to list dropped referrals")
+		return nil, errors.ErrGeneric
+	}
+	return dropped, nil
+}
+
+// DeleteUser soft-deletes the user and revokes their referral code. A user deleting their account within the
+// configured window of being referred claws back the rewards for referring them.
+func (h *Handler) DeleteUser(ctx context.Context, userID string, logger *log.Entry) error {
+	tx, err := h.beginTxFunc()
+	if err != nil {
+		logger.WithError(err).Error("failed to start transaction")
+		return errors.ErrGeneric
+	}
+	defer tx.Rollback(ctx)
+
+	ctx = context.WithValue(ctx, core.TxContextKey, tx)
+	user, err := h.userRepository.FindUserByID(ctx, userID)
+	if err == pgx.ErrNoRows {
+		return errors.ErrUserNotFound
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to find user")
+		return errors.ErrGeneric
+	}
+
+	if err = h.userRepository.DeleteUser(ctx, user); err != nil {
+		logger.WithError(err).Error("failed to delete user")
+		return errors.ErrGeneric
+	}
+
+	code, err := h.referralCodeRepository.FindReferralCodeByUserID(ctx, user.ID)
+	switch {
+	case err == nil:
+		if err = h.referralCodeRepository.RetireReferralCode(ctx, code, core.CodeRetiredRevoked, nil); err != nil {
+			logger.WithError(err).Error("failed to revoke referral code")
+			return errors.ErrGeneric
+		}
+	case err != pgx.ErrNoRows:
+		logger.WithError(err).Error("failed to find referral code")
+		return errors.ErrGeneric
+	}
+
+	if window := h.config.Clawback.DeletionWindow; window > 0 {
+		referral, err := h.referralRepository.FindReferralByReferee(ctx, user.ID)
+		switch {
+		case err == nil:
+			if time.Since(referral.CreatedAt) <= window {
+				if _, err = h.clawBack(ctx, referral, core.ClawbackReasonAccountDeleted, "", logger); err != nil {
+					return err
+				}
+			}
+		case err != pgx.ErrNoRows:
+			logger.WithError(err).Error("failed to find referral")
+			return errors.ErrGeneric
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.WithError(err).Error("failed to commit transaction")
+		return errors.ErrGeneric
+	}
+
+	return nil
+}
+
+// clawBack takes the referral out of its referrer's referrals and reverses its rewards along with the
+// milestone rewards the referrer's lower referral count no longer earns, checking the milestones of every
+// other referrer up the chain the referral paid too. Pending rewards are simply dropped, released ones are
+// taken back by reverseReward, campaign rewards going back to the campaigns account.
+func (h *Handler) clawBack(ctx context.Context, referral *core.Referral, reason, note string, logger *log.Entry) (*core.Clawback, error) {
+	logger = logger.WithField("referral_id", referral.ID)
+	if referral.Status == core.ReferralStatusClawedBack {
+		return nil, errors.ErrReferralClawedBack
+	}
+
+	rewards, err := h.rewardRuleRepository.ListReferralRewards(ctx, referral.ID)
+	if err != nil {
+		logger.WithError(err).Error("failed to list referral rewards")
+		return nil, errors.ErrGeneric
+	}
+
+	// the referrers are locked bottom up, as registrations lock them, so their counts hold until the clawback commits
+	point, err := h.pointRepository.LockPoint(ctx, referral.ReferrerID)
+	if err != nil {
+		logger.WithError(err).Error("failed to lock user point")
+		return nil, errors.ErrGeneric
+	}
+
+	// dropped referrals were taken out of the referrer's count when they were dropped
+	if referral.Status != core.ReferralStatusDropped {
+		point.DecreaseUserReferrals()
+	}
+	// the points of the referrers the referral paid, their cached bonus goes down with the rewards taken back
+	points := map[string]*core.Point{referral.ReferrerID: point}
+	paid := []string{referral.ReferrerID}
+	for _, reward := range rewards {
+		if reward.Status != core.RewardStatusReleased || points[reward.UserID] != nil {
+			continue
+		}
+
+		points[reward.UserID], err = h.pointRepository.LockPoint(ctx, reward.UserID)
+		if err != nil {
+			logger.WithError(err).Error("failed to lock user point")
+			return nil, errors.ErrGeneric
+		}
+		paid = append(paid, reward.UserID)
+	}
+
+	for _, userID := range paid {
+		unearned, err := h.unearnedMilestoneRewards(ctx, referral, userID, points[userID].NumberOfReferredUsers, logger)
+		if err != nil {
+			return nil, err
+		}
+		rewards = append(rewards, unearned...)
+	}
+
+	clawback := &core.Clawback{
+		ReferralID: referral.ID,
+		Reason:     reason,
+		Note:       note,
+		Rewards:    []*core.ReferralReward{},
+	}
+	for _, reward := range rewards {
+		switch reward.Status {
+		case core.RewardStatusReleased:
+			fromBonus, debt, err := h.reverseReward(ctx, referral, reward, reason, logger)
+			if err != nil {
+				return nil, err
+			}
+			points[reward.UserID].DeductBonus(fromBonus)
+			clawback.Points += reward.Points
+			clawback.Debt += debt
+		case core.RewardStatusPending:
+		default:
+			continue
+		}
+		clawback.Rewards = append(clawback.Rewards, reward)
+	}
+
+	if err = h.referralRepository.ClawBackReferral(ctx, referral); err != nil {
+		logger.WithError(err).Error("failed to claw back referral")
+		return nil, errors.ErrGeneric
+	}
+
+	if err = h.clawbackRepository.CreateClawback(ctx, clawback); err != nil {
+		logger.WithError(err).Error("failed to record clawback")
+		return nil, errors.ErrGeneric
+	}
+
+	for _, userID := range paid {
+		// the bonus was taken back through the ledger, the user is paid up once none of it is left to claim
+		points[userID].Paid = points[userID].Bonus == 0
+		if err = h.pointRepository.UpdatePoint(ctx, points[userID]); err != nil {
+			logger.WithError(err).Error("failed to update user point")
+			return nil, errors.ErrGeneric
+		}
+	}
+
+	return clawback, nil
+}
+
+// unearnedMilestoneRewards returns the rewards the user was granted, for referrals other than the given one,
+// for reaching milestones above the referral count they're back down to.
+func (h *Handler) unearnedMilestoneRewards(ctx context.Context, referral *core.Referral, userID string, count int, logger *log.Entry) ([]*core.ReferralReward, error) {
+	rewards, err := h.rewardRuleRepository.ListMilestoneRewards(ctx, userID, count)
+	if err != nil {
+		logger.WithError(err).Error("failed to list milestone rewards")
+		return nil, errors.ErrGeneric
+	}
+
+	unearned := []*core.ReferralReward{}
+	for _, reward := range rewards {
+		// the referral's own rewards are taken back with it
+		if reward.ReferralID != referral.ID {
+			unearned = append(unearned, reward)
+		}
+	}
+
+	return unearned, nil
+}
+
+// reverseReward takes a released reward back out of its user's bonus account first, then out of their
+// spendable points and books whatever they already spent as debt. It returns the points taken out of the
+// bonus account and those booked as debt.
+func (h *Handler) reverseReward(ctx context.Context, referral *core.Referral, reward *core.ReferralReward, reason string, logger *log.Entry) (int, int, error) {
+	bonusAccount, err := h.ledgerRepository.LockAccount(ctx, core.UserBonusAccount(reward.UserID))
+	if err != nil {
+		logger.WithError(err).Error("failed to lock bonus account")
+		return 0, 0, errors.ErrGeneric
+	}
+
+	if _, err = h.ledgerRepository.LockAccount(ctx, core.UserPointsAccount(reward.UserID)); err != nil {
+		logger.WithError(err).Error("failed to lock points account")
+		return 0, 0, errors.ErrGeneric
+	}
+
+	available, err := h.pointRepository.GetPointsBalance(ctx, reward.UserID)
+	if err != nil {
+		logger.WithError(err).Error("failed to get user points balance")
+		return 0, 0, errors.ErrGeneric
+	}
+
+	fromBonus := minPoints(bonusAccount.Balance, reward.Points)
+	fromPoints := minPoints(available, reward.Points-fromBonus)
+	debt := reward.Points - fromBonus - fromPoints
+
+	account, _ := rewardSource(reward)
+	entry := &core.JournalEntry{
+		Type:        core.EntryTypeClawback,
+		ReferenceID: referral.ID,
+		Description: fmt.Sprintf("%s, reward %s", reason, reward.ID),
+		Postings:    []*core.Posting{{AccountCode: account, Amount: reward.Points}},
+	}
+	for _, leg := range []struct {
+		account string
+		points  int
+	}{
+		{core.UserBonusAccount(reward.UserID), fromBonus},
+		{core.UserPointsAccount(reward.UserID), fromPoints},
+		{core.UserDebtAccount(reward.UserID), debt},
+	} {
+		if leg.points > 0 {
+			entry.Postings = append(entry.Postings, &core.Posting{AccountCode: leg.account, Amount: -leg.points})
+		}
+	}
+
+	if err = h.ledgerRepository.PostEntry(ctx, entry); err != nil {
+		logger.WithError(err).Error("failed to post clawback")
+		return 0, 0, errors.ErrGeneric
+	}
+
+	return fromBonus, debt, nil
+}
+
+// SettleDebts pays what users owe for clawed back rewards out of the points they earned since.
+func (h *Handler) SettleDebts(ctx context.Context) error {
+	debtors, err := h.ledgerRepository.ListDebtors(ctx, debtorsBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range debtors {
+		if err = h.settleDebt(ctx, userID); err != nil {
+			log.WithError(err).WithField("user_id", userID).Error("failed to settle debt")
+		}
+	}
+
+	return nil
+}
+
+func (h *Handler) settleDebt(ctx context.Context, userID string) error {
+	tx, err := h.beginTxFunc()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	ctx = context.WithValue(ctx, core.TxContextKey, tx)
+	// the points account is locked before the debt account, as clawbacks do, so the two can't deadlock
+	if _, err = h.ledgerRepository.LockAccount(ctx, core.UserPointsAccount(userID)); err != nil {
+		return err
+	}
+
+	debtAccount, err := h.ledgerRepository.LockAccount(ctx, core.UserDebtAccount(userID))
+	if err != nil {
+		return err
+	}
+
+	available, err := h.pointRepository.GetPointsBalance(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// the spendable balance is net of the debt, so adding it back gives what can go towards it
+	owed := -debtAccount.Balance
+	amount := minPoints(available+owed, owed)
+	if amount == 0 {
+		return nil
+	}
+
+	entry := core.NewJournalEntry(core.EntryTypeDebtRepayment, "", core.UserPointsAccount(userID), core.UserDebtAccount(userID), amount)
+	if err = h.ledgerRepository.PostEntry(ctx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// minPoints returns the smaller of a and b, never less than zero.
+func minPoints(a, b int) int {
+	if b < a {
+		a = b
+	}
+	if a < 0 {
+		return 0
+	}
+	return a
+}
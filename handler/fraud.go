@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/errors"
+
+	"github.com/jackc/pgx/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+// ListFraudReviews returns the fraud reviews in the given status, oldest first.
+func (h *Handler) ListFraudReviews(ctx context.Context, status string, limit int, logger *log.Entry) ([]*core.FraudReview, error) {
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	reviews, err := h.fraudRepository.ListFraudReviews(ctx, status, limit)
+	if err != nil {
+		logger.WithError(err).Error("failed to list fraud reviews")
+		return nil, errors.ErrGeneric
+	}
+	return reviews, nil
+}
+
+// ListDuplicateAccounts reports the users registered more than once under addresses of the same mailbox,
+// which registration rejects but users from before canonical emails may still be.
+func (h *Handler) ListDuplicateAccounts(ctx context.Context, limit int, logger *log.Entry) ([]*core.DuplicateAccounts, error) {
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	duplicates, err := h.userRepository.ListDuplicateAccounts(ctx, limit)
+	if err != nil {
+		logger.WithError(err).Error("failed to list duplicate accounts")
+		return nil, errors.ErrGeneric
+	}
+	return duplicates, nil
+}
+
+// ApproveFraudReview releases the held referral of the review as if it never scored too high: its rewards are
+// posted right away without a qualifying action, otherwise they wait for the referee to qualify within what is
+// left of the qualifying window.
+func (h *Handler) ApproveFraudReview(ctx context.Context, input *FraudReviewRequest, logger *log.Entry) (*core.FraudReview, error) {
+	tx, err := h.beginTxFunc()
+	if err != nil {
+		logger.WithError(err).Error("failed to start transaction")
+		return nil, errors.ErrGeneric
+	}
+	defer tx.Rollback(ctx)
+
+	ctx = context.WithValue(ctx, core.TxContextKey, tx)
+	review, referral, err := h.findHeldReferral(ctx, input.ReviewID, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if referral.Status == core.ReferralStatusClawedBack {
+		return nil, errors.ErrReferralClawedBack
+	}
+
+	referral.Status = core.ReferralStatusPending
+	if err = h.referralRepository.UpdateReferralStatus(ctx, referral); err != nil {
+		logger.WithError(err).Error("failed to update referral status")
+		return nil, errors.ErrGeneric
+	}
+
+	if h.qualifyingAction() == core.QualifyNone {
+		if err = h.qualifyReferee(ctx, referral.RefereeID, logger); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = h.closeFraudReview(ctx, review, core.FraudStatusApproved, input.Note, logger); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.WithError(err).Error("failed to commit transaction")
+		return nil, errors.ErrGeneric
+	}
+
+	return review, nil
+}
+
+// RejectFraudReview claws back the held referral of the review as fraudulent. Its rewards were never posted,
+// so this only drops them and takes the referral out of the referrer's count.
+func (h *Handler) RejectFraudReview(ctx context.Context, input *FraudReviewRequest, logger *log.Entry) (*core.FraudReview, error) {
+	tx, err := h.beginTxFunc()
+	if err != nil {
+		logger.WithError(err).Error("failed to start transaction")
+		return nil, errors.ErrGeneric
+	}
+	defer tx.Rollback(ctx)
+
+	ctx = context.WithValue(ctx, core.TxContextKey, tx)
+	review, referral, err := h.findHeldReferral(ctx, input.ReviewID, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// the referee deleting their account may have clawed it back already
+	if referral.Status != core.ReferralStatusClawedBack {
+		if _, err = h.clawBack(ctx, referral, core.ClawbackReasonFraud, input.Note, logger); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = h.closeFraudReview(ctx, review, core.FraudStatusRejected, input.Note, logger); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.WithError(err).Error("failed to commit transaction")
+		return nil, errors.ErrGeneric
+	}
+
+	return review, nil
+}
+
+// scoreReferral scores the referral the signals are of, holding it when it reaches the configured threshold.
+// The returned review is recorded by the caller once the referral exists.
+func (h *Handler) scoreReferral(ctx context.Context, signals *core.RegistrationSignals, logger *log.Entry) (*core.FraudReview, error) {
+	score, hits, err := h.fraudRules.Score(ctx, signals)
+	if err != nil {
+		logger.WithError(err).Error("failed to score referral")
+		return nil, errors.ErrGeneric
+	}
+
+	review := &core.FraudReview{
+		ReferrerID: signals.ReferrerID,
+		RefereeID:  signals.UserID,
+		Score:      score,
+		Hits:       hits,
+		Status:     core.FraudStatusClear,
+	}
+	if threshold := h.config.Fraud.HoldThreshold; threshold > 0 && score >= threshold {
+		review.Status = core.FraudStatusHeld
+		logger.WithField("score", score).Warn("holding referral for fraud review")
+	}
+
+	return review, nil
+}
+
+// findHeldReferral returns the review, locked, along with its referral, errors.ErrFraudReviewClosed once the
+// review was decided.
+func (h *Handler) findHeldReferral(ctx context.Context, reviewID string, logger *log.Entry) (*core.FraudReview, *core.Referral, error) {
+	review, err := h.fraudRepository.FindFraudReviewByID(ctx, reviewID)
+	if err == pgx.ErrNoRows {
+		return nil, nil, errors.ErrFraudReviewNotFound
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to find fraud review")
+		return nil, nil, errors.ErrGeneric
+	}
+
+	if review.Status != core.FraudStatusHeld {
+		return nil, nil, errors.ErrFraudReviewClosed
+	}
+
+	referral, err := h.referralRepository.FindReferralByID(ctx, review.ReferralID)
+	if err != nil {
+		logger.WithError(err).Error("failed to find referral")
+		return nil, nil, errors.ErrGeneric
+	}
+
+	return review, referral, nil
+}
+
+func (h *Handler) closeFraudReview(ctx context.Context, review *core.FraudReview, status, note string, logger *log.Entry) error {
+	now := time.Now()
+	review.Status = status
+	review.Note = note
+	review.ReviewedAt = &now
+	if err := h.fraudRepository.UpdateFraudReview(ctx, review); err != nil {
+		logger.WithError(err).Error("failed to update fraud review")
+		return errors.ErrGeneric
+	}
+	return nil
+}
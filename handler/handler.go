@@ -5,43 +5,73 @@ import (
 	"crypto/rand"
 	"fmt"
 	"io"
+	"time"
 
 	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/config"
 	"github.com/Qalifah/aboki-africa-assessment/errors"
+	"github.com/Qalifah/aboki-africa-assessment/fraud"
+	"github.com/Qalifah/aboki-africa-assessment/rules"
 
 	"github.com/jackc/pgx/v4"
 	log "github.com/sirupsen/logrus"
 )
 
 const (
-	Fail = "Transfer Failed"
-	Success = "Transfer Successful"
+	Fail     = "Transfer Failed"
+	Success  = "Transfer Successful"
 	transfer = "TRANSFER"
-	bonus = "BONUS"
+	bonus    = "BONUS"
+	reversal = "REVERSAL"
+	expiry   = "EXPIRY"
+	promo    = "PROMO"
+	welcome  = "WELCOME"
 )
 
 type Handler struct {
-	userRepository         	core.UserRepository
-	referralCodeRepository	core.ReferralCodeRepository
-	referralRepository 		core.ReferralRepository
-	pointRepository    		core.PointRepository
-	transactionRepository 	core.TransactionRepository
-	beginTxFunc            func() (pgx.Tx, error)
+	userRepository              core.UserRepository
+	referralCodeRepository      core.ReferralCodeRepository
+	referralRepository          core.ReferralRepository
+	pointRepository             core.PointRepository
+	transactionRepository       core.TransactionRepository
+	ledgerRepository            core.LedgerRepository
+	holdRepository              core.HoldRepository
+	scheduledTransferRepository core.ScheduledTransferRepository
+	rewardRuleRepository        core.RewardRuleRepository
+	campaignRepository          core.CampaignRepository
+	clawbackRepository          core.ClawbackRepository
+	fraudRepository             core.FraudRepository
+	rewardRules                 *rules.Engine
+	fraudRules                  *fraud.Engine
+	config                      *config.BaseConfig
+	beginTxFunc                 func() (pgx.Tx, error)
 }
 
-func New(userRepository core.UserRepository, referralCodeRepository	core.ReferralCodeRepository, referralRepository core.ReferralRepository,
-	pointRepository core.PointRepository, transactionRepository core.TransactionRepository, beginTxFunc func() (pgx.Tx, error)) *Handler {
-		return &Handler{
-			userRepository: userRepository,
-			referralRepository: referralRepository,
-			referralCodeRepository: referralCodeRepository,
-			pointRepository: pointRepository,
-			transactionRepository: transactionRepository,
-			beginTxFunc: beginTxFunc,
-		}
+func New(userRepository core.UserRepository, referralCodeRepository core.ReferralCodeRepository, referralRepository core.ReferralRepository,
+	pointRepository core.PointRepository, transactionRepository core.TransactionRepository, ledgerRepository core.LedgerRepository,
+	holdRepository core.HoldRepository, scheduledTransferRepository core.ScheduledTransferRepository,
+	rewardRuleRepository core.RewardRuleRepository, campaignRepository core.CampaignRepository, clawbackRepository core.ClawbackRepository, fraudRepository core.FraudRepository, rewardRules *rules.Engine, fraudRules *fraud.Engine, cfg *config.BaseConfig, beginTxFunc func() (pgx.Tx, error)) *Handler {
+	return &Handler{
+		userRepository:              userRepository,
+		referralRepository:          referralRepository,
+		referralCodeRepository:      referralCodeRepository,
+		pointRepository:             pointRepository,
+		transactionRepository:       transactionRepository,
+		ledgerRepository:            ledgerRepository,
+		holdRepository:              holdRepository,
+		scheduledTransferRepository: scheduledTransferRepository,
+		rewardRuleRepository:        rewardRuleRepository,
+		campaignRepository:          campaignRepository,
+		clawbackRepository:          clawbackRepository,
+		fraudRepository:             fraudRepository,
+		rewardRules:                 rewardRules,
+		fraudRules:                  fraudRules,
+		config:                      cfg,
+		beginTxFunc:                 beginTxFunc,
+	}
 }
 
-func(h *Handler) RegisterUser(ctx context.Context, input *UserRequest, logger *log.Entry) (*core.User, error) {
+func (h *Handler) RegisterUser(ctx context.Context, input *UserRequest, logger *log.Entry) (*Registration, error) {
 	tx, err := h.beginTxFunc()
 	if err != nil {
 		logger.WithError(err).Error("failed to start transaction")
@@ -51,19 +81,24 @@ func(h *Handler) RegisterUser(ctx context.Context, input *UserRequest, logger *l
 
 	ctx = context.WithValue(ctx, core.TxContextKey, tx)
 	user := &core.User{
-		Name:  input.Name,
-		Email: input.Email,
+		Name:    input.Name,
+		Email:   input.Email,
+		Country: input.Country,
 	}
 
 	err = h.userRepository.CreateUser(ctx, user)
+	if err == errors.ErrDuplicateAccount {
+		return nil, err
+	}
 	if err != nil {
 		logger.WithError(err).Error("failed to create user")
 		return nil, errors.ErrCreateUserFailed
 	}
 
 	userRefCode := &core.ReferralCode{
-		UserID: user.ID,
-		Code: GenReferralCode(7),
+		UserID:         user.ID,
+		Code:           GenReferralCode(7),
+		MaxRedemptions: h.maxRedemptions(0),
 	}
 
 	err = h.referralCodeRepository.CreateReferralCode(ctx, userRefCode)
@@ -83,41 +118,37 @@ func(h *Handler) RegisterUser(ctx context.Context, input *UserRequest, logger *l
 		return nil, errors.ErrGeneric
 	}
 
+	registration := &Registration{User: user}
+	signals := h.fraudRules.Signals(user, input.IP, input.UserAgent, input.DeviceID)
 	if input.ReferralCode != nil {
-		referrer, err := h.userRepository.FindUserByReferralCode(ctx, *input.ReferralCode)
-		if err != nil {
-			logger.WithError(err).Error("failed to find user by referral code")
-			return nil, errors.ErrGeneric
-		}
-
-		userReferral := &core.Referral{
-			ReferrerID: referrer.ID,
-			RefereeID: user.ID,
+		campaign, err := h.campaignRepository.FindCampaignByCode(ctx, *input.ReferralCode)
+		switch {
+		case err == nil:
+			err = h.redeemCampaignCode(ctx, campaign, *input.ReferralCode, user, signals, logger)
+		case err == pgx.ErrNoRows:
+			err = h.redeemReferralCode(ctx, *input.ReferralCode, user, signals, logger)
+		default:
+			logger.WithError(err).Error("failed to find campaign by code")
+			err = errors.ErrGeneric
 		}
 
-		err = h.referralRepository.CreateReferral(ctx, userReferral)
-		if err != nil {
-			logger.WithError(err).Error("failed to create user referral")
-			return nil, errors.ErrGeneric
+		switch {
+		case err == nil:
+			registration.WelcomeBonus, err = h.grantWelcomeBonus(ctx, user, input.ClientCountry, campaign, logger)
+		case input.ReferralCodeFromLink && isStaleReferralCode(err):
+			// a link followed a while back doesn't keep the user from registering without a referrer
+			logger.WithError(err).Warn("ignoring referral code from referral link")
+			err = nil
 		}
-
-		refPoint, err := h.pointRepository.FindPointByUserID(ctx, referrer.ID)
 		if err != nil {
-			logger.WithError(err).Error("failed to find user point")
-			return nil, errors.ErrGeneric
-		}
-
-		// increase user's referrals counter and check if the counter is divisible by 3 to add bonus
-		refPoint.IncreaseUserReferrals()
-		if (refPoint.NumberOfReferredUsers > 0) && (refPoint.NumberOfReferredUsers % 3 == 0) {
-			refPoint.AddBonus()
+			return nil, err
 		}
+	}
 
-		err = h.pointRepository.UpdatePoint(ctx, refPoint)
-		if err != nil {
-			logger.WithError(err).Error("failed to update user point")
-			return nil, errors.ErrGeneric
-		}
+	// the user's signals are recorded once their referral is scored so they aren't compared with themselves
+	if err = h.fraudRepository.CreateSignals(ctx, signals); err != nil {
+		logger.WithError(err).Error("failed to record registration signals")
+		return nil, errors.ErrGeneric
 	}
 
 	if err = tx.Commit(ctx); err != nil {
@@ -125,10 +156,109 @@ func(h *Handler) RegisterUser(ctx context.Context, input *UserRequest, logger *l
 		return nil, errors.ErrGeneric
 	}
 
-	return user, nil
+	return registration, nil
 }
 
-func(h *Handler) TransferPoints(ctx context.Context, input *TransferPointsRequest, logger *log.Entry) (string, error) {
+// redeemReferralCode refers the user to the owner of the referral code and rewards the referrers up the chain,
+// or holds their rewards until the user completes the qualifying action or, for referrals scoring too high
+// on the registration's fraud signals, until a reviewer approves them.
+// The code and its owner's point are locked until the registration commits so concurrent registrations, through
+// this or any other of the owner's codes, can't overshoot the code's limits or the owner's reward caps.
+func (h *Handler) redeemReferralCode(ctx context.Context, code string, user *core.User, signals *core.RegistrationSignals, logger *log.Entry) error {
+	refCode, err := h.referralCodeRepository.FindReferralCodeByCode(ctx, code)
+	if err == pgx.ErrNoRows {
+		return errors.ErrReferralCodeNotFound
+	}
+	if err == errors.ErrReferralCodeRevoked {
+		return err
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to find referral code")
+		return errors.ErrGeneric
+	}
+
+	refPoint, err := h.pointRepository.LockPoint(ctx, refCode.UserID)
+	if err != nil {
+		logger.WithError(err).Error("failed to lock user point")
+		return errors.ErrGeneric
+	}
+
+	redemptions, err := h.referralCodeRepository.CountRedemptions(ctx, refCode.UserID)
+	if err != nil {
+		logger.WithError(err).Error("failed to count referral code redemptions")
+		return errors.ErrGeneric
+	}
+
+	if err = checkReferralCodeLimits(refCode, redemptions, h.maxRedemptions(refCode.MaxRedemptions), user.Email, time.Now()); err != nil {
+		return err
+	}
+
+	err = h.checkReferral(ctx, refCode.UserID, user, logger)
+	if err == errors.ErrUserNotFound {
+		// the code outlived its owner
+		return errors.ErrReferralCodeNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = h.referralCodeRepository.IncrementRedemptions(ctx, refCode); err != nil {
+		logger.WithError(err).Error("failed to count referral code redemption")
+		return errors.ErrGeneric
+	}
+
+	signals.ReferrerID, signals.ReferralCodeID = refCode.UserID, refCode.ID
+	review, err := h.scoreReferral(ctx, signals, logger)
+	if err != nil {
+		return err
+	}
+
+	userReferral := h.newReferral(refCode.UserID, user.ID)
+	userReferral.ReferralCodeID = refCode.ID
+	if review.Status == core.FraudStatusHeld {
+		userReferral.Status = core.ReferralStatusHeld
+	}
+
+	err = h.referralRepository.CreateReferral(ctx, userReferral)
+	if isReferralIntegrityError(err) {
+		return err
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to create user referral")
+		return errors.ErrGeneric
+	}
+
+	review.ReferralID = userReferral.ID
+	if err = h.fraudRepository.CreateFraudReview(ctx, review); err != nil {
+		logger.WithError(err).Error("failed to record referral fraud score")
+		return errors.ErrGeneric
+	}
+
+	refPoint.IncreaseUserReferrals()
+	if err = h.grantReferralRewards(ctx, userReferral, refPoint, logger); err != nil {
+		return err
+	}
+
+	err = h.pointRepository.UpdatePoint(ctx, refPoint)
+	if err != nil {
+		logger.WithError(err).Error("failed to update user point")
+		return errors.ErrGeneric
+	}
+
+	return nil
+}
+
+// isStaleReferralCode reports whether err rejected a referral code that no longer refers new users.
+func isStaleReferralCode(err error) bool {
+	switch err {
+	case errors.ErrReferralCodeNotFound, errors.ErrReferralCodeRevoked, errors.ErrReferralCodeExhausted,
+		errors.ErrReferralCodeExpired, errors.ErrEmailDomainNotAllowed:
+		return true
+	}
+	return false
+}
+
+func (h *Handler) TransferPoints(ctx context.Context, input *TransferPointsRequest, logger *log.Entry) (string, error) {
 	tx, err := h.beginTxFunc()
 	if err != nil {
 		logger.WithError(err).Error("failed to start transaction")
@@ -137,37 +267,310 @@ func(h *Handler) TransferPoints(ctx context.Context, input *TransferPointsReques
 	defer tx.Rollback(ctx)
 
 	ctx = context.WithValue(ctx, core.TxContextKey, tx)
-	balance, err := h.pointRepository.GetPointsBalance(ctx, input.SenderID)
+	point, err := h.pointRepository.FindPointByUserID(ctx, input.SenderID)
 	if err != nil {
-		logger.WithError(err).Error("failed to get user points balance")
+		logger.WithError(err).Error("failed to get sender points")
 		return Fail, errors.ErrGeneric
 	}
 
-	point, err := h.pointRepository.FindPointByUserID(ctx, input.SenderID)
+	_, err = h.transferPoints(ctx, input, logger)
+	if err == errors.ErrInsufficientFunds {
+		return Fail + getBonusBalanceStatement(point), err
+	}
 	if err != nil {
-		logger.WithError(err).Error("failed to get sender points")
-		return Fail, errors.ErrGeneric
+		return Fail, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.WithError(err).Error("failed to commit transaction")
+		return Fail, errors.ErrTransactionFailed
+	}
+
+	return Success + getBonusBalanceStatement(point), nil
+
+}
+
+// transferPoints moves points between two users inside the transaction carried by ctx.
+func (h *Handler) transferPoints(ctx context.Context, input *TransferPointsRequest, logger *log.Entry) (*core.Transaction, error) {
+	// a negative amount would move points from the recipient to the sender
+	if input.Points <= 0 {
+		return nil, errors.ErrInvalidAmount
+	}
+
+	// lock the sender's account so the balance can't be spent elsewhere before the transfer is posted
+	_, err := h.ledgerRepository.LockAccount(ctx, core.UserPointsAccount(input.SenderID))
+	if err != nil {
+		logger.WithError(err).Error("failed to lock sender points account")
+		return nil, errors.ErrGeneric
+	}
+
+	balance, err := h.pointRepository.GetPointsBalance(ctx, input.SenderID)
+	if err != nil {
+		logger.WithError(err).Error("failed to get user points balance")
+		return nil, errors.ErrGeneric
 	}
 
 	if balance < input.Points {
-		return Fail + getBonusBalanceStatement(point), errors.ErrInsufficientFunds
+		return nil, errors.ErrInsufficientFunds
 	}
 
 	tran := &core.Transaction{
-		SenderID: input.SenderID,
+		SenderID:    input.SenderID,
 		RecipientID: input.RecipientID,
-		Points: input.Points,
-		Type: transfer,
+		Points:      input.Points,
+		Type:        transfer,
+		ExpiresAt:   h.lotExpiry(h.config.Expiry.Transfer),
 	}
 
 	err = h.transactionRepository.CreateTransaction(ctx, tran)
 	if err != nil {
 		logger.WithError(err).Error("failed transaction")
-		return Fail, errors.ErrTransactionFailed
+		return nil, errors.ErrTransactionFailed
 	}
 
-	return Success + getBonusBalanceStatement(point), nil
+	entry := core.NewJournalEntry(core.EntryTypeTransfer, tran.ID, core.UserPointsAccount(input.SenderID),
+		core.UserPointsAccount(input.RecipientID), input.Points)
+	entry.ExpiresAt = tran.ExpiresAt
+	err = h.ledgerRepository.PostEntry(ctx, entry)
+	if err == errors.ErrInsufficientFunds {
+		return nil, err
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to post transfer")
+		return nil, errors.ErrTransactionFailed
+	}
 
+	if err = h.qualifyByTransaction(ctx, tran, logger); err != nil {
+		return nil, err
+	}
+
+	return tran, nil
+}
+
+// ClaimReferrerBonus moves unclaimed bonus points into the user's spendable points. A zero amount on
+// the input claims the whole outstanding bonus.
+func (h *Handler) ClaimReferrerBonus(ctx context.Context, input *ClaimBonusRequest, logger *log.Entry) (*core.Transaction, error) {
+	tx, err := h.beginTxFunc()
+	if err != nil {
+		logger.WithError(err).Error("failed to start transaction")
+		return nil, errors.ErrGeneric
+	}
+	defer tx.Rollback(ctx)
+
+	ctx = context.WithValue(ctx, core.TxContextKey, tx)
+	point, err := h.pointRepository.FindPointByUserID(ctx, input.UserID)
+	if err != nil {
+		logger.WithError(err).Error("failed to get user points")
+		return nil, errors.ErrGeneric
+	}
+
+	amount := input.Points
+	if amount == 0 {
+		amount = point.Bonus
+	}
+
+	if amount > point.Bonus {
+		return nil, errors.ErrInsufficientFunds
+	}
+
+	if amount <= 0 || amount < h.config.Bonus.MinClaim {
+		return nil, errors.ErrBelowMinimumClaim
+	}
+
+	tran := &core.Transaction{
+		SenderID:    input.UserID,
+		RecipientID: input.UserID,
+		Points:      amount,
+		Type:        bonus,
+		ExpiresAt:   h.lotExpiry(h.config.Expiry.ReferralBonus),
+	}
+
+	err = h.transactionRepository.ClaimReferrerBonus(ctx, tran)
+	if err == errors.ErrInsufficientFunds {
+		return nil, err
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to claim bonus")
+		return nil, errors.ErrTransactionFailed
+	}
+
+	if err = h.qualifyByTransaction(ctx, tran, logger); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.WithError(err).Error("failed to commit transaction")
+		return nil, errors.ErrTransactionFailed
+	}
+
+	return tran, nil
+}
+
+// ReverseTransaction compensates a transfer with a REVERSAL transaction that moves the points back from
+// the recipient to the sender. A zero amount on the input reverses whatever is left of the transfer.
+func (h *Handler) ReverseTransaction(ctx context.Context, input *ReverseTransactionRequest, logger *log.Entry) (*core.Transaction, error) {
+	tx, err := h.beginTxFunc()
+	if err != nil {
+		logger.WithError(err).Error("failed to start transaction")
+		return nil, errors.ErrGeneric
+	}
+	defer tx.Rollback(ctx)
+
+	ctx = context.WithValue(ctx, core.TxContextKey, tx)
+	original, err := h.transactionRepository.FindTransactionByID(ctx, input.TransactionID)
+	if err == pgx.ErrNoRows {
+		return nil, errors.ErrTransactionNotFound
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to find transaction")
+		return nil, errors.ErrGeneric
+	}
+
+	if original.Type != transfer {
+		return nil, errors.ErrNotReversible
+	}
+
+	remaining := original.Points - original.ReversedPoints
+	amount := input.Points
+	if amount == 0 {
+		amount = remaining
+	}
+
+	if amount <= 0 || amount > remaining {
+		return nil, errors.ErrReversalExceedsAmount
+	}
+
+	tran := &core.Transaction{
+		SenderID:    original.RecipientID,
+		RecipientID: original.SenderID,
+		Points:      amount,
+		Type:        reversal,
+		ReversalOf:  original.ID,
+		// the points go back expiring when the transfer's did, reversing doesn't extend their life
+		ExpiresAt: original.ExpiresAt,
+	}
+
+	err = h.transactionRepository.CreateTransaction(ctx, tran)
+	if err != nil {
+		logger.WithError(err).Error("failed to create reversal")
+		return nil, errors.ErrTransactionFailed
+	}
+
+	entry := core.NewJournalEntry(core.EntryTypeReversal, tran.ID, core.UserPointsAccount(original.RecipientID),
+		core.UserPointsAccount(original.SenderID), amount)
+	entry.Description = input.Reason
+	entry.ExpiresAt = tran.ExpiresAt
+	err = h.ledgerRepository.PostEntry(ctx, entry)
+	if err == errors.ErrInsufficientFunds {
+		return nil, err
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to post reversal")
+		return nil, errors.ErrTransactionFailed
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.WithError(err).Error("failed to commit transaction")
+		return nil, errors.ErrTransactionFailed
+	}
+
+	return tran, nil
+}
+
+// grantReferralRewards posts the bonus points the reward rules grant the referrer, and the referrers above
+// them up to the configured depth, for the referral and records which rule, at which version, granted them.
+// The rewards of a pending or held referral are only recorded, they're posted once the referee qualifies.
+func (h *Handler) grantReferralRewards(ctx context.Context, referral *core.Referral, refPoint *core.Point, logger *log.Entry) error {
+	ancestors, err := h.referralRepository.ListAncestors(ctx, referral.RefereeID, h.maxReferralDepth())
+	if err != nil {
+		logger.WithError(err).Error("failed to list referral ancestors")
+		return errors.ErrGeneric
+	}
+
+	pending := referral.Status == core.ReferralStatusPending || referral.Status == core.ReferralStatusHeld
+	now := time.Now()
+	for _, ancestor := range ancestors {
+		point, count := refPoint, refPoint.NumberOfReferredUsers
+		if ancestor.Level > 1 {
+			// ancestors are locked bottom up like the direct referrer, so their reward caps hold too
+			point, err = h.pointRepository.LockPoint(ctx, ancestor.UserID)
+			if err != nil {
+				logger.WithError(err).Error("failed to lock user point")
+				return errors.ErrGeneric
+			}
+			count = 0
+		}
+
+		rewards, err := h.rewardRules.Evaluate(ctx, referral, ancestor.UserID, ancestor.Level, count, now)
+		if err != nil {
+			logger.WithError(err).Error("failed to evaluate reward rules")
+			return errors.ErrGeneric
+		}
+
+		for _, reward := range rewards {
+			reward.Status = core.RewardStatusPending
+			if !pending {
+				if err = h.postReferralReward(ctx, referral, reward, logger); err != nil {
+					return err
+				}
+				reward.Status = core.RewardStatusReleased
+			}
+
+			if err = h.rewardRuleRepository.CreateReferralReward(ctx, reward); err != nil {
+				logger.WithError(err).Error("failed to record referral reward")
+				return errors.ErrGeneric
+			}
+
+			if !pending {
+				point.AddBonus(reward.Points)
+				point.Paid = false
+			}
+		}
+
+		// the direct referrer's point is saved by the caller along with their new referral count
+		if ancestor.Level > 1 && len(rewards) > 0 && !pending {
+			if err = h.pointRepository.UpdatePoint(ctx, point); err != nil {
+				logger.WithError(err).Error("failed to update user point")
+				return errors.ErrGeneric
+			}
+		}
+	}
+
+	return nil
+}
+
+// postReferralReward posts the reward to its user's bonus account.
+func (h *Handler) postReferralReward(ctx context.Context, referral *core.Referral, reward *core.ReferralReward, logger *log.Entry) error {
+	account, entryType := rewardSource(reward)
+	entry := core.NewJournalEntry(entryType, referral.ID, account, core.UserBonusAccount(reward.UserID), reward.Points)
+	entry.Description = fmt.Sprintf("reward rule %s v%d, level %d", reward.RuleID, reward.RuleVersion, reward.Level)
+	if referral.CampaignID != "" {
+		entry.Description = fmt.Sprintf("campaign %s, %s", referral.CampaignID, entry.Description)
+	}
+	if err := h.ledgerRepository.PostEntry(ctx, entry); err != nil {
+		logger.WithError(err).Error("failed to post referral bonus")
+		return errors.ErrGeneric
+	}
+
+	reward.JournalEntryID = entry.ID
+	return nil
+}
+
+// rewardSource returns the account the reward is paid out of and the type of the entries paying it: the
+// campaign's budget for rewards granted by a campaign rule, the referral bonus pool otherwise.
+func rewardSource(reward *core.ReferralReward) (string, string) {
+	if reward.CampaignID != "" {
+		return core.SystemCampaignsAccount, core.EntryTypeCampaign
+	}
+	return core.SystemReferralBonusAccount, core.EntryTypeBonus
+}
+
+// maxReferralDepth is how many levels up the referral chain rewards are granted, at least the direct referrer.
+func (h *Handler) maxReferralDepth() int {
+	if h.config.Referral.MaxDepth < 1 {
+		return 1
+	}
+	return h.config.Referral.MaxDepth
 }
 
 func getBonusBalanceStatement(point *core.Point) string {
@@ -189,4 +592,4 @@ func GenReferralCode(max int) string {
 		b[i] = table[int(b[i])%len(table)]
 	}
 	return string(b)
-}
\ No newline at end of file
+}
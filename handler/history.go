@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// ListTransactions returns a page of the user's transaction history, newest first. The page's
+// NextCursor is empty once there is nothing left to fetch.
+func (h *Handler) ListTransactions(ctx context.Context, input *ListTransactionsRequest, logger *log.Entry) (*TransactionPage, error) {
+	limit := input.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	filter := &core.TransactionFilter{
+		UserID:    input.UserID,
+		Direction: input.Direction,
+		Type:      input.Type,
+		MinPoints: input.MinPoints,
+		MaxPoints: input.MaxPoints,
+		From:      input.From,
+		To:        input.To,
+		// fetch one extra row to know whether there is a next page
+		Limit: limit + 1,
+	}
+
+	if input.Cursor != "" {
+		after, err := decodeCursor(input.Cursor)
+		if err != nil {
+			return nil, errors.ErrInvalidCursor
+		}
+		filter.After = after
+	}
+
+	transactions, err := h.transactionRepository.ListTransactions(ctx, filter)
+	if err != nil {
+		logger.WithError(err).Error("failed to list transactions")
+		return nil, errors.ErrGeneric
+	}
+
+	page := &TransactionPage{Data: transactions}
+	if len(transactions) > limit {
+		page.Data = transactions[:limit]
+		last := page.Data[limit-1]
+		page.NextCursor = encodeCursor(&core.TransactionCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	return page, nil
+}
+
+func encodeCursor(cursor *core.TransactionCursor) string {
+	return encodePosition(cursor.CreatedAt, cursor.ID)
+}
+
+func decodeCursor(s string) (*core.TransactionCursor, error) {
+	createdAt, id, err := decodePosition(s)
+	if err != nil {
+		return nil, err
+	}
+	return &core.TransactionCursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+// encodePosition encodes a position in a (time, id) ordering as an opaque cursor.
+func encodePosition(t time.Time, id string) string {
+	raw := t.UTC().Format(time.RFC3339Nano) + "|" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodePosition(s string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 || !core.IsUUID(parts[1]) {
+		return time.Time{}, "", errors.ErrInvalidCursor
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, "", err
+	}
+
+	return t, parts[1], nil
+}
@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/errors"
+
+	"github.com/jackc/pgx/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultHoldTTL is how long a hold reserves points when no TTL is configured.
+const defaultHoldTTL = 15 * time.Minute
+
+// AuthorizeTransfer reserves points of the sender for a later transfer to the recipient. Held points
+// are left out of the available balance but stay on the sender's account until the hold is captured.
+func (h *Handler) AuthorizeTransfer(ctx context.Context, input *AuthorizeTransferRequest, logger *log.Entry) (*core.Hold, error) {
+	tx, err := h.beginTxFunc()
+	if err != nil {
+		logger.WithError(err).Error("failed to start transaction")
+		return nil, errors.ErrGeneric
+	}
+	defer tx.Rollback(ctx)
+
+	ctx = context.WithValue(ctx, core.TxContextKey, tx)
+	_, err = h.ledgerRepository.LockAccount(ctx, core.UserPointsAccount(input.SenderID))
+	if err != nil {
+		logger.WithError(err).Error("failed to lock sender points account")
+		return nil, errors.ErrGeneric
+	}
+
+	balance, err := h.pointRepository.GetPointsBalance(ctx, input.SenderID)
+	if err != nil {
+		logger.WithError(err).Error("failed to get user points balance")
+		return nil, errors.ErrGeneric
+	}
+
+	if balance < input.Points {
+		return nil, errors.ErrInsufficientFunds
+	}
+
+	ttl := h.config.Holds.TTL
+	if ttl <= 0 {
+		ttl = defaultHoldTTL
+	}
+
+	hold := &core.Hold{
+		SenderID:    input.SenderID,
+		RecipientID: input.RecipientID,
+		Points:      input.Points,
+		Status:      core.HoldStatusActive,
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+
+	if err = h.holdRepository.CreateHold(ctx, hold); err != nil {
+		logger.WithError(err).Error("failed to create hold")
+		return nil, errors.ErrTransactionFailed
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.WithError(err).Error("failed to commit transaction")
+		return nil, errors.ErrTransactionFailed
+	}
+
+	return hold, nil
+}
+
+// CaptureTransfer transfers the held points, or part of them, to the recipient. Capturing closes the
+// hold, points held but not captured become available again.
+func (h *Handler) CaptureTransfer(ctx context.Context, input *CaptureTransferRequest, logger *log.Entry) (*core.Transaction, error) {
+	tx, err := h.beginTxFunc()
+	if err != nil {
+		logger.WithError(err).Error("failed to start transaction")
+		return nil, errors.ErrGeneric
+	}
+	defer tx.Rollback(ctx)
+
+	ctx = context.WithValue(ctx, core.TxContextKey, tx)
+	hold, err := h.findActiveHold(ctx, input.HoldID, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	amount := input.Points
+	if amount == 0 {
+		amount = hold.Points
+	}
+
+	if amount <= 0 || amount > hold.Points {
+		return nil, errors.ErrCaptureExceedsHold
+	}
+
+	_, err = h.ledgerRepository.LockAccount(ctx, core.UserPointsAccount(hold.SenderID))
+	if err != nil {
+		logger.WithError(err).Error("failed to lock sender points account")
+		return nil, errors.ErrGeneric
+	}
+
+	tran := &core.Transaction{
+		SenderID:    hold.SenderID,
+		RecipientID: hold.RecipientID,
+		Points:      amount,
+		Type:        transfer,
+		ExpiresAt:   h.lotExpiry(h.config.Expiry.Transfer),
+	}
+
+	err = h.transactionRepository.CreateTransaction(ctx, tran)
+	if err != nil {
+		logger.WithError(err).Error("failed transaction")
+		return nil, errors.ErrTransactionFailed
+	}
+
+	hold.Status = core.HoldStatusCaptured
+	hold.CapturedPoints = amount
+	hold.TransactionID = tran.ID
+	if err = h.holdRepository.UpdateHold(ctx, hold); err != nil {
+		logger.WithError(err).Error("failed to update hold")
+		return nil, errors.ErrTransactionFailed
+	}
+
+	// with the hold released its points count towards the balance again, the capture only
+	// fails if they were spent some other way, e.g. by expiring
+	balance, err := h.pointRepository.GetPointsBalance(ctx, hold.SenderID)
+	if err != nil {
+		logger.WithError(err).Error("failed to get user points balance")
+		return nil, errors.ErrGeneric
+	}
+
+	if balance < amount {
+		return nil, errors.ErrInsufficientFunds
+	}
+
+	entry := core.NewJournalEntry(core.EntryTypeTransfer, tran.ID, core.UserPointsAccount(hold.SenderID),
+		core.UserPointsAccount(hold.RecipientID), amount)
+	entry.ExpiresAt = tran.ExpiresAt
+	err = h.ledgerRepository.PostEntry(ctx, entry)
+	if err == errors.ErrInsufficientFunds {
+		return nil, err
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to post transfer")
+		return nil, errors.ErrTransactionFailed
+	}
+
+	if err = h.qualifyByTransaction(ctx, tran, logger); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.WithError(err).Error("failed to commit transaction")
+		return nil, errors.ErrTransactionFailed
+	}
+
+	return tran, nil
+}
+
+// VoidTransfer releases the points reserved by a hold without transferring any of them.
+func (h *Handler) VoidTransfer(ctx context.Context, holdID string, logger *log.Entry) (*core.Hold, error) {
+	tx, err := h.beginTxFunc()
+	if err != nil {
+		logger.WithError(err).Error("failed to start transaction")
+		return nil, errors.ErrGeneric
+	}
+	defer tx.Rollback(ctx)
+
+	ctx = context.WithValue(ctx, core.TxContextKey, tx)
+	hold, err := h.findActiveHold(ctx, holdID, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	hold.Status = core.HoldStatusVoided
+	if err = h.holdRepository.UpdateHold(ctx, hold); err != nil {
+		logger.WithError(err).Error("failed to update hold")
+		return nil, errors.ErrTransactionFailed
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.WithError(err).Error("failed to commit transaction")
+		return nil, errors.ErrTransactionFailed
+	}
+
+	return hold, nil
+}
+
+// ExpireHolds marks holds past their expiry as EXPIRED. Expired holds stop reserving points
+// as soon as they expire, the job only keeps their status accurate.
+func (h *Handler) ExpireHolds(ctx context.Context) error {
+	n, err := h.holdRepository.ExpireHolds(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		log.WithField("expired", n).Info("expired holds")
+	}
+	return nil
+}
+
+// findActiveHold locks the hold and makes sure it can still be captured or voided.
+func (h *Handler) findActiveHold(ctx context.Context, holdID string, logger *log.Entry) (*core.Hold, error) {
+	hold, err := h.holdRepository.FindHoldByID(ctx, holdID)
+	if err == pgx.ErrNoRows {
+		return nil, errors.ErrHoldNotFound
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to find hold")
+		return nil, errors.ErrGeneric
+	}
+
+	if hold.Status != core.HoldStatusActive {
+		return nil, errors.ErrHoldNotActive
+	}
+
+	if hold.IsExpired(time.Now()) {
+		return nil, errors.ErrHoldExpired
+	}
+
+	return hold, nil
+}
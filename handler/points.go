@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// expiredLotsBatch is how many expired lots are written off per run of ExpirePoints.
+const expiredLotsBatch = 100
+
+// GetPointsBalance returns the user's spendable points, the points reserved by holds, what they owe for
+// clawed back rewards and the part of the balance that expires within the configured warning window.
+func (h *Handler) GetPointsBalance(ctx context.Context, userID string, logger *log.Entry) (*PointsBalance, error) {
+	balance, err := h.pointRepository.GetPointsBalance(ctx, userID)
+	if err != nil {
+		logger.WithError(err).Error("failed to get user points balance")
+		return nil, errors.ErrGeneric
+	}
+
+	held, err := h.holdRepository.GetHeldPoints(ctx, userID)
+	if err != nil {
+		logger.WithError(err).Error("failed to get held points")
+		return nil, errors.ErrGeneric
+	}
+
+	point, err := h.pointRepository.FindPointByUserID(ctx, userID)
+	if err != nil {
+		logger.WithError(err).Error("failed to get user points")
+		return nil, errors.ErrGeneric
+	}
+
+	expiring, err := h.pointRepository.GetExpiringPoints(ctx, userID, time.Now().Add(h.config.Expiry.WarningWindow))
+	if err != nil {
+		logger.WithError(err).Error("failed to get expiring points")
+		return nil, errors.ErrGeneric
+	}
+
+	debt, err := h.ledgerRepository.FindAccountByCode(ctx, core.UserDebtAccount(userID))
+	if err != nil {
+		logger.WithError(err).Error("failed to get user debt account")
+		return nil, errors.ErrGeneric
+	}
+
+	return &PointsBalance{
+		UserID:    userID,
+		Total:     point.Points,
+		Available: balance,
+		Held:      held,
+		Debt:      -debt.Balance,
+		Expiring:  expiring,
+	}, nil
+}
+
+// GrantPromoPoints credits the user with promotional points that expire after the configured promo lifetime.
+func (h *Handler) GrantPromoPoints(ctx context.Context, input *PromoRequest, logger *log.Entry) (*core.Transaction, error) {
+	tx, err := h.beginTxFunc()
+	if err != nil {
+		logger.WithError(err).Error("failed to start transaction")
+		return nil, errors.ErrGeneric
+	}
+	defer tx.Rollback(ctx)
+
+	ctx = context.WithValue(ctx, core.TxContextKey, tx)
+	tran := &core.Transaction{
+		SenderID:    input.UserID,
+		RecipientID: input.UserID,
+		Points:      input.Points,
+		Type:        promo,
+		ExpiresAt:   h.lotExpiry(h.config.Expiry.Promo),
+	}
+
+	if err = h.transactionRepository.CreateTransaction(ctx, tran); err != nil {
+		logger.WithError(err).Error("failed to create promo transaction")
+		return nil, errors.ErrTransactionFailed
+	}
+
+	entry := core.NewJournalEntry(core.EntryTypePromo, tran.ID, core.SystemPromotionsAccount, core.UserPointsAccount(input.UserID), input.Points)
+	entry.Description = input.Description
+	entry.ExpiresAt = tran.ExpiresAt
+	if err = h.ledgerRepository.PostEntry(ctx, entry); err != nil {
+		logger.WithError(err).Error("failed to post promo points")
+		return nil, errors.ErrTransactionFailed
+	}
+
+	if err = h.qualifyByTransaction(ctx, tran, logger); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.WithError(err).Error("failed to commit transaction")
+		return nil, errors.ErrTransactionFailed
+	}
+
+	return tran, nil
+}
+
+// ExpirePoints writes off what is left of every expired lot with an EXPIRY transaction. It is
+// meant to run periodically and handles at most expiredLotsBatch lots per run.
+func (h *Handler) ExpirePoints(ctx context.Context) error {
+	lots, err := h.pointRepository.ListExpiredLots(ctx, time.Now(), expiredLotsBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, lot := range lots {
+		if err = h.expireLot(ctx, lot.ID); err != nil {
+			log.WithError(err).WithField("lot_id", lot.ID).Error("failed to expire points")
+		}
+	}
+
+	return nil
+}
+
+func (h *Handler) expireLot(ctx context.Context, lotID string) error {
+	tx, err := h.beginTxFunc()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	ctx = context.WithValue(ctx, core.TxContextKey, tx)
+	// re-read the lot under lock, it may have been spent since it was listed
+	lot, err := h.pointRepository.FindLotByID(ctx, lotID)
+	if err != nil {
+		return err
+	}
+
+	if lot.Remaining == 0 {
+		return nil
+	}
+
+	tran := &core.Transaction{
+		SenderID:    lot.UserID,
+		RecipientID: lot.UserID,
+		Points:      lot.Remaining,
+		Type:        expiry,
+	}
+
+	if err = h.transactionRepository.CreateTransaction(ctx, tran); err != nil {
+		return err
+	}
+
+	entry := core.NewJournalEntry(core.EntryTypeExpiry, tran.ID, core.UserPointsAccount(lot.UserID), core.SystemExpiredPointsAccount, lot.Remaining)
+	entry.LotID = lot.ID
+	if err = h.ledgerRepository.PostEntry(ctx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// lotExpiry returns when points granted now with the given lifetime expire, nil if they never do.
+func (h *Handler) lotExpiry(lifetime time.Duration) *time.Time {
+	if lifetime <= 0 {
+		return nil
+	}
+
+	expiresAt := time.Now().Add(lifetime)
+	return &expiresAt
+}
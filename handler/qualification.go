@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/errors"
+
+	"github.com/jackc/pgx/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+// qualifiedRefereesBatch is how many qualified referrals QualifyReferrals releases per run.
+const qualifiedRefereesBatch = 100
+
+// EmailVerificationToken returns the token proving the user owns their current address, valid until expiresAt.
+// It is what verification emails carry, signed with the configured key.
+func (h *Handler) EmailVerificationToken(user *core.User, expiresAt time.Time) string {
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
+	return expiry + "." + h.signEmailVerification(user, expiry)
+}
+
+// VerifyEmail marks the user's email as verified once they proved they own the address with the token sent
+// to it, and releases the rewards for referring them when that is the qualifying action.
+func (h *Handler) VerifyEmail(ctx context.Context, input *VerifyEmailRequest, logger *log.Entry) (*core.User, error) {
+	tx, err := h.beginTxFunc()
+	if err != nil {
+		logger.WithError(err).Error("failed to start transaction")
+		return nil, errors.ErrGeneric
+	}
+	defer tx.Rollback(ctx)
+
+	ctx = context.WithValue(ctx, core.TxContextKey, tx)
+	user, err := h.userRepository.FindUserByID(ctx, input.UserID)
+	if err == pgx.ErrNoRows {
+		return nil, errors.ErrUserNotFound
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to find user")
+		return nil, errors.ErrGeneric
+	}
+
+	if !h.checkEmailVerificationToken(user, input.Token, time.Now()) {
+		return nil, errors.ErrInvalidVerificationToken
+	}
+
+	if err = h.userRepository.VerifyEmail(ctx, user); err != nil {
+		logger.WithError(err).Error("failed to verify user email")
+		return nil, errors.ErrGeneric
+	}
+
+	if h.qualifyingAction() == core.QualifyEmailVerified {
+		if err = h.qualifyReferee(ctx, user.ID, logger); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.WithError(err).Error("failed to commit transaction")
+		return nil, errors.ErrGeneric
+	}
+
+	return user, nil
+}
+
+// checkEmailVerificationToken reports whether the token was issued for the user's current address and is
+// still valid. No token is valid without a key to sign them with.
+func (h *Handler) checkEmailVerificationToken(user *core.User, token string, now time.Time) bool {
+	if h.config.EmailVerification.Key == "" {
+		return false
+	}
+
+	dot := strings.Index(token, ".")
+	if dot < 0 {
+		return false
+	}
+
+	expiry, signature := token[:dot], token[dot+1:]
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || now.Unix() >= expiresAt {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(h.signEmailVerification(user, expiry)))
+}
+
+func (h *Handler) signEmailVerification(user *core.User, expiry string) string {
+	mac := hmac.New(sha256.New, []byte(h.config.EmailVerification.Key))
+	fmt.Fprintf(mac, "%s\n%s\n%s", user.ID, strings.ToLower(user.Email), expiry)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// QualifyReferrals expires the pending referrals whose window ended, then releases the rewards of referees
+// who completed the qualifying action on a path that doesn't check for it, e.g. points reaching the
+// threshold through a reversal or a refunded payout, or before the action was configured.
+func (h *Handler) QualifyReferrals(ctx context.Context) error {
+	n, err := h.referralRepository.ExpirePendingReferrals(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		log.WithField("expired", n).Info("expired pending referrals")
+	}
+
+	referees, err := h.referralRepository.ListQualifiedReferees(ctx, h.qualifyingAction(), h.config.Referral.QualifyingPoints, qualifiedRefereesBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, refereeID := range referees {
+		logger := log.WithField("referee_id", refereeID)
+		if err = h.releaseReferral(ctx, refereeID, logger); err != nil {
+			logger.WithError(err).Error("failed to release referral rewards")
+		}
+	}
+
+	return nil
+}
+
+func (h *Handler) releaseReferral(ctx context.Context, refereeID string, logger *log.Entry) error {
+	tx, err := h.beginTxFunc()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	ctx = context.WithValue(ctx, core.TxContextKey, tx)
+	if err = h.qualifyReferee(ctx, refereeID, logger); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// qualifyByTransaction checks whether the transaction completes the qualifying action for the referral of
+// its sender or recipient, and releases the referral's rewards if it does.
+func (h *Handler) qualifyByTransaction(ctx context.Context, tran *core.Transaction, logger *log.Entry) error {
+	switch h.qualifyingAction() {
+	case core.QualifyFirstTransfer:
+		if tran.Type == transfer {
+			return h.qualifyReferee(ctx, tran.SenderID, logger)
+		}
+	case core.QualifyMinPoints:
+		balance, err := h.pointRepository.GetPointsBalance(ctx, tran.RecipientID)
+		if err != nil {
+			logger.WithError(err).Error("failed to get user points balance")
+			return errors.ErrGeneric
+		}
+
+		if balance >= h.config.Referral.QualifyingPoints {
+			return h.qualifyReferee(ctx, tran.RecipientID, logger)
+		}
+	}
+	return nil
+}
+
+// qualifyReferee releases the pending rewards for referring the user, if they have any, posting them to
+// the referrers' bonus accounts.
+func (h *Handler) qualifyReferee(ctx context.Context, refereeID string, logger *log.Entry) error {
+	referral, err := h.referralRepository.FindPendingReferral(ctx, refereeID)
+	if err == pgx.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to find pending referral")
+		return errors.ErrGeneric
+	}
+
+	rewards, err := h.rewardRuleRepository.ListReferralRewards(ctx, referral.ID)
+	if err != nil {
+		logger.WithError(err).Error("failed to list referral rewards")
+		return errors.ErrGeneric
+	}
+
+	for _, reward := range rewards {
+		if reward.Status != core.RewardStatusPending {
+			continue
+		}
+
+		if err = h.postReferralReward(ctx, referral, reward, logger); err != nil {
+			return err
+		}
+
+		if err = h.rewardRuleRepository.ReleaseReferralReward(ctx, reward); err != nil {
+			logger.WithError(err).Error("failed to release referral reward")
+			return errors.ErrGeneric
+		}
+
+		point, err := h.pointRepository.FindPointByUserID(ctx, reward.UserID)
+		if err != nil {
+			logger.WithError(err).Error("failed to find user point")
+			return errors.ErrGeneric
+		}
+
+		point.AddBonus(reward.Points)
+		point.Paid = false
+		if err = h.pointRepository.UpdatePoint(ctx, point); err != nil {
+			logger.WithError(err).Error("failed to update user point")
+			return errors.ErrGeneric
+		}
+	}
+
+	now := time.Now()
+	referral.Status = core.ReferralStatusQualified
+	referral.QualifiedAt = &now
+	if err = h.referralRepository.UpdateReferralStatus(ctx, referral); err != nil {
+		logger.WithError(err).Error("failed to update referral status")
+		return errors.ErrGeneric
+	}
+
+	return nil
+}
+
+// newReferral returns the referral of the referee to the referrer, pending until the referee completes the
+// qualifying action unless there is none to complete.
+func (h *Handler) newReferral(referrerID, refereeID string) *core.Referral {
+	referral := &core.Referral{
+		ReferrerID: referrerID,
+		RefereeID:  refereeID,
+		Status:     core.ReferralStatusQualified,
+	}
+
+	if h.qualifyingAction() != core.QualifyNone {
+		referral.Status = core.ReferralStatusPending
+		if window := h.config.Referral.QualifyingWindow; window > 0 {
+			expiresAt := time.Now().Add(window)
+			referral.ExpiresAt = &expiresAt
+		}
+	}
+
+	return referral
+}
+
+// qualifyingAction is the configured qualifying action, rewards are released at registration without one.
+func (h *Handler) qualifyingAction() string {
+	if h.config.Referral.QualifyingAction == "" {
+		return core.QualifyNone
+	}
+	return h.config.Referral.QualifyingAction
+}
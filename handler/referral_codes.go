@@ -0,0 +1,296 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/errors"
+
+	"github.com/jackc/pgx/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	minVanityCodeLen = 4
+	maxVanityCodeLen = 20
+)
+
+var (
+	vanityCodePattern = regexp.MustCompile(`^[A-Za-z0-9]+(-[A-Za-z0-9]+)*$`)
+	domainPattern     = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,}$`)
+)
+
+// reservedCodes can't be claimed as they could pass for codes of the service itself.
+var reservedCodes = map[string]bool{
+	"ABOKI": true, "ADMIN": true, "ADMINISTRATOR": true, "API": true, "HELP": true, "NULL": true, "OFFICIAL": true,
+	"REFERRAL": true, "ROOT": true, "STAFF": true, "SUPPORT": true, "SYSTEM": true, "UNDEFINED": true,
+}
+
+// profanity lists words vanity codes may not contain anywhere, dashes aside.
+var profanity = []string{"ARSE", "BASTARD", "BITCH", "BOLLOCK", "CUNT", "DICK", "FUCK", "PISS", "PUSSY", "SHIT", "SLUT", "TWAT", "WANK", "WHORE"}
+
+func (r *ReferralCodeRequest) Validate() error {
+	if len(r.Code) < minVanityCodeLen || len(r.Code) > maxVanityCodeLen {
+		return fmt.Errorf("code must be %d to %d characters long", minVanityCodeLen, maxVanityCodeLen)
+	}
+
+	if !vanityCodePattern.MatchString(r.Code) {
+		return fmt.Errorf("code may only contain letters, digits and single dashes between them")
+	}
+
+	upper := strings.ToUpper(r.Code)
+	if reservedCodes[upper] {
+		return fmt.Errorf("code %q is reserved", r.Code)
+	}
+
+	squashed := strings.ReplaceAll(upper, "-", "")
+	for _, word := range profanity {
+		if strings.Contains(squashed, word) {
+			return fmt.Errorf("code %q is not allowed", r.Code)
+		}
+	}
+
+	return nil
+}
+
+func (r *ReferralCodeLimitsRequest) Validate() error {
+	if r.MaxRedemptions < 0 {
+		return fmt.Errorf("max_redemptions cannot be negative")
+	}
+
+	for i, domain := range r.AllowedDomains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if !domainPattern.MatchString(domain) {
+			return fmt.Errorf("allowed domain %q is invalid", r.AllowedDomains[i])
+		}
+		r.AllowedDomains[i] = domain
+	}
+
+	return nil
+}
+
+// SetReferralCode replaces the user's referral code with the vanity code they chose. The code it
+// replaces keeps referring new users to them for the configured grace period.
+func (h *Handler) SetReferralCode(ctx context.Context, input *ReferralCodeRequest, logger *log.Entry) (*core.ReferralCode, error) {
+	tx, err := h.beginTxFunc()
+	if err != nil {
+		logger.WithError(err).Error("failed to start transaction")
+		return nil, errors.ErrGeneric
+	}
+	defer tx.Rollback(ctx)
+
+	ctx = context.WithValue(ctx, core.TxContextKey, tx)
+	current, err := h.referralCodeRepository.FindReferralCodeByUserID(ctx, input.UserID)
+	if err == pgx.ErrNoRows {
+		return nil, errors.ErrUserNotFound
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to find user referral code")
+		return nil, errors.ErrGeneric
+	}
+
+	if current.Vanity && current.Code == input.Code {
+		return current, nil
+	}
+
+	now := time.Now()
+	if limit := h.config.Referral.CodeChangeLimit; limit > 0 {
+		changes, err := h.referralCodeRepository.CountVanityCodesSince(ctx, input.UserID, now.Add(-h.config.Referral.CodeChangeWindow))
+		if err != nil {
+			logger.WithError(err).Error("failed to count referral code changes")
+			return nil, errors.ErrGeneric
+		}
+		if changes >= limit {
+			return nil, errors.ErrReferralCodeChangeLimit
+		}
+	}
+
+	// the user's own vanity code can change case, any other code in use can't be claimed
+	if !strings.EqualFold(current.Code, input.Code) || !current.Vanity {
+		taken, err := h.campaignRepository.IsCodeTaken(ctx, input.Code)
+		if err != nil {
+			logger.WithError(err).Error("failed to check referral code")
+			return nil, errors.ErrGeneric
+		}
+		if taken {
+			return nil, errors.ErrReferralCodeTaken
+		}
+	}
+
+	aliasExpiresAt := now.Add(h.config.Referral.AliasGracePeriod)
+	if err = h.referralCodeRepository.RetireReferralCode(ctx, current, core.CodeRetiredReplaced, &aliasExpiresAt); err != nil {
+		logger.WithError(err).Error("failed to replace user referral code")
+		return nil, errors.ErrGeneric
+	}
+
+	code := replacementCode(current, input.Code)
+	code.Vanity = true
+	err = h.referralCodeRepository.CreateReferralCode(ctx, code)
+	if err == errors.ErrReferralCodeTaken {
+		return nil, err
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to create user referral code")
+		return nil, errors.ErrGeneric
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.WithError(err).Error("failed to commit transaction")
+		return nil, errors.ErrGeneric
+	}
+
+	return code, nil
+}
+
+// RotateReferralCode issues the user a new generated code. The rotated code keeps referring new users
+// to them for the configured grace period.
+func (h *Handler) RotateReferralCode(ctx context.Context, userID string, logger *log.Entry) (*core.ReferralCode, error) {
+	aliasExpiresAt := time.Now().Add(h.config.Referral.AliasGracePeriod)
+	return h.reissueReferralCode(ctx, userID, core.CodeRetiredRotated, &aliasExpiresAt, logger)
+}
+
+// RevokeReferralCode issues the user a new generated code and stops the revoked one from working at once.
+func (h *Handler) RevokeReferralCode(ctx context.Context, userID string, logger *log.Entry) (*core.ReferralCode, error) {
+	return h.reissueReferralCode(ctx, userID, core.CodeRetiredRevoked, nil, logger)
+}
+
+// ListReferralCodes returns the history of the user's referral codes, oldest first.
+func (h *Handler) ListReferralCodes(ctx context.Context, userID string, logger *log.Entry) ([]*core.ReferralCode, error) {
+	codes, err := h.referralCodeRepository.ListReferralCodes(ctx, userID)
+	if err != nil {
+		logger.WithError(err).Error("failed to list user referral codes")
+		return nil, errors.ErrGeneric
+	}
+
+	if len(codes) == 0 {
+		return nil, errors.ErrUserNotFound
+	}
+	return codes, nil
+}
+
+func (h *Handler) reissueReferralCode(ctx context.Context, userID, reason string, aliasExpiresAt *time.Time, logger *log.Entry) (*core.ReferralCode, error) {
+	tx, err := h.beginTxFunc()
+	if err != nil {
+		logger.WithError(err).Error("failed to start transaction")
+		return nil, errors.ErrGeneric
+	}
+	defer tx.Rollback(ctx)
+
+	ctx = context.WithValue(ctx, core.TxContextKey, tx)
+	current, err := h.referralCodeRepository.FindReferralCodeByUserID(ctx, userID)
+	if err == pgx.ErrNoRows {
+		return nil, errors.ErrUserNotFound
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to find user referral code")
+		return nil, errors.ErrGeneric
+	}
+
+	if err = h.referralCodeRepository.RetireReferralCode(ctx, current, reason, aliasExpiresAt); err != nil {
+		logger.WithError(err).Error("failed to retire user referral code")
+		return nil, errors.ErrGeneric
+	}
+
+	code := replacementCode(current, GenReferralCode(7))
+	if err = h.referralCodeRepository.CreateReferralCode(ctx, code); err != nil {
+		logger.WithError(err).Error("failed to create user referral code")
+		return nil, errors.ErrGeneric
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.WithError(err).Error("failed to commit transaction")
+		return nil, errors.ErrGeneric
+	}
+
+	return code, nil
+}
+
+// SetReferralCodeLimits replaces the limits on who may register with the user's active code and how often.
+// Owners may lower the configured cap on redemptions but not raise it.
+func (h *Handler) SetReferralCodeLimits(ctx context.Context, input *ReferralCodeLimitsRequest, logger *log.Entry) (*core.ReferralCode, error) {
+	if limit := h.config.Referral.MaxRedemptions; limit > 0 && input.MaxRedemptions > limit {
+		return nil, errors.ErrReferralCodeLimitTooHigh
+	}
+
+	tx, err := h.beginTxFunc()
+	if err != nil {
+		logger.WithError(err).Error("failed to start transaction")
+		return nil, errors.ErrGeneric
+	}
+	defer tx.Rollback(ctx)
+
+	ctx = context.WithValue(ctx, core.TxContextKey, tx)
+	code, err := h.referralCodeRepository.FindReferralCodeByUserID(ctx, input.UserID)
+	if err == pgx.ErrNoRows {
+		return nil, errors.ErrUserNotFound
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to find user referral code")
+		return nil, errors.ErrGeneric
+	}
+
+	code.MaxRedemptions = h.maxRedemptions(input.MaxRedemptions)
+	code.ExpiresAt = input.ExpiresAt
+	code.AllowedDomains = input.AllowedDomains
+	if err = h.referralCodeRepository.UpdateReferralCodeLimits(ctx, code); err != nil {
+		logger.WithError(err).Error("failed to update referral code limits")
+		return nil, errors.ErrGeneric
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.WithError(err).Error("failed to commit transaction")
+		return nil, errors.ErrGeneric
+	}
+
+	return code, nil
+}
+
+// replacementCode returns the code replacing current, with its limits so replacing a code doesn't lift them.
+// Its redemptions start over, the cap is held against those of all the owner's codes.
+func replacementCode(current *core.ReferralCode, code string) *core.ReferralCode {
+	return &core.ReferralCode{
+		UserID:         current.UserID,
+		Code:           code,
+		MaxRedemptions: current.MaxRedemptions,
+		ExpiresAt:      current.ExpiresAt,
+		AllowedDomains: current.AllowedDomains,
+	}
+}
+
+// maxRedemptions is the cap on redemptions of a code its owner limited to the given number, the configured
+// cap when it's lower or the code isn't limited.
+func (h *Handler) maxRedemptions(requested int) int {
+	if limit := h.config.Referral.MaxRedemptions; limit > 0 && (requested == 0 || requested > limit) {
+		return limit
+	}
+	return requested
+}
+
+// checkReferralCodeLimits returns why a user with the given email can't register with the code, if they can't.
+// maxRedemptions replaces the code's own cap so codes from before the configured cap are held to it. It caps
+// redemptions, the count of the owner's codes together, so a replaced code and its aliases share one count.
+func checkReferralCodeLimits(code *core.ReferralCode, redemptions, maxRedemptions int, email string, now time.Time) error {
+	if code.ExpiresAt != nil && !now.Before(*code.ExpiresAt) {
+		return errors.ErrReferralCodeExpired
+	}
+
+	if maxRedemptions > 0 && redemptions >= maxRedemptions {
+		return errors.ErrReferralCodeExhausted
+	}
+
+	if len(code.AllowedDomains) > 0 {
+		domain := strings.ToLower(email[strings.LastIndex(email, "@")+1:])
+		for _, allowed := range code.AllowedDomains {
+			if domain == allowed {
+				return nil
+			}
+		}
+		return errors.ErrEmailDomainNotAllowed
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"context"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/errors"
+
+	"github.com/jackc/pgx/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultTreeDepth = 3
+	maxTreeDepth     = 10
+)
+
+// ListReferrals returns a page of the users the user referred, newest first.
+func (h *Handler) ListReferrals(ctx context.Context, input *ListReferralsRequest, logger *log.Entry) (*ReferralPage, error) {
+	limit := input.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	var after *core.RefereeCursor
+	if input.Cursor != "" {
+		var err error
+		if after, err = decodeRefereeCursor(input.Cursor); err != nil {
+			return nil, errors.ErrInvalidCursor
+		}
+	}
+
+	if err := h.findUser(ctx, input.UserID, logger); err != nil {
+		return nil, err
+	}
+
+	// fetch one extra row to know whether there is a next page
+	referees, err := h.referralRepository.ListReferees(ctx, input.UserID, after, limit+1)
+	if err != nil {
+		logger.WithError(err).Error("failed to list referees")
+		return nil, errors.ErrGeneric
+	}
+
+	page := &ReferralPage{Data: referees}
+	if len(referees) > limit {
+		page.Data = referees[:limit]
+		last := page.Data[limit-1]
+		page.NextCursor = encodeRefereeCursor(&core.RefereeCursor{ReferredAt: last.ReferredAt, ReferralID: last.ReferralID})
+	}
+
+	return page, nil
+}
+
+func encodeRefereeCursor(cursor *core.RefereeCursor) string {
+	return encodePosition(cursor.ReferredAt, cursor.ReferralID)
+}
+
+func decodeRefereeCursor(s string) (*core.RefereeCursor, error) {
+	referredAt, referralID, err := decodePosition(s)
+	if err != nil {
+		return nil, err
+	}
+	return &core.RefereeCursor{ReferredAt: referredAt, ReferralID: referralID}, nil
+}
+
+// GetReferralTree returns the user with the users they referred nested below them, depth levels deep.
+func (h *Handler) GetReferralTree(ctx context.Context, userID string, depth int, logger *log.Entry) (*core.ReferralTreeNode, error) {
+	if depth <= 0 {
+		depth = defaultTreeDepth
+	}
+	if depth > maxTreeDepth {
+		depth = maxTreeDepth
+	}
+
+	user, err := h.userRepository.FindUserByID(ctx, userID)
+	if err == pgx.ErrNoRows {
+		return nil, errors.ErrUserNotFound
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to find user")
+		return nil, errors.ErrGeneric
+	}
+
+	descendants, err := h.referralRepository.ListDescendants(ctx, userID, depth)
+	if err != nil {
+		logger.WithError(err).Error("failed to list referral descendants")
+		return nil, errors.ErrGeneric
+	}
+
+	root := &core.ReferralTreeNode{UserID: user.ID, Name: user.Name, Children: []*core.ReferralTreeNode{}}
+	nodes := map[string]*core.ReferralTreeNode{root.UserID: root}
+	// descendants come level by level so every referrer is in the tree before the users they referred
+	for _, descendant := range descendants {
+		parent, ok := nodes[descendant.ReferrerID]
+		if !ok {
+			continue
+		}
+
+		referredAt := descendant.ReferredAt
+		node := &core.ReferralTreeNode{
+			UserID:     descendant.UserID,
+			Name:       descendant.Name,
+			Level:      descendant.Level,
+			ReferredAt: &referredAt,
+			Children:   []*core.ReferralTreeNode{},
+		}
+		parent.Children = append(parent.Children, node)
+		if _, seen := nodes[node.UserID]; !seen {
+			nodes[node.UserID] = node
+		}
+	}
+
+	return root, nil
+}
+
+func (h *Handler) GetReferralStats(ctx context.Context, userID string, logger *log.Entry) (*core.ReferralStats, error) {
+	if err := h.findUser(ctx, userID, logger); err != nil {
+		return nil, err
+	}
+
+	stats, err := h.referralRepository.GetReferralStats(ctx, userID)
+	if err != nil {
+		logger.WithError(err).Error("failed to get referral stats")
+		return nil, errors.ErrGeneric
+	}
+	return stats, nil
+}
+
+// findUser returns errors.ErrUserNotFound unless the user exists.
+func (h *Handler) findUser(ctx context.Context, userID string, logger *log.Entry) error {
+	_, err := h.userRepository.FindUserByID(ctx, userID)
+	if err == pgx.ErrNoRows {
+		return errors.ErrUserNotFound
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to find user")
+		return errors.ErrGeneric
+	}
+	return nil
+}
+
+// checkReferral makes sure the user can be referred by the referrer: nobody refers themselves, under another
+// address of their mailbox either. The database enforces the rest, users only ever having one referrer and
+// chains not looping.
+func (h *Handler) checkReferral(ctx context.Context, referrerID string, user *core.User, logger *log.Entry) error {
+	if referrerID == user.ID {
+		return errors.ErrSelfReferral
+	}
+
+	referrer, err := h.userRepository.FindUserByID(ctx, referrerID)
+	if err == pgx.ErrNoRows {
+		return errors.ErrUserNotFound
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to find referrer")
+		return errors.ErrGeneric
+	}
+
+	if core.SameMailbox(referrer.Email, user.Email) {
+		return errors.ErrSelfReferral
+	}
+
+	return nil
+}
+
+// isReferralIntegrityError reports whether err rejected a referral that would break the referral chain.
+func isReferralIntegrityError(err error) bool {
+	switch err {
+	case errors.ErrSelfReferral, errors.ErrAlreadyReferred, errors.ErrReferralCycle:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,282 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/cron"
+	"github.com/Qalifah/aboki-africa-assessment/errors"
+
+	"github.com/jackc/pgx/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// dueSchedulesBatch is how many due schedules are run per run of RunScheduledTransfers.
+	dueSchedulesBatch = 100
+	// executionsLimit is how many of the latest executions are listed for a schedule.
+	executionsLimit = 50
+)
+
+// CreateScheduledTransfer schedules a transfer to run once at input.RunAt, or repeatedly on input.Rule.
+func (h *Handler) CreateScheduledTransfer(ctx context.Context, input *ScheduledTransferRequest, logger *log.Entry) (*core.ScheduledTransfer, error) {
+	schedule := &core.ScheduledTransfer{
+		SenderID:    input.SenderID,
+		RecipientID: input.RecipientID,
+		Points:      input.Points,
+		Rule:        input.Rule,
+		Status:      core.ScheduleStatusActive,
+	}
+
+	nextRunAt, err := nextRun(schedule.Rule, input.RunAt, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	schedule.NextRunAt = nextRunAt
+
+	if err = h.scheduledTransferRepository.CreateScheduledTransfer(ctx, schedule); err != nil {
+		logger.WithError(err).Error("failed to create scheduled transfer")
+		return nil, errors.ErrGeneric
+	}
+
+	return schedule, nil
+}
+
+func (h *Handler) GetScheduledTransfer(ctx context.Context, id string, logger *log.Entry) (*core.ScheduledTransfer, error) {
+	schedule, err := h.scheduledTransferRepository.FindScheduledTransferByID(ctx, id)
+	if err == pgx.ErrNoRows {
+		return nil, errors.ErrScheduledTransferNotFound
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to find scheduled transfer")
+		return nil, errors.ErrGeneric
+	}
+	return schedule, nil
+}
+
+func (h *Handler) ListScheduledTransfers(ctx context.Context, senderID string, logger *log.Entry) ([]*core.ScheduledTransfer, error) {
+	schedules, err := h.scheduledTransferRepository.ListScheduledTransfers(ctx, senderID)
+	if err != nil {
+		logger.WithError(err).Error("failed to list scheduled transfers")
+		return nil, errors.ErrGeneric
+	}
+	return schedules, nil
+}
+
+func (h *Handler) ListScheduledTransferExecutions(ctx context.Context, id string, logger *log.Entry) ([]*core.ScheduledTransferExecution, error) {
+	if _, err := h.GetScheduledTransfer(ctx, id, logger); err != nil {
+		return nil, err
+	}
+
+	executions, err := h.scheduledTransferRepository.ListExecutions(ctx, id, executionsLimit)
+	if err != nil {
+		logger.WithError(err).Error("failed to list scheduled transfer executions")
+		return nil, errors.ErrGeneric
+	}
+	return executions, nil
+}
+
+// UpdateScheduledTransfer changes the amount or timing of a schedule, or pauses and resumes it.
+// Resuming a schedule clears its failures.
+func (h *Handler) UpdateScheduledTransfer(ctx context.Context, input *UpdateScheduledTransferRequest, logger *log.Entry) (*core.ScheduledTransfer, error) {
+	tx, err := h.beginTxFunc()
+	if err != nil {
+		logger.WithError(err).Error("failed to start transaction")
+		return nil, errors.ErrGeneric
+	}
+	defer tx.Rollback(ctx)
+
+	ctx = context.WithValue(ctx, core.TxContextKey, tx)
+	schedule, err := h.GetScheduledTransfer(ctx, input.ID, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if schedule.Status == core.ScheduleStatusCompleted {
+		return nil, errors.ErrScheduleCompleted
+	}
+
+	if input.Points != nil {
+		schedule.Points = *input.Points
+	}
+
+	now := time.Now()
+	if input.Rule != nil || input.RunAt != nil {
+		rule := ""
+		if input.Rule != nil {
+			rule = *input.Rule
+		}
+
+		schedule.NextRunAt, err = nextRun(rule, input.RunAt, now)
+		if err != nil {
+			return nil, err
+		}
+		schedule.Rule = rule
+	}
+
+	if input.Status != nil && *input.Status != schedule.Status {
+		schedule.Status = *input.Status
+		if schedule.Status == core.ScheduleStatusActive {
+			schedule.ConsecutiveFailures = 0
+			// a recurring schedule doesn't catch up on the runs missed while it was paused
+			if schedule.IsRecurring() && schedule.NextRunAt.Before(now) {
+				if schedule.NextRunAt, err = nextRun(schedule.Rule, nil, now); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if err = h.scheduledTransferRepository.UpdateScheduledTransfer(ctx, schedule); err != nil {
+		logger.WithError(err).Error("failed to update scheduled transfer")
+		return nil, errors.ErrGeneric
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.WithError(err).Error("failed to commit transaction")
+		return nil, errors.ErrGeneric
+	}
+
+	return schedule, nil
+}
+
+func (h *Handler) DeleteScheduledTransfer(ctx context.Context, id string, logger *log.Entry) error {
+	err := h.scheduledTransferRepository.DeleteScheduledTransfer(ctx, id)
+	if err == pgx.ErrNoRows {
+		return errors.ErrScheduledTransferNotFound
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to delete scheduled transfer")
+		return errors.ErrGeneric
+	}
+	return nil
+}
+
+// RunScheduledTransfers executes the schedules that are due. It is meant to run periodically and
+// handles at most dueSchedulesBatch schedules per run.
+func (h *Handler) RunScheduledTransfers(ctx context.Context) error {
+	ids, err := h.scheduledTransferRepository.ListDueScheduledTransfers(ctx, time.Now(), dueSchedulesBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err = h.runScheduledTransfer(ctx, id); err != nil {
+			log.WithError(err).WithField("scheduled_transfer_id", id).Error("failed to run scheduled transfer")
+		}
+	}
+
+	return nil
+}
+
+// runScheduledTransfer makes the transfer of a due schedule, records the outcome and moves the
+// schedule on. A transfer that fails is retried after the configured delay, and the schedule is
+// paused once it fails for lack of funds too many times in a row.
+func (h *Handler) runScheduledTransfer(ctx context.Context, id string) error {
+	tx, err := h.beginTxFunc()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	ctx = context.WithValue(ctx, core.TxContextKey, tx)
+	schedule, err := h.scheduledTransferRepository.FindScheduledTransferByID(ctx, id)
+	if err == pgx.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// the schedule may have been run, paused or rescheduled since it was listed
+	now := time.Now()
+	if schedule.Status != core.ScheduleStatusActive || schedule.NextRunAt == nil || schedule.NextRunAt.After(now) {
+		return nil
+	}
+
+	execution := &core.ScheduledTransferExecution{
+		ScheduledTransferID: schedule.ID,
+		ScheduledFor:        *schedule.NextRunAt,
+	}
+
+	// the transfer runs in a savepoint so a failed one can still be recorded
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	logger := log.WithField("scheduled_transfer_id", schedule.ID)
+	tran, err := h.transferPoints(context.WithValue(ctx, core.TxContextKey, savepoint), &TransferPointsRequest{
+		SenderID:    schedule.SenderID,
+		RecipientID: schedule.RecipientID,
+		Points:      schedule.Points,
+	}, logger)
+	if err != nil {
+		if rbErr := savepoint.Rollback(ctx); rbErr != nil {
+			return rbErr
+		}
+
+		execution.Status = core.ExecutionStatusFailed
+		execution.Error = err.Error()
+		if err == errors.ErrInsufficientFunds {
+			schedule.ConsecutiveFailures++
+		}
+
+		retryAt := now.Add(h.config.Schedules.RetryDelay)
+		schedule.NextRunAt = &retryAt
+		if h.config.Schedules.MaxFailures > 0 && schedule.ConsecutiveFailures >= h.config.Schedules.MaxFailures {
+			schedule.Status = core.ScheduleStatusPaused
+		}
+	} else {
+		if err = savepoint.Commit(ctx); err != nil {
+			return err
+		}
+
+		execution.Status = core.ExecutionStatusSuccess
+		execution.TransactionID = tran.ID
+		schedule.ConsecutiveFailures = 0
+		schedule.NextRunAt = nil
+		if schedule.IsRecurring() {
+			schedule.NextRunAt, _ = nextRun(schedule.Rule, nil, now)
+		}
+		if schedule.NextRunAt == nil {
+			schedule.Status = core.ScheduleStatusCompleted
+		}
+	}
+
+	if err = h.scheduledTransferRepository.CreateExecution(ctx, execution); err != nil {
+		return err
+	}
+
+	if err = h.scheduledTransferRepository.UpdateScheduledTransfer(ctx, schedule); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// nextRun returns when a schedule with the given rule, or the given one-off run time, runs next.
+func nextRun(rule string, runAt *time.Time, now time.Time) (*time.Time, error) {
+	if rule == "" {
+		if runAt == nil || !runAt.After(now) {
+			return nil, errors.ErrInvalidSchedule
+		}
+		return runAt, nil
+	}
+
+	if runAt != nil {
+		return nil, errors.ErrInvalidSchedule
+	}
+
+	s, err := cron.Parse(rule)
+	if err != nil {
+		return nil, errors.ErrInvalidSchedule
+	}
+
+	next := s.Next(now)
+	if next.IsZero() {
+		return nil, errors.ErrInvalidSchedule
+	}
+	return &next, nil
+}
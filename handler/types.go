@@ -1,13 +1,160 @@
 package handler
 
+import (
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+)
+
 type UserRequest struct {
 	Name         string  `json:"name"`
 	Email        string  `json:"email"`
 	ReferralCode *string `json:"referral_code"`
+	// Country is the ISO 3166-1 alpha-2 code of the user's country.
+	Country string `json:"country"`
+	// ReferralCodeFromLink is set when ReferralCode comes from the attribution cookie of a referral link
+	// rather than the request, a code that stopped referring users is then ignored instead of rejected.
+	ReferralCodeFromLink bool `json:"-"`
+	// IP, UserAgent and DeviceID are what the request tells of where the user registers from, they're
+	// recorded for fraud scoring.
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
+	DeviceID  string `json:"-"`
+	// ClientCountry is the country the user registers from as located by the proxy in front of the service.
+	// Unlike Country, which is only what the user says, it picks their welcome bonus.
+	ClientCountry string `json:"-"`
+}
+
+// Registration is a newly registered user along with the welcome bonus they got for registering with a
+// referral or campaign code.
+type Registration struct {
+	*core.User
+	WelcomeBonus *core.Transaction `json:"welcome_bonus,omitempty"`
+}
+
+// VerifyEmailRequest verifies the user's email with the token sent to the address.
+type VerifyEmailRequest struct {
+	UserID string `json:"-"`
+	Token  string `json:"token"`
 }
 
 type TransferPointsRequest struct {
-	SenderID          string `json:"sender_id"`
-	RecipientID 	  string `json:"recipient_id"`
-	Points            int    `json:"points"`
-}
\ No newline at end of file
+	SenderID    string `json:"sender_id"`
+	RecipientID string `json:"recipient_id"`
+	Points      int    `json:"points"`
+}
+
+type ClaimBonusRequest struct {
+	UserID string `json:"user_id"`
+	Points int    `json:"points"`
+}
+
+type ReverseTransactionRequest struct {
+	TransactionID string `json:"-"`
+	Points        int    `json:"points"`
+	Reason        string `json:"reason"`
+}
+
+type PromoRequest struct {
+	UserID      string `json:"user_id"`
+	Points      int    `json:"points"`
+	Description string `json:"description"`
+}
+
+type AuthorizeTransferRequest struct {
+	SenderID    string `json:"sender_id"`
+	RecipientID string `json:"recipient_id"`
+	Points      int    `json:"points"`
+}
+
+type CaptureTransferRequest struct {
+	HoldID string `json:"-"`
+	Points int    `json:"points"`
+}
+
+// PointsBalance splits the user's points into what can be spent and what is reserved by holds. Debt is
+// what the user owes for clawed back rewards, Available is net of it.
+type PointsBalance struct {
+	UserID    string                 `json:"user_id"`
+	Total     int                    `json:"total"`
+	Available int                    `json:"available"`
+	Held      int                    `json:"held"`
+	Debt      int                    `json:"debt"`
+	Expiring  []*core.ExpiringPoints `json:"expiring"`
+}
+
+type ListTransactionsRequest struct {
+	UserID    string
+	Direction string
+	Type      string
+	MinPoints *int
+	MaxPoints *int
+	From      *time.Time
+	To        *time.Time
+	Cursor    string
+	Limit     int
+}
+
+type TransactionPage struct {
+	Data       []*core.Transaction `json:"data"`
+	NextCursor string              `json:"next_cursor"`
+}
+
+// ScheduledTransferRequest schedules a transfer either once at RunAt or repeatedly on the
+// cron-like Rule, exactly one of them must be set.
+type ScheduledTransferRequest struct {
+	SenderID    string     `json:"sender_id"`
+	RecipientID string     `json:"recipient_id"`
+	Points      int        `json:"points"`
+	RunAt       *time.Time `json:"run_at"`
+	Rule        string     `json:"rule"`
+}
+
+// UpdateScheduledTransferRequest leaves fields that aren't set unchanged. Setting either RunAt
+// or Rule replaces the timing of the schedule.
+type UpdateScheduledTransferRequest struct {
+	ID     string     `json:"-"`
+	Points *int       `json:"points"`
+	RunAt  *time.Time `json:"run_at"`
+	Rule   *string    `json:"rule"`
+	Status *string    `json:"status"`
+}
+
+// ClawbackRequest takes back the rewards granted for a referral, Reason being one of core.ClawbackReasons.
+type ClawbackRequest struct {
+	ReferralID string `json:"-"`
+	Reason     string `json:"reason"`
+	Note       string `json:"note"`
+}
+
+// FraudReviewRequest approves or rejects the held referral of a fraud review.
+type FraudReviewRequest struct {
+	ReviewID string `json:"-"`
+	Note     string `json:"note"`
+}
+
+// ReferralCodeRequest claims Code as the user's vanity referral code.
+type ReferralCodeRequest struct {
+	UserID string `json:"-"`
+	Code   string `json:"code"`
+}
+
+// ReferralCodeLimitsRequest replaces the limits of the user's active referral code. Zero MaxRedemptions,
+// no ExpiresAt and no AllowedDomains lift the respective limit, MaxRedemptions down to the configured cap.
+type ReferralCodeLimitsRequest struct {
+	UserID         string     `json:"-"`
+	MaxRedemptions int        `json:"max_redemptions"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	AllowedDomains []string   `json:"allowed_domains"`
+}
+
+type ListReferralsRequest struct {
+	UserID string
+	Cursor string
+	Limit  int
+}
+
+type ReferralPage struct {
+	Data       []*core.Referee `json:"data"`
+	NextCursor string          `json:"next_cursor"`
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"context"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// grantWelcomeBonus credits a user who registered with a referral or campaign code with their welcome
+// bonus, returning nil when there is none to grant. country is where the user registered from.
+func (h *Handler) grantWelcomeBonus(ctx context.Context, user *core.User, country string, campaign *core.Campaign, logger *log.Entry) (*core.Transaction, error) {
+	points := h.welcomePoints(country, campaign)
+	if points <= 0 {
+		return nil, nil
+	}
+
+	tran := &core.Transaction{
+		SenderID:    user.ID,
+		RecipientID: user.ID,
+		Points:      points,
+		Type:        welcome,
+		ExpiresAt:   h.lotExpiry(h.config.Expiry.Welcome),
+	}
+
+	if err := h.transactionRepository.CreateTransaction(ctx, tran); err != nil {
+		logger.WithError(err).Error("failed to create welcome transaction")
+		return nil, errors.ErrGeneric
+	}
+
+	entry := core.NewJournalEntry(core.EntryTypeWelcome, tran.ID, core.SystemWelcomeAccount, core.UserPointsAccount(user.ID), points)
+	entry.ExpiresAt = tran.ExpiresAt
+	if campaign != nil {
+		entry.Description = "campaign " + campaign.ID
+	}
+	if err := h.ledgerRepository.PostEntry(ctx, entry); err != nil {
+		logger.WithError(err).Error("failed to post welcome bonus")
+		return nil, errors.ErrGeneric
+	}
+
+	return tran, nil
+}
+
+// welcomePoints picks the user's welcome bonus: the campaign's if it sets one, then the country's they
+// registered from, then the default.
+func (h *Handler) welcomePoints(country string, campaign *core.Campaign) int {
+	if campaign != nil && campaign.WelcomePoints != nil {
+		return *campaign.WelcomePoints
+	}
+
+	if points, ok := h.config.Welcome.ByCountry[country]; ok && country != "" {
+		return points
+	}
+	return h.config.Welcome.Points
+}
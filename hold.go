@@ -0,0 +1,45 @@
+package aboki_africa_assessment
+
+import (
+	"context"
+	"time"
+)
+
+// Hold states. Only an ACTIVE hold that hasn't passed its expiry reserves points, every other
+// state is final.
+const (
+	HoldStatusActive   = "ACTIVE"
+	HoldStatusCaptured = "CAPTURED"
+	HoldStatusVoided   = "VOIDED"
+	HoldStatusExpired  = "EXPIRED"
+)
+
+// Hold reserves points of the sender for a transfer to the recipient that is only made once the
+// hold is captured.
+type Hold struct {
+	ID             string    `json:"id"`
+	SenderID       string    `json:"sender_id"`
+	RecipientID    string    `json:"recipient_id"`
+	Points         int       `json:"points"`
+	CapturedPoints int       `json:"captured_points"`
+	Status         string    `json:"status"`
+	TransactionID  string    `json:"transaction_id,omitempty"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (h *Hold) IsExpired(now time.Time) bool {
+	return !now.Before(h.ExpiresAt)
+}
+
+type HoldRepository interface {
+	CreateHold(ctx context.Context, hold *Hold) error
+	// FindHoldByID locks the hold for the rest of the surrounding transaction.
+	FindHoldByID(ctx context.Context, id string) (*Hold, error)
+	UpdateHold(ctx context.Context, hold *Hold) error
+	// GetHeldPoints returns the points reserved by the user's active holds.
+	GetHeldPoints(ctx context.Context, userID string) (int, error)
+	// ExpireHolds marks active holds that expired before the given time as EXPIRED.
+	ExpireHolds(ctx context.Context, before time.Time) (int64, error)
+}
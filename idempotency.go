@@ -0,0 +1,28 @@
+package aboki_africa_assessment
+
+import (
+	"context"
+	"time"
+)
+
+// IdempotencyKey stores the first response given to a request sent with an Idempotency-Key
+// header so retries of the same request get the same response. StatusCode is zero while the
+// first request is still being processed.
+type IdempotencyKey struct {
+	Key          string    `json:"key"`
+	Route        string    `json:"route"`
+	RequestHash  string    `json:"request_hash"`
+	StatusCode   int       `json:"status_code"`
+	ResponseBody []byte    `json:"response_body"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type IdempotencyRepository interface {
+	// ClaimIdempotencyKey stores the key unless an unexpired one exists for the same route, in which
+	// case it returns false and fills key with what is stored.
+	ClaimIdempotencyKey(ctx context.Context, key *IdempotencyKey) (bool, error)
+	SaveIdempotentResponse(ctx context.Context, key *IdempotencyKey) error
+	DeleteIdempotencyKey(ctx context.Context, key, route string) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error)
+}
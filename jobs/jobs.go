@@ -0,0 +1,32 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Every runs fn once per interval until ctx is cancelled. Failures are logged and
+// the job carries on at the next tick. A job without a positive interval never runs.
+func Every(ctx context.Context, interval time.Duration, name string, fn func(ctx context.Context) error) {
+	logger := log.WithField("job", name)
+	if interval <= 0 {
+		logger.Warn("job disabled, no interval configured")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := fn(ctx); err != nil {
+				logger.WithError(err).Error("job failed")
+			}
+		}
+	}
+}
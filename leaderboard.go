@@ -0,0 +1,57 @@
+package aboki_africa_assessment
+
+import (
+	"context"
+	"time"
+)
+
+// Leaderboard periods. WEEK and MONTH cover the current calendar week and month in UTC.
+const (
+	LeaderboardPeriodWeek  = "week"
+	LeaderboardPeriodMonth = "month"
+	LeaderboardPeriodAll   = "all"
+)
+
+// Leaderboard metrics users can be ranked by.
+const (
+	LeaderboardMetricReferrals = "referrals"
+	LeaderboardMetricPoints    = "points"
+)
+
+// LeaderboardPeriods lists the periods refreshed by every leaderboard refresh, with the period their
+// start is computed over.
+var LeaderboardPeriods = map[string]string{
+	LeaderboardPeriodWeek:  CapPeriodWeek,
+	LeaderboardPeriodMonth: CapPeriodMonth,
+	LeaderboardPeriodAll:   "",
+}
+
+// LeaderboardEntry is a user's standing on a leaderboard as of its last refresh. Users with the same
+// score are ranked by who reached it first, then by user id.
+type LeaderboardEntry struct {
+	Rank      int       `json:"rank"`
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	Referrals int       `json:"referrals"`
+	Points    int       `json:"points"`
+	ReachedAt time.Time `json:"reached_at"`
+}
+
+type Leaderboard struct {
+	Period      string              `json:"period"`
+	Metric      string              `json:"metric"`
+	RefreshedAt *time.Time          `json:"refreshed_at"`
+	Entries     []*LeaderboardEntry `json:"entries"`
+	// Me is the standing of the user the leaderboard was requested for, whether or not it made the top entries.
+	Me *LeaderboardEntry `json:"me,omitempty"`
+}
+
+type LeaderboardRepository interface {
+	// RefreshLeaderboard replaces the snapshot of the period with the referrals and referral points
+	// earned since the given time.
+	RefreshLeaderboard(ctx context.Context, period string, since time.Time) error
+	ListLeaderboard(ctx context.Context, period, metric string, limit int) ([]*LeaderboardEntry, error)
+	FindLeaderboardEntry(ctx context.Context, period, metric, userID string) (*LeaderboardEntry, error)
+	// LastRefreshedAt returns when the period was last refreshed, nil if it never was.
+	LastRefreshedAt(ctx context.Context, period string) (*time.Time, error)
+}
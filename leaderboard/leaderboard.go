@@ -0,0 +1,86 @@
+// Package leaderboard ranks users by their referrals and the points they earned for them. Rankings are
+// read from snapshots a background job refreshes, so they lag behind by up to the refresh interval.
+package leaderboard
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/errors"
+
+	"github.com/jackc/pgx/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+type Service struct {
+	leaderboardRepository core.LeaderboardRepository
+	beginTxFunc           func() (pgx.Tx, error)
+}
+
+func New(leaderboardRepository core.LeaderboardRepository, beginTxFunc func() (pgx.Tx, error)) *Service {
+	return &Service{
+		leaderboardRepository: leaderboardRepository,
+		beginTxFunc:           beginTxFunc,
+	}
+}
+
+// Refresh rebuilds the snapshots of every period in one database transaction, readers keep seeing
+// the previous snapshots until it commits.
+func (s *Service) Refresh(ctx context.Context) error {
+	tx, err := s.beginTxFunc()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	ctx = context.WithValue(ctx, core.TxContextKey, tx)
+	now := time.Now()
+	periods := make([]string, 0, len(core.LeaderboardPeriods))
+	for period := range core.LeaderboardPeriods {
+		periods = append(periods, period)
+	}
+	// a stable order keeps concurrent refreshes from deadlocking
+	sort.Strings(periods)
+
+	for _, period := range periods {
+		since := core.PeriodStart(core.LeaderboardPeriods[period], now)
+		if err = s.leaderboardRepository.RefreshLeaderboard(ctx, period, since); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *Service) Get(ctx context.Context, req *Request, logger *log.Entry) (*core.Leaderboard, error) {
+	board := &core.Leaderboard{
+		Period: req.Period,
+		Metric: req.Metric,
+	}
+
+	var err error
+	board.RefreshedAt, err = s.leaderboardRepository.LastRefreshedAt(ctx, req.Period)
+	if err != nil {
+		logger.WithError(err).Error("failed to find last leaderboard refresh")
+		return nil, errors.ErrGeneric
+	}
+
+	board.Entries, err = s.leaderboardRepository.ListLeaderboard(ctx, req.Period, req.Metric, req.Limit)
+	if err != nil {
+		logger.WithError(err).Error("failed to list leaderboard")
+		return nil, errors.ErrGeneric
+	}
+
+	if req.UserID != "" {
+		board.Me, err = s.leaderboardRepository.FindLeaderboardEntry(ctx, req.Period, req.Metric, req.UserID)
+		// users without a score aren't ranked
+		if err != nil && err != pgx.ErrNoRows {
+			logger.WithError(err).Error("failed to find leaderboard entry")
+			return nil, errors.ErrGeneric
+		}
+	}
+
+	return board, nil
+}
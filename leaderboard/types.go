@@ -0,0 +1,51 @@
+package leaderboard
+
+import (
+	"fmt"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+)
+
+const (
+	defaultLimit = 10
+	maxLimit     = 100
+)
+
+type Request struct {
+	Period string
+	Metric string
+	Limit  int
+	// UserID, when set, adds the user's own standing to the leaderboard.
+	UserID string
+}
+
+// Validate checks the request and fills in the defaults of the fields left empty.
+func (r *Request) Validate() error {
+	if r.Period == "" {
+		r.Period = core.LeaderboardPeriodMonth
+	}
+	if _, ok := core.LeaderboardPeriods[r.Period]; !ok {
+		return fmt.Errorf("period must be one of %s, %s or %s", core.LeaderboardPeriodWeek, core.LeaderboardPeriodMonth, core.LeaderboardPeriodAll)
+	}
+
+	switch r.Metric {
+	case "":
+		r.Metric = core.LeaderboardMetricReferrals
+	case core.LeaderboardMetricReferrals, core.LeaderboardMetricPoints:
+	default:
+		return fmt.Errorf("by must be %s or %s", core.LeaderboardMetricReferrals, core.LeaderboardMetricPoints)
+	}
+
+	if r.Limit <= 0 {
+		r.Limit = defaultLimit
+	}
+	if r.Limit > maxLimit {
+		r.Limit = maxLimit
+	}
+
+	if r.UserID != "" && !core.IsUUID(r.UserID) {
+		return fmt.Errorf("user id must be a uuid")
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package aboki_africa_assessment
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Ledger account types. Every user owns one POINTS (spendable) and one BONUS (unclaimed) account,
+// points enter and leave the system through SYSTEM accounts. A user's DEBT account goes negative by
+// what they owe for clawed back rewards they had already spent.
+const (
+	AccountTypePoints = "POINTS"
+	AccountTypeBonus  = "BONUS"
+	AccountTypeDebt   = "DEBT"
+	AccountTypeSystem = "SYSTEM"
+)
+
+// Journal entry types.
+const (
+	EntryTypeOpening    = "OPENING"
+	EntryTypeTransfer   = "TRANSFER"
+	EntryTypeBonus      = "BONUS"
+	EntryTypeBonusClaim = "BONUS_CLAIM"
+	EntryTypeReversal   = "REVERSAL"
+	EntryTypeClawback   = "CLAWBACK"
+	// EntryTypeDebtRepayment settles a user's debt out of their points account.
+	EntryTypeDebtRepayment = "DEBT_REPAYMENT"
+)
+
+// System accounts seeded by the ledger migration.
+const (
+	SystemOpeningBalanceAccount = "system:opening_balance"
+	SystemReferralBonusAccount  = "system:referral_bonus"
+)
+
+type LedgerAccount struct {
+	ID            string    `json:"id"`
+	UserID        string    `json:"user_id"`
+	Code          string    `json:"code"`
+	Type          string    `json:"type"`
+	Balance       int       `json:"balance"`
+	AllowNegative bool      `json:"allow_negative"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// JournalEntry is an immutable record of points moving between accounts.
+// The amounts of its postings always sum to zero.
+type JournalEntry struct {
+	ID          string     `json:"id"`
+	Type        string     `json:"type"`
+	ReferenceID string     `json:"reference_id"`
+	Description string     `json:"description"`
+	Postings    []*Posting `json:"postings"`
+	CreatedAt   time.Time  `json:"created_at"`
+	// ExpiresAt is when the points the entry credits to a user's points account expire, nil if never.
+	ExpiresAt *time.Time `json:"expires_at"`
+	// LotID makes debits from a user's points account come out of that lot instead of the oldest ones.
+	LotID string `json:"lot_id"`
+}
+
+// Posting is a single leg of a journal entry. A positive amount credits the account,
+// a negative amount debits it.
+type Posting struct {
+	ID             string    `json:"id"`
+	JournalEntryID string    `json:"journal_entry_id"`
+	AccountID      string    `json:"account_id"`
+	AccountCode    string    `json:"account_code"`
+	Amount         int       `json:"amount"`
+	BalanceAfter   int       `json:"balance_after"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// NewJournalEntry builds a two-legged entry moving points from one account to another.
+func NewJournalEntry(entryType, referenceID, fromAccount, toAccount string, points int) *JournalEntry {
+	return &JournalEntry{
+		Type:        entryType,
+		ReferenceID: referenceID,
+		Postings: []*Posting{
+			{AccountCode: fromAccount, Amount: -points},
+			{AccountCode: toAccount, Amount: points},
+		},
+	}
+}
+
+// IsBalanced reports whether the postings of the entry sum to zero.
+func (e *JournalEntry) IsBalanced() bool {
+	sum := 0
+	for _, p := range e.Postings {
+		sum += p.Amount
+	}
+	return sum == 0
+}
+
+func UserPointsAccount(userID string) string {
+	return fmt.Sprintf("user:%s:points", userID)
+}
+
+func UserBonusAccount(userID string) string {
+	return fmt.Sprintf("user:%s:bonus", userID)
+}
+
+func UserDebtAccount(userID string) string {
+	return fmt.Sprintf("user:%s:debt", userID)
+}
+
+type LedgerRepository interface {
+	CreateUserAccounts(ctx context.Context, userID string) error
+	FindAccountByCode(ctx context.Context, code string) (*LedgerAccount, error)
+	LockAccount(ctx context.Context, code string) (*LedgerAccount, error)
+	PostEntry(ctx context.Context, entry *JournalEntry) error
+	ListPostingsByAccount(ctx context.Context, code string) ([]*Posting, error)
+	// ListDebtors returns the users who owe points, those whose debt last moved longest ago first.
+	ListDebtors(ctx context.Context, limit int) ([]string, error)
+}
@@ -0,0 +1,35 @@
+package aboki_africa_assessment
+
+import "time"
+
+const (
+	EntryTypeExpiry = "EXPIRY"
+	EntryTypePromo  = "PROMO"
+	// EntryTypeWelcome credits referred users with a welcome bonus when they register.
+	EntryTypeWelcome = "WELCOME"
+)
+
+const (
+	SystemExpiredPointsAccount = "system:expired_points"
+	SystemPromotionsAccount    = "system:promotions"
+	SystemWelcomeAccount       = "system:welcome"
+)
+
+// PointLot is a batch of points credited to a user's points account. Lots are spent
+// oldest first and whatever is left of a lot when it expires is written off.
+type PointLot struct {
+	ID             string     `json:"id"`
+	UserID         string     `json:"user_id"`
+	JournalEntryID string     `json:"journal_entry_id"`
+	Source         string     `json:"source"`
+	Points         int        `json:"points"`
+	Remaining      int        `json:"remaining"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// ExpiringPoints is the amount of a user's points expiring at the same time.
+type ExpiringPoints struct {
+	Points    int       `json:"points"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
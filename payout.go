@@ -0,0 +1,57 @@
+package aboki_africa_assessment
+
+import (
+	"context"
+	"time"
+)
+
+// Payout states. Points are only debited once a payout reaches PayoutStatusSuccess
+// and are refunded if a successful payout is later reversed.
+const (
+	PayoutStatusPending  = "PENDING"
+	PayoutStatusSuccess  = "SUCCESS"
+	PayoutStatusFailed   = "FAILED"
+	PayoutStatusReversed = "REVERSED"
+)
+
+const (
+	EntryTypePayout         = "PAYOUT"
+	EntryTypePayoutReversal = "PAYOUT_REVERSAL"
+)
+
+// SystemPayoutAccount collects the points users cash out.
+const SystemPayoutAccount = "system:payouts"
+
+// PayoutRecipient is a bank account registered with Paystack as a transfer recipient.
+type PayoutRecipient struct {
+	ID            string    `json:"id"`
+	UserID        string    `json:"user_id"`
+	AccountNumber string    `json:"account_number"`
+	BankCode      string    `json:"bank_code"`
+	AccountName   string    `json:"account_name"`
+	RecipientCode string    `json:"recipient_code"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type Payout struct {
+	ID            string    `json:"id"`
+	UserID        string    `json:"user_id"`
+	RecipientID   string    `json:"recipient_id"`
+	Points        int       `json:"points"`
+	AmountKobo    int       `json:"amount_kobo"`
+	Currency      string    `json:"currency"`
+	Status        string    `json:"status"`
+	Reference     string    `json:"reference"`
+	TransferCode  string    `json:"transfer_code"`
+	FailureReason string    `json:"failure_reason"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type PayoutRepository interface {
+	CreateRecipient(ctx context.Context, recipient *PayoutRecipient) error
+	FindRecipient(ctx context.Context, userID, bankCode, accountNumber string) (*PayoutRecipient, error)
+	CreatePayout(ctx context.Context, payout *Payout) error
+	FindPayoutByReference(ctx context.Context, reference string) (*Payout, error)
+	UpdatePayout(ctx context.Context, payout *Payout) error
+}
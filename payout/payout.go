@@ -0,0 +1,280 @@
+package payout
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/config"
+	"github.com/Qalifah/aboki-africa-assessment/errors"
+	"github.com/Qalifah/aboki-africa-assessment/paystack"
+
+	"github.com/jackc/pgx/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+const currency = "NGN"
+
+// Service cashes points out to users' bank accounts through Paystack transfers.
+type Service struct {
+	payoutRepository core.PayoutRepository
+	pointRepository  core.PointRepository
+	ledgerRepository core.LedgerRepository
+	client           paystack.Client
+	config           *config.BaseConfig
+	beginTxFunc      func() (pgx.Tx, error)
+}
+
+func New(payoutRepository core.PayoutRepository, pointRepository core.PointRepository, ledgerRepository core.LedgerRepository,
+	client paystack.Client, cfg *config.BaseConfig, beginTxFunc func() (pgx.Tx, error)) *Service {
+	return &Service{
+		payoutRepository: payoutRepository,
+		pointRepository:  pointRepository,
+		ledgerRepository: ledgerRepository,
+		client:           client,
+		config:           cfg,
+		beginTxFunc:      beginTxFunc,
+	}
+}
+
+// RequestPayout reserves the points for a payout and asks Paystack to transfer their Naira value.
+// The points stay in the user's account until Paystack reports the transfer as successful.
+func (s *Service) RequestPayout(ctx context.Context, input *Request, logger *log.Entry) (*core.Payout, error) {
+	if input.Points < s.config.Payout.MinPoints {
+		return nil, errors.ErrBelowMinimumPayout
+	}
+
+	recipient, err := s.findOrCreateRecipient(ctx, input, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	payout, err := s.createPayout(ctx, input, recipient, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer, err := s.client.InitiateTransfer(ctx, &paystack.TransferRequest{
+		Source:    "balance",
+		Amount:    payout.AmountKobo,
+		Recipient: recipient.RecipientCode,
+		Reason:    "Points cash out",
+		Reference: payout.Reference,
+		Currency:  currency,
+	})
+	if err != nil {
+		if apiErr, ok := err.(*paystack.APIError); !ok || apiErr.StatusCode >= http.StatusInternalServerError {
+			// the transfer may or may not have been queued, leave it pending until it is verified
+			logger.WithError(err).Error("failed to initiate paystack transfer")
+			return payout, nil
+		}
+		transfer = &paystack.Transfer{Reference: payout.Reference, Status: paystack.TransferFailed, Reason: err.Error()}
+	}
+
+	return s.applyTransfer(ctx, transfer, logger)
+}
+
+// VerifyPayout refreshes a pending payout from Paystack and returns its current state.
+func (s *Service) VerifyPayout(ctx context.Context, reference string, logger *log.Entry) (*core.Payout, error) {
+	payout, err := s.payoutRepository.FindPayoutByReference(ctx, reference)
+	if err == pgx.ErrNoRows {
+		return nil, errors.ErrPayoutNotFound
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to find payout")
+		return nil, errors.ErrGeneric
+	}
+
+	if payout.Status != core.PayoutStatusPending {
+		return payout, nil
+	}
+
+	transfer, err := s.client.VerifyTransfer(ctx, reference)
+	if err != nil {
+		logger.WithError(err).Error("failed to verify paystack transfer")
+		return payout, nil
+	}
+
+	return s.applyTransfer(ctx, transfer, logger)
+}
+
+// HandleWebhook applies a signed Paystack transfer event to the payout it refers to.
+func (s *Service) HandleWebhook(ctx context.Context, body []byte, signature string, logger *log.Entry) error {
+	if !paystack.VerifySignature(s.config.PaystackAPIKey, body, signature) {
+		return errors.ErrInvalidSignature
+	}
+
+	event := &paystack.Event{}
+	if err := json.Unmarshal(body, event); err != nil {
+		logger.WithError(err).Error("failed to decode paystack event")
+		return errors.ErrGeneric
+	}
+
+	switch event.Event {
+	case paystack.EventTransferSuccess, paystack.EventTransferFailed, paystack.EventTransferReversed:
+		_, err := s.applyTransfer(ctx, &event.Data, logger)
+		return err
+	default:
+		return nil
+	}
+}
+
+func (s *Service) findOrCreateRecipient(ctx context.Context, input *Request, logger *log.Entry) (*core.PayoutRecipient, error) {
+	recipient, err := s.payoutRepository.FindRecipient(ctx, input.UserID, input.BankCode, input.AccountNumber)
+	if err == nil {
+		return recipient, nil
+	}
+	if err != pgx.ErrNoRows {
+		logger.WithError(err).Error("failed to find payout recipient")
+		return nil, errors.ErrGeneric
+	}
+
+	res, err := s.client.CreateTransferRecipient(ctx, &paystack.RecipientRequest{
+		Type:          "nuban",
+		Name:          input.AccountName,
+		AccountNumber: input.AccountNumber,
+		BankCode:      input.BankCode,
+		Currency:      currency,
+	})
+	if err != nil {
+		logger.WithError(err).Error("failed to create paystack transfer recipient")
+		return nil, errors.ErrPayoutFailed
+	}
+
+	recipient = &core.PayoutRecipient{
+		UserID:        input.UserID,
+		AccountNumber: input.AccountNumber,
+		BankCode:      input.BankCode,
+		AccountName:   input.AccountName,
+		RecipientCode: res.RecipientCode,
+	}
+
+	if err = s.payoutRepository.CreateRecipient(ctx, recipient); err != nil {
+		logger.WithError(err).Error("failed to save payout recipient")
+		return nil, errors.ErrGeneric
+	}
+
+	return recipient, nil
+}
+
+func (s *Service) createPayout(ctx context.Context, input *Request, recipient *core.PayoutRecipient, logger *log.Entry) (*core.Payout, error) {
+	tx, err := s.beginTxFunc()
+	if err != nil {
+		logger.WithError(err).Error("failed to start transaction")
+		return nil, errors.ErrGeneric
+	}
+	defer tx.Rollback(ctx)
+
+	ctx = context.WithValue(ctx, core.TxContextKey, tx)
+	// lock the account so concurrent payouts and transfers can't both spend the same points
+	_, err = s.ledgerRepository.LockAccount(ctx, core.UserPointsAccount(input.UserID))
+	if err != nil {
+		logger.WithError(err).Error("failed to lock user points account")
+		return nil, errors.ErrGeneric
+	}
+
+	balance, err := s.pointRepository.GetPointsBalance(ctx, input.UserID)
+	if err != nil {
+		logger.WithError(err).Error("failed to get user points balance")
+		return nil, errors.ErrGeneric
+	}
+
+	if balance < input.Points {
+		return nil, errors.ErrInsufficientFunds
+	}
+
+	payout := &core.Payout{
+		UserID:      input.UserID,
+		RecipientID: recipient.ID,
+		Points:      input.Points,
+		AmountKobo:  input.Points * s.config.Payout.KoboPerPoint,
+		Currency:    currency,
+		Status:      core.PayoutStatusPending,
+		Reference:   newReference(),
+	}
+
+	if err = s.payoutRepository.CreatePayout(ctx, payout); err != nil {
+		logger.WithError(err).Error("failed to create payout")
+		return nil, errors.ErrGeneric
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.WithError(err).Error("failed to commit transaction")
+		return nil, errors.ErrGeneric
+	}
+
+	return payout, nil
+}
+
+// applyTransfer moves the payout to the state Paystack reports for its transfer. Points are debited
+// when a pending payout succeeds and refunded when a successful one is reversed, events that arrive
+// after the payout reached a final state are ignored.
+func (s *Service) applyTransfer(ctx context.Context, transfer *paystack.Transfer, logger *log.Entry) (*core.Payout, error) {
+	tx, err := s.beginTxFunc()
+	if err != nil {
+		logger.WithError(err).Error("failed to start transaction")
+		return nil, errors.ErrGeneric
+	}
+	defer tx.Rollback(ctx)
+
+	ctx = context.WithValue(ctx, core.TxContextKey, tx)
+	payout, err := s.payoutRepository.FindPayoutByReference(ctx, transfer.Reference)
+	if err == pgx.ErrNoRows {
+		return nil, errors.ErrPayoutNotFound
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to find payout")
+		return nil, errors.ErrGeneric
+	}
+
+	if transfer.TransferCode != "" {
+		payout.TransferCode = transfer.TransferCode
+	}
+
+	var entry *core.JournalEntry
+	switch {
+	case transfer.Status == paystack.TransferSuccess && payout.Status == core.PayoutStatusPending:
+		payout.Status = core.PayoutStatusSuccess
+		entry = core.NewJournalEntry(core.EntryTypePayout, payout.ID, core.UserPointsAccount(payout.UserID),
+			core.SystemPayoutAccount, payout.Points)
+	case transfer.Status == paystack.TransferFailed && payout.Status == core.PayoutStatusPending:
+		payout.Status = core.PayoutStatusFailed
+		payout.FailureReason = transfer.Reason
+	case transfer.Status == paystack.TransferReversed && payout.Status == core.PayoutStatusSuccess:
+		payout.Status = core.PayoutStatusReversed
+		entry = core.NewJournalEntry(core.EntryTypePayoutReversal, payout.ID, core.SystemPayoutAccount,
+			core.UserPointsAccount(payout.UserID), payout.Points)
+	case transfer.Status == paystack.TransferReversed && payout.Status == core.PayoutStatusPending:
+		payout.Status = core.PayoutStatusReversed
+	}
+
+	if entry != nil {
+		if err = s.ledgerRepository.PostEntry(ctx, entry); err != nil {
+			logger.WithError(err).WithField("reference", payout.Reference).Error("failed to post payout")
+			return nil, errors.ErrGeneric
+		}
+	}
+
+	if err = s.payoutRepository.UpdatePayout(ctx, payout); err != nil {
+		logger.WithError(err).Error("failed to update payout")
+		return nil, errors.ErrGeneric
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.WithError(err).Error("failed to commit transaction")
+		return nil, errors.ErrGeneric
+	}
+
+	return payout, nil
+}
+
+func newReference() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return "payout_" + hex.EncodeToString(b)
+}
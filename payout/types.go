@@ -0,0 +1,9 @@
+package payout
+
+type Request struct {
+	UserID        string `json:"user_id"`
+	Points        int    `json:"points"`
+	AccountNumber string `json:"account_number"`
+	BankCode      string `json:"bank_code"`
+	AccountName   string `json:"account_name"`
+}
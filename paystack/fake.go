@@ -0,0 +1,162 @@
+package paystack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// FakeServer is a local stand-in for the Paystack transfers API so payouts can be
+// exercised without network access. Transfers start out pending, or in the status
+// set with SetTransferStatus, and are settled by sending the matching webhook event.
+type FakeServer struct {
+	*httptest.Server
+
+	secret string
+
+	mu             sync.Mutex
+	transferStatus string
+	transferError  int
+	recipients     map[string]*RecipientRequest
+	transfers      map[string]*Transfer
+}
+
+func NewFakeServer(secret string) *FakeServer {
+	f := &FakeServer{
+		secret:         secret,
+		transferStatus: TransferPending,
+		recipients:     map[string]*RecipientRequest{},
+		transfers:      map[string]*Transfer{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/transferrecipient", f.createRecipient)
+	mux.HandleFunc("/transfer", f.initiateTransfer)
+	mux.HandleFunc("/transfer/verify/", f.verifyTransfer)
+	f.Server = httptest.NewServer(f.authorize(mux))
+
+	return f
+}
+
+// SetTransferStatus changes the status new transfers are created with.
+func (f *FakeServer) SetTransferStatus(status string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.transferStatus = status
+}
+
+// SetTransferError makes new transfers get queued but answered with the given HTTP status, the way
+// Paystack sometimes fails after accepting a transfer. Zero answers them normally again.
+func (f *FakeServer) SetTransferError(status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.transferError = status
+}
+
+// SendEvent settles the transfer with the given reference and delivers the signed
+// webhook event to webhookURL.
+func (f *FakeServer) SendEvent(webhookURL, event, reference string) (*http.Response, error) {
+	f.mu.Lock()
+	transfer, ok := f.transfers[reference]
+	if !ok {
+		f.mu.Unlock()
+		return nil, fmt.Errorf("unknown transfer reference %q", reference)
+	}
+	transfer.Status = strings.TrimPrefix(event, "transfer.")
+	body, err := json.Marshal(&Event{Event: event, Data: *transfer})
+	f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(f.secret, body))
+
+	return http.DefaultClient.Do(req)
+}
+
+func (f *FakeServer) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+f.secret {
+			f.write(w, http.StatusUnauthorized, false, "Invalid key", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (f *FakeServer) createRecipient(w http.ResponseWriter, r *http.Request) {
+	req := &RecipientRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil || req.AccountNumber == "" || req.BankCode == "" {
+		f.write(w, http.StatusBadRequest, false, "Invalid recipient", nil)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	code := fmt.Sprintf("RCP_%d", len(f.recipients)+1)
+	f.recipients[code] = req
+	f.write(w, http.StatusCreated, true, "Transfer recipient created successfully", &Recipient{RecipientCode: code, Name: req.Name})
+}
+
+func (f *FakeServer) initiateTransfer(w http.ResponseWriter, r *http.Request) {
+	req := &TransferRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil || req.Amount <= 0 {
+		f.write(w, http.StatusBadRequest, false, "Invalid transfer", nil)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.recipients[req.Recipient]; !ok {
+		f.write(w, http.StatusBadRequest, false, "Recipient not found", nil)
+		return
+	}
+
+	if transfer, ok := f.transfers[req.Reference]; ok {
+		f.write(w, http.StatusBadRequest, false, "Duplicate Transfer Reference", transfer)
+		return
+	}
+
+	transfer := &Transfer{
+		Reference:    req.Reference,
+		TransferCode: fmt.Sprintf("TRF_%d", len(f.transfers)+1),
+		Status:       f.transferStatus,
+		Amount:       req.Amount,
+		Reason:       req.Reason,
+	}
+	f.transfers[req.Reference] = transfer
+	if f.transferError != 0 {
+		f.write(w, f.transferError, false, http.StatusText(f.transferError), nil)
+		return
+	}
+	f.write(w, http.StatusOK, true, "Transfer has been queued", transfer)
+}
+
+func (f *FakeServer) verifyTransfer(w http.ResponseWriter, r *http.Request) {
+	reference := strings.TrimPrefix(r.URL.Path, "/transfer/verify/")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	transfer, ok := f.transfers[reference]
+	if !ok {
+		f.write(w, http.StatusNotFound, false, "Transfer not found", nil)
+		return
+	}
+	f.write(w, http.StatusOK, true, "Transfer retrieved", transfer)
+}
+
+func (f *FakeServer) write(w http.ResponseWriter, status int, ok bool, message string, data interface{}) {
+	buf, _ := json.Marshal(data)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&response{Status: ok, Message: message, Data: buf})
+}
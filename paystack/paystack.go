@@ -0,0 +1,162 @@
+package paystack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const DefaultBaseURL = "https://api.paystack.co"
+
+// Transfer states reported by Paystack.
+const (
+	TransferPending  = "pending"
+	TransferOTP      = "otp"
+	TransferSuccess  = "success"
+	TransferFailed   = "failed"
+	TransferReversed = "reversed"
+)
+
+type RecipientRequest struct {
+	Type          string `json:"type"`
+	Name          string `json:"name"`
+	AccountNumber string `json:"account_number"`
+	BankCode      string `json:"bank_code"`
+	Currency      string `json:"currency"`
+}
+
+type Recipient struct {
+	RecipientCode string `json:"recipient_code"`
+	Name          string `json:"name"`
+}
+
+type TransferRequest struct {
+	Source    string `json:"source"`
+	Amount    int    `json:"amount"`
+	Recipient string `json:"recipient"`
+	Reason    string `json:"reason"`
+	Reference string `json:"reference"`
+	Currency  string `json:"currency"`
+}
+
+type Transfer struct {
+	Reference    string `json:"reference"`
+	TransferCode string `json:"transfer_code"`
+	Status       string `json:"status"`
+	Amount       int    `json:"amount"`
+	Reason       string `json:"reason"`
+}
+
+// Client is the subset of the Paystack transfers API used to pay users out.
+type Client interface {
+	CreateTransferRecipient(ctx context.Context, req *RecipientRequest) (*Recipient, error)
+	InitiateTransfer(ctx context.Context, req *TransferRequest) (*Transfer, error)
+	VerifyTransfer(ctx context.Context, reference string) (*Transfer, error)
+}
+
+// APIError is returned when Paystack answers a request with an error, as opposed to
+// the request not reaching Paystack or its response getting lost. Only a 4xx means
+// the request was rejected, after a 5xx it may still have gone through.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("paystack: %d %s", e.StatusCode, e.Message)
+}
+
+type response struct {
+	Status  bool            `json:"status"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type HTTPClient struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+// New returns a Paystack client authenticating with the secret key apiKey.
+// An empty baseURL talks to the live API.
+func New(apiKey, baseURL string) *HTTPClient {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	return &HTTPClient{
+		apiKey:  apiKey,
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (c *HTTPClient) CreateTransferRecipient(ctx context.Context, req *RecipientRequest) (*Recipient, error) {
+	recipient := &Recipient{}
+	if err := c.do(ctx, http.MethodPost, "/transferrecipient", req, recipient); err != nil {
+		return nil, err
+	}
+	return recipient, nil
+}
+
+func (c *HTTPClient) InitiateTransfer(ctx context.Context, req *TransferRequest) (*Transfer, error) {
+	transfer := &Transfer{}
+	if err := c.do(ctx, http.MethodPost, "/transfer", req, transfer); err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+func (c *HTTPClient) VerifyTransfer(ctx context.Context, reference string) (*Transfer, error) {
+	transfer := &Transfer{}
+	if err := c.do(ctx, http.MethodGet, "/transfer/verify/"+reference, nil, transfer); err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+func (c *HTTPClient) do(ctx context.Context, method, path string, body, data interface{}) error {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, &buf)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "paystack request failed")
+	}
+	defer resp.Body.Close()
+
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	res := &response{}
+	if err = json.Unmarshal(raw, res); err != nil {
+		return errors.Wrapf(err, "failed to decode paystack response with status %d", resp.StatusCode)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest || !res.Status {
+		return &APIError{StatusCode: resp.StatusCode, Message: res.Message}
+	}
+
+	return json.Unmarshal(res.Data, data)
+}
@@ -0,0 +1,33 @@
+package paystack
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+)
+
+// SignatureHeader carries the HMAC of a webhook body signed with the secret key.
+const SignatureHeader = "x-paystack-signature"
+
+// Webhook events for transfers.
+const (
+	EventTransferSuccess  = "transfer.success"
+	EventTransferFailed   = "transfer.failed"
+	EventTransferReversed = "transfer.reversed"
+)
+
+type Event struct {
+	Event string   `json:"event"`
+	Data  Transfer `json:"data"`
+}
+
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha512.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports whether signature is the HMAC-SHA512 of body under secret.
+func VerifySignature(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
@@ -0,0 +1,70 @@
+package aboki_africa_assessment
+
+import (
+	"context"
+	"time"
+)
+
+// Reconciliation run states.
+const (
+	RunStatusRunning   = "RUNNING"
+	RunStatusCompleted = "COMPLETED"
+	RunStatusFailed    = "FAILED"
+)
+
+// Reconciliation checks. POINTS, BONUS and DEBT compare the cached balance of a user's account with
+// its postings plus any transaction that never made it into the ledger, REFERRALS compares the
+// referral counter of a user with their rows in referrals.
+const (
+	CheckPoints    = "POINTS"
+	CheckBonus     = "BONUS"
+	CheckDebt      = "DEBT"
+	CheckReferrals = "REFERRALS"
+)
+
+const EntryTypeAdjustment = "ADJUSTMENT"
+
+// SystemAdjustmentsAccount balances the correcting entries written by reconciliation.
+const SystemAdjustmentsAccount = "system:adjustments"
+
+type ReconciliationRun struct {
+	ID            string                `json:"id"`
+	Repair        bool                  `json:"repair"`
+	Status        string                `json:"status"`
+	UsersChecked  int                   `json:"users_checked"`
+	Discrepancies int                   `json:"discrepancies"`
+	Repaired      int                   `json:"repaired"`
+	Error         string                `json:"error"`
+	Items         []*ReconciliationItem `json:"items,omitempty"`
+	StartedAt     time.Time             `json:"started_at"`
+	FinishedAt    *time.Time            `json:"finished_at"`
+}
+
+// ReconciliationItem is a discrepancy found by a run.
+type ReconciliationItem struct {
+	ID        string    `json:"id"`
+	RunID     string    `json:"run_id"`
+	UserID    string    `json:"user_id"`
+	Check     string    `json:"check"`
+	Expected  int       `json:"expected"`
+	Actual    int       `json:"actual"`
+	Repaired  bool      `json:"repaired"`
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ReconciliationRepository interface {
+	CreateRun(ctx context.Context, run *ReconciliationRun) error
+	FinishRun(ctx context.Context, run *ReconciliationRun) error
+	FindRunByID(ctx context.Context, id string) (*ReconciliationRun, error)
+	ListRuns(ctx context.Context, limit int) ([]*ReconciliationRun, error)
+	CreateItem(ctx context.Context, item *ReconciliationItem) error
+	ListItems(ctx context.Context, runID string) ([]*ReconciliationItem, error)
+	// FindDiscrepancies recomputes every user's balances and referral counter and returns the ones
+	// that don't match, along with how many users were checked.
+	FindDiscrepancies(ctx context.Context) ([]*ReconciliationItem, int, error)
+	// ListUnpostedTransactions returns the user's transactions that have no journal entry.
+	ListUnpostedTransactions(ctx context.Context, userID string) ([]*Transaction, error)
+	ResyncAccountBalance(ctx context.Context, code string) error
+	ResyncReferralCount(ctx context.Context, userID string) error
+}
@@ -0,0 +1,203 @@
+package reconciliation
+
+import (
+	"context"
+	"fmt"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/errors"
+
+	"github.com/jackc/pgx/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultRunsLimit = 20
+
+// Service recomputes users' balances from the ledger and transaction history and reports,
+// and optionally repairs, the ones that drifted.
+type Service struct {
+	reconciliationRepository core.ReconciliationRepository
+	ledgerRepository         core.LedgerRepository
+	beginTxFunc              func() (pgx.Tx, error)
+}
+
+func New(reconciliationRepository core.ReconciliationRepository, ledgerRepository core.LedgerRepository, beginTxFunc func() (pgx.Tx, error)) *Service {
+	return &Service{
+		reconciliationRepository: reconciliationRepository,
+		ledgerRepository:         ledgerRepository,
+		beginTxFunc:              beginTxFunc,
+	}
+}
+
+// Run records a reconciliation run and every discrepancy it finds. With repair set, each
+// discrepancy is corrected in its own database transaction as it is recorded.
+func (s *Service) Run(ctx context.Context, repair bool, logger *log.Entry) (*core.ReconciliationRun, error) {
+	run := &core.ReconciliationRun{
+		Repair: repair,
+		Status: core.RunStatusRunning,
+	}
+
+	if err := s.reconciliationRepository.CreateRun(ctx, run); err != nil {
+		logger.WithError(err).Error("failed to create reconciliation run")
+		return nil, errors.ErrGeneric
+	}
+
+	items, usersChecked, err := s.reconciliationRepository.FindDiscrepancies(ctx)
+	if err != nil {
+		logger.WithError(err).Error("failed to find discrepancies")
+		run.Status = core.RunStatusFailed
+		run.Error = err.Error()
+		return run, s.finish(ctx, run, logger)
+	}
+
+	run.UsersChecked = usersChecked
+	run.Discrepancies = len(items)
+	for _, item := range items {
+		item.RunID = run.ID
+		if repair {
+			if err = s.repair(ctx, run, item); err != nil {
+				logger.WithError(err).WithField("user_id", item.UserID).Error("failed to repair discrepancy")
+				item.Note = err.Error()
+			} else {
+				item.Repaired = true
+				run.Repaired++
+			}
+		}
+
+		if err = s.reconciliationRepository.CreateItem(ctx, item); err != nil {
+			logger.WithError(err).Error("failed to save reconciliation item")
+		}
+	}
+
+	run.Status = core.RunStatusCompleted
+	run.Items = items
+	return run, s.finish(ctx, run, logger)
+}
+
+// Job returns a background job running reconciliation with the given repair mode.
+func (s *Service) Job(repair bool) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		run, err := s.Run(ctx, repair, log.WithField("job", "reconciliation"))
+		if err != nil {
+			return err
+		}
+
+		if run.Discrepancies > 0 {
+			log.WithFields(map[string]interface{}{
+				"run_id":        run.ID,
+				"discrepancies": run.Discrepancies,
+				"repaired":      run.Repaired,
+			}).Warn("reconciliation found discrepancies")
+		}
+		return nil
+	}
+}
+
+func (s *Service) FindRun(ctx context.Context, id string, logger *log.Entry) (*core.ReconciliationRun, error) {
+	run, err := s.reconciliationRepository.FindRunByID(ctx, id)
+	if err == pgx.ErrNoRows {
+		return nil, errors.ErrReconciliationRunNotFound
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to find reconciliation run")
+		return nil, errors.ErrGeneric
+	}
+
+	run.Items, err = s.reconciliationRepository.ListItems(ctx, id)
+	if err != nil {
+		logger.WithError(err).Error("failed to list reconciliation items")
+		return nil, errors.ErrGeneric
+	}
+
+	return run, nil
+}
+
+func (s *Service) ListRuns(ctx context.Context, logger *log.Entry) ([]*core.ReconciliationRun, error) {
+	runs, err := s.reconciliationRepository.ListRuns(ctx, defaultRunsLimit)
+	if err != nil {
+		logger.WithError(err).Error("failed to list reconciliation runs")
+		return nil, errors.ErrGeneric
+	}
+	return runs, nil
+}
+
+func (s *Service) finish(ctx context.Context, run *core.ReconciliationRun, logger *log.Entry) error {
+	if err := s.reconciliationRepository.FinishRun(ctx, run); err != nil {
+		logger.WithError(err).Error("failed to finish reconciliation run")
+		return errors.ErrGeneric
+	}
+	return nil
+}
+
+// repair resyncs the cached value the item is about with its source of truth. For balances
+// that means resetting the account to its postings and posting an ADJUSTMENT entry for every
+// transaction of the user that never reached the ledger.
+func (s *Service) repair(ctx context.Context, run *core.ReconciliationRun, item *core.ReconciliationItem) error {
+	tx, err := s.beginTxFunc()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	ctx = context.WithValue(ctx, core.TxContextKey, tx)
+	switch item.Check {
+	case core.CheckReferrals:
+		err = s.reconciliationRepository.ResyncReferralCount(ctx, item.UserID)
+	case core.CheckPoints, core.CheckBonus, core.CheckDebt:
+		err = s.repairBalances(ctx, run, item.UserID)
+	default:
+		err = fmt.Errorf("unknown reconciliation check %q", item.Check)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *Service) repairBalances(ctx context.Context, run *core.ReconciliationRun, userID string) error {
+	for _, code := range []string{core.UserPointsAccount(userID), core.UserBonusAccount(userID), core.UserDebtAccount(userID)} {
+		if err := s.reconciliationRepository.ResyncAccountBalance(ctx, code); err != nil {
+			return err
+		}
+	}
+
+	unposted, err := s.reconciliationRepository.ListUnpostedTransactions(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, tran := range unposted {
+		from, to, ok := transactionAccounts(tran)
+		if !ok {
+			return fmt.Errorf("transaction %s has unknown type %q", tran.ID, tran.Type)
+		}
+
+		entry := core.NewJournalEntry(core.EntryTypeAdjustment, tran.ID, from, to, tran.Points)
+		entry.Description = fmt.Sprintf("reconciliation run %s: unposted %s transaction", run.ID, tran.Type)
+		if err = s.ledgerRepository.PostEntry(ctx, entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// transactionAccounts returns the accounts a transaction of the given type moves points between.
+func transactionAccounts(tran *core.Transaction) (string, string, bool) {
+	switch tran.Type {
+	case core.EntryTypeTransfer, core.EntryTypeReversal:
+		return core.UserPointsAccount(tran.SenderID), core.UserPointsAccount(tran.RecipientID), true
+	case core.EntryTypeBonus:
+		// BONUS transactions are bonus claims
+		return core.UserBonusAccount(tran.SenderID), core.UserPointsAccount(tran.RecipientID), true
+	case core.EntryTypePromo:
+		return core.SystemPromotionsAccount, core.UserPointsAccount(tran.RecipientID), true
+	case core.EntryTypeWelcome:
+		return core.SystemWelcomeAccount, core.UserPointsAccount(tran.RecipientID), true
+	case core.EntryTypeExpiry:
+		return core.UserPointsAccount(tran.SenderID), core.SystemExpiredPointsAccount, true
+	default:
+		return "", "", false
+	}
+}
@@ -0,0 +1,6 @@
+package reconciliation
+
+type Request struct {
+	// Repair makes the run correct the discrepancies it finds.
+	Repair bool `json:"repair"`
+}
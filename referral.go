@@ -0,0 +1,95 @@
+package aboki_africa_assessment
+
+import "time"
+
+const (
+	ReferralStatusPending   = "PENDING"
+	ReferralStatusQualified = "QUALIFIED"
+	ReferralStatusExpired   = "EXPIRED"
+	// ReferralStatusClawedBack referrals had their rewards taken back and no longer count towards the referrer's.
+	ReferralStatusClawedBack = "CLAWED_BACK"
+	// ReferralStatusHeld referrals scored too high on fraud signals, their rewards wait for a reviewer.
+	ReferralStatusHeld = "HELD"
+	// ReferralStatusDropped referrals were duplicate or self-referrals dropped when a user's referral was made
+	// unique. They no longer count towards the referrer's, but the rewards they released are only taken back
+	// by clawing them back.
+	ReferralStatusDropped = "DROPPED"
+)
+
+const (
+	RewardStatusPending    = "PENDING"
+	RewardStatusReleased   = "RELEASED"
+	RewardStatusExpired    = "EXPIRED"
+	RewardStatusClawedBack = "CLAWED_BACK"
+)
+
+// Qualifying actions a referee completes for the rewards for referring them to be released.
+const (
+	// QualifyNone releases rewards as soon as the referee registers.
+	QualifyNone          = "none"
+	QualifyFirstTransfer = "first_transfer"
+	// QualifyMinPoints waits for the referee's points balance to reach a threshold.
+	QualifyMinPoints     = "min_points"
+	QualifyEmailVerified = "email_verified"
+)
+
+var QualifyingActions = map[string]bool{
+	QualifyNone:          true,
+	QualifyFirstTransfer: true,
+	QualifyMinPoints:     true,
+	QualifyEmailVerified: true,
+}
+
+// Referee is a user as listed to the referrer who referred them.
+type Referee struct {
+	ReferralID string `json:"referral_id"`
+	UserID     string `json:"user_id"`
+	Name       string `json:"name"`
+	CampaignID string `json:"campaign_id,omitempty"`
+	Status     string `json:"status"`
+	// Active referees made at least one transfer.
+	Active bool `json:"active"`
+	// PointsEarned is what the referrer earned for referring them.
+	PointsEarned int       `json:"points_earned"`
+	ReferredAt   time.Time `json:"referred_at"`
+}
+
+// RefereeCursor is the position of a referee in the (referred_at, referral_id) ordering.
+type RefereeCursor struct {
+	ReferredAt time.Time
+	ReferralID string
+}
+
+// ReferralDescendant is a user down the referral chain of another, Level 1 being the users they referred.
+type ReferralDescendant struct {
+	UserID     string
+	ReferrerID string
+	Name       string
+	Level      int
+	ReferredAt time.Time
+}
+
+type ReferralTreeNode struct {
+	UserID     string              `json:"user_id"`
+	Name       string              `json:"name"`
+	Level      int                 `json:"level"`
+	ReferredAt *time.Time          `json:"referred_at,omitempty"`
+	Children   []*ReferralTreeNode `json:"children"`
+}
+
+type ReferralStats struct {
+	UserID         string `json:"user_id"`
+	TotalReferees  int    `json:"total_referees"`
+	ActiveReferees int    `json:"active_referees"`
+	// PointsEarned sums the referral rewards and campaign rewards the user earned for referrals at every level.
+	PointsEarned int              `json:"points_earned"`
+	ByMonth      []*ReferralMonth `json:"by_month"`
+}
+
+// ReferralMonth counts the referees a user referred in a month and how many of them became active.
+type ReferralMonth struct {
+	Month          string  `json:"month"`
+	Referees       int     `json:"referees"`
+	Active         int     `json:"active"`
+	ConversionRate float64 `json:"conversion_rate"`
+}
@@ -0,0 +1,198 @@
+package aboki_africa_assessment
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Reward rule kinds. FLAT rewards every referral, MILESTONE rewards every Nth referral or the listed
+// referral counts and TIERED rewards each referral by the tier its count falls in.
+const (
+	RuleKindFlat      = "FLAT"
+	RuleKindMilestone = "MILESTONE"
+	RuleKindTiered    = "TIERED"
+)
+
+// Periods a reward rule can be capped over. A cap without a period applies over the lifetime of the rule.
+const (
+	CapPeriodDay   = "DAY"
+	CapPeriodWeek  = "WEEK"
+	CapPeriodMonth = "MONTH"
+)
+
+// RewardTier rewards the From-th to To-th referral of a user with Points each. A zero To leaves the tier open ended.
+type RewardTier struct {
+	From   int `json:"from"`
+	To     int `json:"to"`
+	Points int `json:"points"`
+}
+
+// RewardRule decides how many bonus points a referrer earns for a referral. Every change to a rule
+// bumps its Version so grants can be traced back to the rule as it was when they were made.
+type RewardRule struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Kind    string `json:"kind"`
+	Version int    `json:"version"`
+	Active  bool   `json:"active"`
+	// Level is how far up the referral chain the rewarded user is: 1 rewards the direct referrer,
+	// 2 the referrer's referrer and so on.
+	Level      int          `json:"level"`
+	Points     int          `json:"points"`
+	Every      int          `json:"every"`
+	Milestones []int        `json:"milestones"`
+	Tiers      []RewardTier `json:"tiers"`
+	CapPoints  int          `json:"cap_points"`
+	CapPeriod  string       `json:"cap_period"`
+	// CampaignID ties the rule to a campaign. Campaign rules only reward registrations made with the
+	// campaign's codes and are left out of regular referral rewards.
+	CampaignID string    `json:"campaign_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Reward returns the points the rule grants for a referrer's count-th referral, before any cap.
+func (r *RewardRule) Reward(count int) int {
+	switch r.Kind {
+	case RuleKindFlat:
+		return r.Points
+	case RuleKindMilestone:
+		if r.Every > 0 && count%r.Every == 0 {
+			return r.Points
+		}
+		for _, milestone := range r.Milestones {
+			if milestone == count {
+				return r.Points
+			}
+		}
+	case RuleKindTiered:
+		for _, tier := range r.Tiers {
+			if count >= tier.From && (tier.To == 0 || count <= tier.To) {
+				return tier.Points
+			}
+		}
+	}
+	return 0
+}
+
+// PeriodStart returns the start of the cap period the given time falls in, the zero time for rules
+// capped over their lifetime.
+func (r *RewardRule) PeriodStart(now time.Time) time.Time {
+	return PeriodStart(r.CapPeriod, now)
+}
+
+// PeriodStart returns the start, in UTC, of the DAY, WEEK or MONTH the given time falls in, the zero
+// time for any other period.
+func PeriodStart(period string, now time.Time) time.Time {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case CapPeriodDay:
+		return day
+	case CapPeriodWeek:
+		// weeks start on monday
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case CapPeriodMonth:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Time{}
+	}
+}
+
+func (r *RewardRule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	switch r.Kind {
+	case RuleKindFlat:
+		if r.Points <= 0 {
+			return fmt.Errorf("points must be greater than zero")
+		}
+	case RuleKindMilestone:
+		if r.Points <= 0 {
+			return fmt.Errorf("points must be greater than zero")
+		}
+		if r.Every < 0 || (r.Every == 0 && len(r.Milestones) == 0) {
+			return fmt.Errorf("a milestone rule needs a positive every or a list of milestones")
+		}
+		for _, milestone := range r.Milestones {
+			if milestone <= 0 {
+				return fmt.Errorf("milestones must be greater than zero")
+			}
+		}
+	case RuleKindTiered:
+		if len(r.Tiers) == 0 {
+			return fmt.Errorf("a tiered rule needs at least one tier")
+		}
+		for i, tier := range r.Tiers {
+			if tier.From <= 0 || (tier.To != 0 && tier.To < tier.From) || tier.Points <= 0 {
+				return fmt.Errorf("tier %d is invalid", i+1)
+			}
+			for _, other := range r.Tiers[:i] {
+				if (tier.To == 0 || tier.To >= other.From) && (other.To == 0 || other.To >= tier.From) {
+					return fmt.Errorf("tier %d overlaps another tier", i+1)
+				}
+			}
+		}
+	default:
+		return fmt.Errorf("kind must be one of %s, %s or %s", RuleKindFlat, RuleKindMilestone, RuleKindTiered)
+	}
+
+	if r.Level < 1 {
+		return fmt.Errorf("level must be at least 1")
+	}
+
+	// only direct referrers have a referral count the other kinds could be evaluated against
+	if r.Level > 1 && r.Kind != RuleKindFlat {
+		return fmt.Errorf("rules for level 2 and above must be %s", RuleKindFlat)
+	}
+
+	if r.CapPoints < 0 {
+		return fmt.Errorf("cap_points cannot be negative")
+	}
+
+	switch r.CapPeriod {
+	case "", CapPeriodDay, CapPeriodWeek, CapPeriodMonth:
+	default:
+		return fmt.Errorf("cap_period must be one of %s, %s or %s", CapPeriodDay, CapPeriodWeek, CapPeriodMonth)
+	}
+
+	return nil
+}
+
+// ReferralReward records bonus points granted to a user for a referral and the rule, at the version
+// it had, that granted them.
+type ReferralReward struct {
+	ID             string    `json:"id"`
+	ReferralID     string    `json:"referral_id"`
+	UserID         string    `json:"user_id"`
+	RuleID         string    `json:"rule_id"`
+	RuleVersion    int       `json:"rule_version"`
+	Level          int       `json:"level"`
+	Points         int       `json:"points"`
+	Status         string    `json:"status"`
+	JournalEntryID string    `json:"journal_entry_id,omitempty"`
+	CampaignID     string    `json:"campaign_id,omitempty"` // the campaign of the rule that granted it, if any
+	Milestone      int       `json:"milestone,omitempty"`   // the referral count a milestone rule granted it for
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type RewardRuleRepository interface {
+	CreateRewardRule(ctx context.Context, rule *RewardRule) error
+	FindRewardRuleByID(ctx context.Context, id string) (*RewardRule, error)
+	ListRewardRules(ctx context.Context, activeOnly bool) ([]*RewardRule, error)
+	// UpdateRewardRule saves the rule as its next version.
+	UpdateRewardRule(ctx context.Context, rule *RewardRule) error
+	CreateReferralReward(ctx context.Context, reward *ReferralReward) error
+	ListReferralRewards(ctx context.Context, referralID string) ([]*ReferralReward, error)
+	// ReleaseReferralReward marks a pending reward released by the journal entry that posted it.
+	ReleaseReferralReward(ctx context.Context, reward *ReferralReward) error
+	// SumRewardPoints returns the points the rule granted the user since the given time, pending rewards
+	// included and expired ones left out.
+	SumRewardPoints(ctx context.Context, ruleID, userID string, since time.Time) (int, error)
+	// ListMilestoneRewards returns the user's pending and released rewards for milestones above the given
+	// referral count, locked for the rest of the surrounding transaction.
+	ListMilestoneRewards(ctx context.Context, userID string, above int) ([]*ReferralReward, error)
+}
@@ -0,0 +1,63 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/campaign"
+	"github.com/dimfeld/httptreemux"
+	log "github.com/sirupsen/logrus"
+)
+
+func SetupCampaignRoutes(router *httptreemux.TreeMux, s *campaign.Service) {
+	router.POST("/admin/campaigns", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		req := &campaign.Request{}
+		err := getRequestBody(r.Body, req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if err = req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"campaign_name": req.Name})
+		c, err := s.Create(context.Background(), req, logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, c)
+	})
+
+	router.GET("/admin/campaigns", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		campaigns, err := s.List(context.Background(), log.WithFields(map[string]interface{}{}))
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, campaigns)
+	})
+
+	router.GET("/admin/campaigns/:id", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if !core.IsUUID(params["id"]) {
+			http.Error(w, "campaign id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"campaign_id": params["id"]})
+		c, err := s.Find(context.Background(), params["id"], logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, c)
+	})
+}
@@ -0,0 +1,90 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/dimfeld/httptreemux"
+	log "github.com/sirupsen/logrus"
+)
+
+func setupClawbackRoutes(router *httptreemux.TreeMux, h *handler.Handler) {
+	router.DELETE("/users/:id", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if !core.IsUUID(params["id"]) {
+			http.Error(w, "user id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"user_id": params["id"]})
+		if err := h.DeleteUser(context.Background(), params["id"], logger); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	router.POST("/admin/referrals/:id/clawback", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		req := &handler.ClawbackRequest{}
+		err := getRequestBody(r.Body, req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		req.ReferralID = params["id"]
+		if !core.IsUUID(req.ReferralID) {
+			http.Error(w, "referral id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		if err = req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"referral_id": req.ReferralID, "reason": req.Reason})
+		clawback, err := h.ClawbackReferral(context.Background(), req, logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, clawback)
+	})
+
+	router.GET("/admin/clawbacks", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		limit, err := getIntParam(r.URL.Query(), "limit")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		clawbacks, err := h.ListClawbacks(context.Background(), limit, log.WithFields(map[string]interface{}{}))
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, clawbacks)
+	})
+
+	router.GET("/admin/referrals/dropped", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		limit, err := getIntParam(r.URL.Query(), "limit")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		dropped, err := h.ListDroppedReferrals(context.Background(), limit, log.WithFields(map[string]interface{}{}))
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, dropped)
+	})
+}
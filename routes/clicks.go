@@ -0,0 +1,85 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/clicks"
+	"github.com/Qalifah/aboki-africa-assessment/config"
+	"github.com/dimfeld/httptreemux"
+	log "github.com/sirupsen/logrus"
+)
+
+// ReferralCookie carries the code of the last referral link the visitor followed, /register falls back to
+// it when the request names no referral code.
+const ReferralCookie = "referral_code"
+
+func SetupReferralLinkRoutes(router *httptreemux.TreeMux, s *clicks.Service, cfg config.ReferralLinkConfig, proxies *Proxies) {
+	router.GET("/r/:code", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		query := r.URL.Query()
+		req := &clicks.Request{
+			Code:        params["code"],
+			IP:          proxies.ClientIP(r),
+			UserAgent:   r.UserAgent(),
+			UTMSource:   query.Get("utm_source"),
+			UTMMedium:   query.Get("utm_medium"),
+			UTMCampaign: query.Get("utm_campaign"),
+			UTMTerm:     query.Get("utm_term"),
+			UTMContent:  query.Get("utm_content"),
+		}
+		if err := req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"code": req.Code})
+		click, err := s.Record(context.Background(), req, logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     ReferralCookie,
+			Value:    click.Code,
+			Path:     "/",
+			Expires:  time.Now().Add(cfg.CookieTTL),
+			MaxAge:   int(cfg.CookieTTL.Seconds()),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, referralRedirectURL(cfg.RedirectURL, click.Code), http.StatusFound)
+	})
+
+	router.GET("/users/:id/referral-funnel", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if !core.IsUUID(params["id"]) {
+			http.Error(w, "user id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"user_id": params["id"]})
+		funnels, err := s.Funnels(context.Background(), params["id"], logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, funnels)
+	})
+}
+
+// referralRedirectURL adds the code to the redirect URL so the landing page can show it.
+func referralRedirectURL(redirectURL, code string) string {
+	target, err := url.Parse(redirectURL)
+	if err != nil {
+		return redirectURL
+	}
+
+	query := target.Query()
+	query.Set("ref", code)
+	target.RawQuery = query.Encode()
+	return target.String()
+}
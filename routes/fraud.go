@@ -0,0 +1,83 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/dimfeld/httptreemux"
+	log "github.com/sirupsen/logrus"
+)
+
+// DeviceFingerprintHeader carries the fingerprint clients compute of the device a user registers from.
+const DeviceFingerprintHeader = "X-Device-Fingerprint"
+
+func setupFraudRoutes(router *httptreemux.TreeMux, h *handler.Handler) {
+	router.GET("/admin/fraud/reviews", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		query := r.URL.Query()
+		status := query.Get("status")
+		if status == "" {
+			status = core.FraudStatusHeld
+		}
+
+		limit, err := getIntParam(query, "limit")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		reviews, err := h.ListFraudReviews(context.Background(), status, limit, log.WithFields(map[string]interface{}{}))
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, reviews)
+	})
+
+	router.GET("/admin/fraud/duplicate-accounts", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		limit, err := getIntParam(r.URL.Query(), "limit")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		duplicates, err := h.ListDuplicateAccounts(context.Background(), limit, log.WithFields(map[string]interface{}{}))
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, duplicates)
+	})
+
+	router.POST("/admin/fraud/reviews/:id/approve", reviewFraud(h.ApproveFraudReview))
+	router.POST("/admin/fraud/reviews/:id/reject", reviewFraud(h.RejectFraudReview))
+}
+
+func reviewFraud(review func(context.Context, *handler.FraudReviewRequest, *log.Entry) (*core.FraudReview, error)) httptreemux.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		req := &handler.FraudReviewRequest{}
+		if err := getRequestBody(r.Body, req); err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		req.ReviewID = params["id"]
+		if !core.IsUUID(req.ReviewID) {
+			http.Error(w, "review id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"fraud_review_id": req.ReviewID})
+		result, err := review(context.Background(), req, logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, result)
+	}
+}
@@ -0,0 +1,140 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/dimfeld/httptreemux"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	maxIdempotencyKeyLen = 255
+)
+
+// Idempotency replays the stored response to requests that reuse an Idempotency-Key
+// instead of processing them again.
+type Idempotency struct {
+	repository core.IdempotencyRepository
+	ttl        time.Duration
+}
+
+func NewIdempotency(repository core.IdempotencyRepository, ttl time.Duration) *Idempotency {
+	return &Idempotency{
+		repository: repository,
+		ttl:        ttl,
+	}
+}
+
+// Wrap makes next idempotent for requests carrying an Idempotency-Key header. Responses with a
+// 5xx status aren't stored so the request can be retried with the same key.
+func (i *Idempotency) Wrap(route string, next httptreemux.HandlerFunc) httptreemux.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next(w, r, params)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLen {
+			http.Error(w, "idempotency key is too long", http.StatusBadRequest)
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "failed to read request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.Sum256(body)
+		stored := &core.IdempotencyKey{
+			Key:         key,
+			Route:       route,
+			RequestHash: hex.EncodeToString(hash[:]),
+			ExpiresAt:   time.Now().Add(i.ttl),
+		}
+
+		logger := log.WithFields(map[string]interface{}{"idempotency_key": key, "route": route})
+		ctx := context.Background()
+		claimed, err := i.repository.ClaimIdempotencyKey(ctx, stored)
+		if err != nil {
+			logger.WithError(err).Error("failed to claim idempotency key")
+			http.Error(w, "something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		if !claimed {
+			switch {
+			case stored.RequestHash != hex.EncodeToString(hash[:]):
+				http.Error(w, "idempotency key was already used for a different request", http.StatusUnprocessableEntity)
+			case stored.StatusCode == 0:
+				http.Error(w, "a request with this idempotency key is still being processed", http.StatusConflict)
+			default:
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(stored.StatusCode)
+				w.Write(stored.ResponseBody)
+			}
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r, params)
+
+		if rec.status >= http.StatusInternalServerError {
+			if err = i.repository.DeleteIdempotencyKey(ctx, key, route); err != nil {
+				logger.WithError(err).Error("failed to release idempotency key")
+			}
+			return
+		}
+
+		stored.StatusCode = rec.status
+		stored.ResponseBody = rec.body.Bytes()
+		if err = i.repository.SaveIdempotentResponse(ctx, stored); err != nil {
+			logger.WithError(err).Error("failed to save idempotent response")
+		}
+	}
+}
+
+// SweepExpired deletes idempotency keys whose TTL has passed.
+func (i *Idempotency) SweepExpired(ctx context.Context) error {
+	n, err := i.repository.DeleteExpiredIdempotencyKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		log.WithField("deleted", n).Info("swept expired idempotency keys")
+	}
+	return nil
+}
+
+// responseRecorder passes the response through while keeping a copy of its status and body.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
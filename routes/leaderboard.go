@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Qalifah/aboki-africa-assessment/leaderboard"
+	"github.com/dimfeld/httptreemux"
+	log "github.com/sirupsen/logrus"
+)
+
+func SetupLeaderboardRoutes(router *httptreemux.TreeMux, s *leaderboard.Service) {
+	router.GET("/leaderboard", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		query := r.URL.Query()
+		limit, err := getIntParam(query, "limit")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		req := &leaderboard.Request{
+			Period: query.Get("period"),
+			Metric: query.Get("by"),
+			Limit:  limit,
+			UserID: query.Get("user_id"),
+		}
+		if err = req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"period": req.Period, "metric": req.Metric})
+		board, err := s.Get(context.Background(), req, logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, board)
+	})
+}
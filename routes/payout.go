@@ -0,0 +1,88 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/Qalifah/aboki-africa-assessment/payout"
+	"github.com/Qalifah/aboki-africa-assessment/paystack"
+	"github.com/dimfeld/httptreemux"
+	log "github.com/sirupsen/logrus"
+)
+
+func SetupPayoutRoutes(router *httptreemux.TreeMux, s *payout.Service) {
+	router.POST("/payouts", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		req := &payout.Request{}
+		err := getRequestBody(r.Body, req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if req.UserID == "" {
+			http.Error(w, "user id is required", http.StatusBadRequest)
+			return
+		}
+
+		if req.Points <= 0 {
+			http.Error(w, "points must be greater than zero", http.StatusBadRequest)
+			return
+		}
+
+		if req.AccountNumber == "" || req.BankCode == "" || req.AccountName == "" {
+			http.Error(w, "account number, bank code and account name are required", http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"user_id": req.UserID})
+		p, err := s.RequestPayout(context.Background(), req, logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, p)
+	})
+
+	router.GET("/payouts/:reference", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		logger := log.WithFields(map[string]interface{}{"reference": params["reference"]})
+		p, err := s.VerifyPayout(context.Background(), params["reference"], logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, p)
+	})
+
+	router.POST("/paystack/webhook", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to read request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{})
+		err = s.HandleWebhook(context.Background(), body, r.Header.Get(paystack.SignatureHeader), logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	buf, err := json.Marshal(data)
+	if err != nil {
+		http.Error(w, "failed to marshal response", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(status)
+	w.Write(buf)
+}
@@ -0,0 +1,99 @@
+package routes
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Proxies tells the IP and country of the client behind the proxies the service trusts. X-Forwarded-For
+// and the country header are only read from requests coming through one of them, anyone else could put
+// anything in them.
+type Proxies struct {
+	trusted       []*net.IPNet
+	countryHeader string
+}
+
+// NewProxies returns the proxies at the given addresses, single IPs or CIDR blocks, telling the client's
+// country in countryHeader. Without a header the client's country is never known.
+func NewProxies(addresses []string, countryHeader string) (*Proxies, error) {
+	p := &Proxies{countryHeader: countryHeader}
+	for _, address := range addresses {
+		if !strings.Contains(address, "/") {
+			ip := net.ParseIP(address)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy address %q", address)
+			}
+			p.trusted = append(p.trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy address %q: %v", address, err)
+		}
+		p.trusted = append(p.trusted, network)
+	}
+	return p, nil
+}
+
+// ClientIP returns the IP of the client. Coming through a trusted proxy, it is the last address in
+// X-Forwarded-For that isn't a trusted proxy itself: the addresses before it were added by whoever
+// the proxy got the request from, so they can't be trusted.
+func (p *Proxies) ClientIP(r *http.Request) string {
+	ip := remoteIP(r)
+	if !p.isTrusted(ip) {
+		return ip
+	}
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			break
+		}
+
+		ip = hop
+		if !p.isTrusted(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// ClientCountry returns the ISO 3166-1 alpha-2 code of the country a trusted proxy located the client in,
+// empty when the request didn't come through one or it couldn't tell.
+func (p *Proxies) ClientCountry(r *http.Request) string {
+	if p.countryHeader == "" || !p.isTrusted(remoteIP(r)) {
+		return ""
+	}
+
+	country := strings.ToUpper(strings.TrimSpace(r.Header.Get(p.countryHeader)))
+	if !countryPattern.MatchString(country) {
+		return ""
+	}
+	return country
+}
+
+func remoteIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+func (p *Proxies) isTrusted(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range p.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
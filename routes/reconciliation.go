@@ -0,0 +1,58 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/reconciliation"
+	"github.com/dimfeld/httptreemux"
+	log "github.com/sirupsen/logrus"
+)
+
+func SetupReconciliationRoutes(router *httptreemux.TreeMux, s *reconciliation.Service) {
+	router.POST("/admin/reconciliations", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		req := &reconciliation.Request{}
+		err := getRequestBody(r.Body, req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"repair": req.Repair})
+		run, err := s.Run(context.Background(), req.Repair, logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, run)
+	})
+
+	router.GET("/admin/reconciliations", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		runs, err := s.ListRuns(context.Background(), log.WithFields(map[string]interface{}{}))
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, runs)
+	})
+
+	router.GET("/admin/reconciliations/:id", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if !core.IsUUID(params["id"]) {
+			http.Error(w, "reconciliation run id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"run_id": params["id"]})
+		run, err := s.FindRun(context.Background(), params["id"], logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, run)
+	})
+}
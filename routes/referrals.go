@@ -0,0 +1,79 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/dimfeld/httptreemux"
+	log "github.com/sirupsen/logrus"
+)
+
+func setupReferralRoutes(router *httptreemux.TreeMux, h *handler.Handler) {
+	router.GET("/users/:id/referrals", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if !core.IsUUID(params["id"]) {
+			http.Error(w, "user id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		limit, err := getIntParam(r.URL.Query(), "limit")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		req := &handler.ListReferralsRequest{
+			UserID: params["id"],
+			Cursor: r.URL.Query().Get("cursor"),
+			Limit:  limit,
+		}
+
+		logger := log.WithFields(map[string]interface{}{"user_id": req.UserID})
+		page, err := h.ListReferrals(context.Background(), req, logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, page)
+	})
+
+	router.GET("/users/:id/referral-tree", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if !core.IsUUID(params["id"]) {
+			http.Error(w, "user id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		depth, err := getIntParam(r.URL.Query(), "depth")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"user_id": params["id"]})
+		tree, err := h.GetReferralTree(context.Background(), params["id"], depth, logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, tree)
+	})
+
+	router.GET("/users/:id/referral-stats", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if !core.IsUUID(params["id"]) {
+			http.Error(w, "user id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"user_id": params["id"]})
+		stats, err := h.GetReferralStats(context.Background(), params["id"], logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, stats)
+	})
+}
@@ -1,19 +1,29 @@
 package routes
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/errors"
 	"github.com/Qalifah/aboki-africa-assessment/handler"
 	"github.com/dimfeld/httptreemux"
 	log "github.com/sirupsen/logrus"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 )
 
-func SetupRoutes(router *httptreemux.TreeMux, h *handler.Handler) {
-	router.POST("/register", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+var countryPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+func SetupRoutes(router *httptreemux.TreeMux, h *handler.Handler, idempotency *Idempotency, proxies *Proxies) {
+	router.POST("/register", idempotency.Wrap("/register", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
 		req := &handler.UserRequest{}
 		err := getRequestBody(r.Body, req)
 		if err != nil {
@@ -31,14 +41,32 @@ func SetupRoutes(router *httptreemux.TreeMux, h *handler.Handler) {
 			return
 		}
 
+		req.Country = strings.ToUpper(req.Country)
+		if req.Country != "" && !countryPattern.MatchString(req.Country) {
+			http.Error(w, "country must be an ISO 3166-1 alpha-2 code", http.StatusBadRequest)
+			return
+		}
+
+		if req.ReferralCode == nil {
+			if cookie, err := r.Cookie(ReferralCookie); err == nil && cookie.Value != "" {
+				req.ReferralCode = &cookie.Value
+				req.ReferralCodeFromLink = true
+			}
+		}
+
+		req.IP = proxies.ClientIP(r)
+		req.ClientCountry = proxies.ClientCountry(r)
+		req.UserAgent = r.UserAgent()
+		req.DeviceID = r.Header.Get(DeviceFingerprintHeader)
+
 		logger := log.WithFields(map[string]interface{}{})
-		user, err := h.RegisterUser(context.Background(), req, logger)
+		registration, err := h.RegisterUser(context.Background(), req, logger)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), errorStatus(err))
 			return
 		}
 
-		buf, err := json.Marshal(user)
+		buf, err := json.Marshal(registration)
 		if err != nil {
 			http.Error(w, "failed to marshal response", http.StatusInternalServerError)
 			return
@@ -46,9 +74,9 @@ func SetupRoutes(router *httptreemux.TreeMux, h *handler.Handler) {
 
 		w.WriteHeader(http.StatusOK)
 		w.Write(buf)
-	})
+	}))
 
-	router.POST("/transaction", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	router.POST("/transaction", idempotency.Wrap("/transaction", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
 		req := &handler.TransferPointsRequest{}
 		err := getRequestBody(r.Body, req)
 		if err != nil {
@@ -66,21 +94,463 @@ func SetupRoutes(router *httptreemux.TreeMux, h *handler.Handler) {
 			return
 		}
 
-		if req.Points == 0 {
-			http.Error(w, "points cannot be zero is required", http.StatusBadRequest)
+		if req.Points <= 0 {
+			http.Error(w, "points must be greater than zero", http.StatusBadRequest)
 			return
 		}
 
 		logger := log.WithFields(map[string]interface{}{})
 		status, err := h.TransferPoints(context.Background(), req, logger)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), errorStatus(err))
 			return
 		}
 
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(status))
+	}))
+
+	router.POST("/bonus/claim", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		req := &handler.ClaimBonusRequest{}
+		err := getRequestBody(r.Body, req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if req.UserID == "" {
+			http.Error(w, "user id is required", http.StatusBadRequest)
+			return
+		}
+
+		if req.Points < 0 {
+			http.Error(w, "points cannot be negative", http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{})
+		tran, err := h.ClaimReferrerBonus(context.Background(), req, logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		buf, err := json.Marshal(tran)
+		if err != nil {
+			http.Error(w, "failed to marshal response", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf)
+	})
+
+	router.POST("/transactions/:id/reverse", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		req := &handler.ReverseTransactionRequest{}
+		err := getRequestBody(r.Body, req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		req.TransactionID = params["id"]
+		if !core.IsUUID(req.TransactionID) {
+			http.Error(w, "transaction id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		if req.Points < 0 {
+			http.Error(w, "points cannot be negative", http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"transaction_id": req.TransactionID})
+		tran, err := h.ReverseTransaction(context.Background(), req, logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, tran)
+	})
+
+	router.GET("/users/:id/balance", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if !core.IsUUID(params["id"]) {
+			http.Error(w, "user id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"user_id": params["id"]})
+		balance, err := h.GetPointsBalance(context.Background(), params["id"], logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, balance)
+	})
+
+	router.PUT("/users/:id/referral-code", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		req := &handler.ReferralCodeRequest{}
+		err := getRequestBody(r.Body, req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		req.UserID = params["id"]
+		if !core.IsUUID(req.UserID) {
+			http.Error(w, "user id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		if err = req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"user_id": req.UserID})
+		code, err := h.SetReferralCode(context.Background(), req, logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, code)
+	})
+
+	router.PUT("/users/:id/referral-code/limits", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		req := &handler.ReferralCodeLimitsRequest{}
+		err := getRequestBody(r.Body, req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		req.UserID = params["id"]
+		if !core.IsUUID(req.UserID) {
+			http.Error(w, "user id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		if err = req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"user_id": req.UserID})
+		code, err := h.SetReferralCodeLimits(context.Background(), req, logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, code)
+	})
+
+	router.GET("/users/:id/referral-codes", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if !core.IsUUID(params["id"]) {
+			http.Error(w, "user id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"user_id": params["id"]})
+		codes, err := h.ListReferralCodes(context.Background(), params["id"], logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, codes)
+	})
+
+	router.POST("/users/:id/referral-code/rotate", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if !core.IsUUID(params["id"]) {
+			http.Error(w, "user id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"user_id": params["id"]})
+		code, err := h.RotateReferralCode(context.Background(), params["id"], logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, code)
+	})
+
+	router.POST("/users/:id/referral-code/revoke", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if !core.IsUUID(params["id"]) {
+			http.Error(w, "user id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"user_id": params["id"]})
+		code, err := h.RevokeReferralCode(context.Background(), params["id"], logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, code)
+	})
+
+	router.POST("/users/:id/verify-email", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if !core.IsUUID(params["id"]) {
+			http.Error(w, "user id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		req := &handler.VerifyEmailRequest{}
+		if err := getRequestBody(r.Body, req); err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		req.UserID = params["id"]
+		if req.Token == "" {
+			http.Error(w, "token is required", http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"user_id": req.UserID})
+		user, err := h.VerifyEmail(context.Background(), req, logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, user)
+	})
+
+	router.GET("/users/:id/transactions", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		req, err := getListTransactionsRequest(params["id"], r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"user_id": req.UserID})
+		page, err := h.ListTransactions(context.Background(), req, logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, page)
+	})
+
+	router.POST("/admin/promos", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		req := &handler.PromoRequest{}
+		err := getRequestBody(r.Body, req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if req.UserID == "" {
+			http.Error(w, "user id is required", http.StatusBadRequest)
+			return
+		}
+
+		if req.Points <= 0 {
+			http.Error(w, "points must be greater than zero", http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"user_id": req.UserID})
+		tran, err := h.GrantPromoPoints(context.Background(), req, logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, tran)
 	})
+
+	router.POST("/holds", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		req := &handler.AuthorizeTransferRequest{}
+		err := getRequestBody(r.Body, req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if req.SenderID == "" {
+			http.Error(w, "sender id is required", http.StatusBadRequest)
+			return
+		}
+
+		if req.RecipientID == "" {
+			http.Error(w, "recipient id is required", http.StatusBadRequest)
+			return
+		}
+
+		if req.Points <= 0 {
+			http.Error(w, "points must be greater than zero", http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"user_id": req.SenderID})
+		hold, err := h.AuthorizeTransfer(context.Background(), req, logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, hold)
+	})
+
+	router.POST("/holds/:id/capture", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		req := &handler.CaptureTransferRequest{}
+		err := getRequestBody(r.Body, req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		req.HoldID = params["id"]
+		if !core.IsUUID(req.HoldID) {
+			http.Error(w, "hold id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		if req.Points < 0 {
+			http.Error(w, "points cannot be negative", http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"hold_id": req.HoldID})
+		tran, err := h.CaptureTransfer(context.Background(), req, logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, tran)
+	})
+
+	router.POST("/holds/:id/void", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if !core.IsUUID(params["id"]) {
+			http.Error(w, "hold id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"hold_id": params["id"]})
+		hold, err := h.VoidTransfer(context.Background(), params["id"], logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, hold)
+	})
+
+	setupScheduledTransferRoutes(router, h)
+	setupReferralRoutes(router, h)
+	setupClawbackRoutes(router, h)
+	setupFraudRoutes(router, h)
+}
+
+// errorStatus maps errors returned for invalid requests or failed upstream calls to their status code.
+func errorStatus(err error) int {
+	switch err {
+	case errors.ErrInsufficientFunds, errors.ErrBelowMinimumClaim, errors.ErrBelowMinimumPayout, errors.ErrNotReversible,
+		errors.ErrReversalExceedsAmount, errors.ErrHoldNotActive, errors.ErrHoldExpired, errors.ErrCaptureExceedsHold,
+		errors.ErrScheduleCompleted, errors.ErrCampaignSponsorNotFound, errors.ErrReferralCodeRevoked,
+		errors.ErrReferralCodeExhausted, errors.ErrReferralCodeExpired, errors.ErrEmailDomainNotAllowed, errors.ErrReferralClawedBack,
+		errors.ErrReferralCodeLimitTooHigh,
+		errors.ErrSelfReferral, errors.ErrReferralCycle, errors.ErrFraudReviewClosed:
+		return http.StatusUnprocessableEntity
+	case errors.ErrInvalidCursor, errors.ErrInvalidSchedule, errors.ErrInvalidAmount:
+		return http.StatusBadRequest
+	case errors.ErrPayoutNotFound, errors.ErrTransactionNotFound, errors.ErrReconciliationRunNotFound,
+		errors.ErrHoldNotFound, errors.ErrScheduledTransferNotFound,
+		errors.ErrRewardRuleNotFound, errors.ErrCampaignNotFound, errors.ErrUserNotFound, errors.ErrReferralCodeNotFound,
+		errors.ErrReferralNotFound, errors.ErrFraudReviewNotFound:
+		return http.StatusNotFound
+	case errors.ErrCampaignCodeTaken, errors.ErrReferralCodeTaken, errors.ErrAlreadyReferred, errors.ErrDuplicateAccount:
+		return http.StatusConflict
+	case errors.ErrReferralCodeChangeLimit:
+		return http.StatusTooManyRequests
+	case errors.ErrInvalidSignature, errors.ErrInvalidVerificationToken:
+		return http.StatusUnauthorized
+	case errors.ErrPayoutFailed:
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func getListTransactionsRequest(userID string, query url.Values) (*handler.ListTransactionsRequest, error) {
+	if !core.IsUUID(userID) {
+		return nil, fmt.Errorf("user id must be a uuid")
+	}
+
+	req := &handler.ListTransactionsRequest{
+		UserID:    userID,
+		Direction: query.Get("direction"),
+		Type:      query.Get("type"),
+		Cursor:    query.Get("cursor"),
+	}
+
+	if req.Direction != "" && req.Direction != core.DirectionSent && req.Direction != core.DirectionReceived {
+		return nil, fmt.Errorf("direction must be %s or %s", core.DirectionSent, core.DirectionReceived)
+	}
+
+	var err error
+	if req.Limit, err = getIntParam(query, "limit"); err != nil {
+		return nil, err
+	}
+	if req.MinPoints, err = getOptionalIntParam(query, "min_points"); err != nil {
+		return nil, err
+	}
+	if req.MaxPoints, err = getOptionalIntParam(query, "max_points"); err != nil {
+		return nil, err
+	}
+	if req.From, err = getTimeParam(query, "from"); err != nil {
+		return nil, err
+	}
+	if req.To, err = getTimeParam(query, "to"); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+func getIntParam(query url.Values, name string) (int, error) {
+	v, err := getOptionalIntParam(query, name)
+	if err != nil || v == nil {
+		return 0, err
+	}
+	return *v, nil
+}
+
+func getOptionalIntParam(query url.Values, name string) (*int, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		return nil, fmt.Errorf("%s must be a non-negative integer", name)
+	}
+	return &v, nil
+}
+
+func getTimeParam(query url.Values, name string) (*time.Time, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+	}
+	return &t, nil
 }
 
 func getRequestBody(respBody io.ReadCloser, data interface{}) error {
@@ -89,9 +559,14 @@ func getRequestBody(respBody io.ReadCloser, data interface{}) error {
 		return err
 	}
 
+	// an empty body leaves every field at its default
+	if len(bytes.TrimSpace(buf)) == 0 {
+		return nil
+	}
+
 	if err = json.Unmarshal(buf, data); err != nil {
 		return err
 	}
 
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,88 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/rules"
+	"github.com/dimfeld/httptreemux"
+	log "github.com/sirupsen/logrus"
+)
+
+func SetupRewardRuleRoutes(router *httptreemux.TreeMux, e *rules.Engine) {
+	router.GET("/admin/reward-rules", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		list, err := e.ListRules(context.Background(), log.WithFields(map[string]interface{}{}))
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, list)
+	})
+
+	router.POST("/admin/reward-rules", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		req := &core.RewardRule{Active: true, Level: 1}
+		err := getRequestBody(r.Body, req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if err = req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// campaign rules are created along with their campaign
+		req.CampaignID = ""
+		logger := log.WithFields(map[string]interface{}{"rule_name": req.Name})
+		rule, err := e.CreateRule(context.Background(), req, logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, rule)
+	})
+
+	router.PUT("/admin/reward-rules/:id", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		req := &core.RewardRule{Level: 1}
+		err := getRequestBody(r.Body, req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		req.ID = params["id"]
+		if !core.IsUUID(req.ID) {
+			http.Error(w, "reward rule id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		if err = req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"rule_id": req.ID})
+		rule, err := e.UpdateRule(context.Background(), req, logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, rule)
+	})
+
+	router.POST("/admin/reward-rules/reload", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if err := e.Reload(context.Background()); err != nil {
+			log.WithError(err).Error("failed to reload reward rules")
+			http.Error(w, "failed to reload reward rules", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, e.Rules())
+	})
+}
@@ -0,0 +1,145 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/dimfeld/httptreemux"
+	log "github.com/sirupsen/logrus"
+)
+
+func setupScheduledTransferRoutes(router *httptreemux.TreeMux, h *handler.Handler) {
+	router.POST("/scheduled-transfers", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		req := &handler.ScheduledTransferRequest{}
+		err := getRequestBody(r.Body, req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if !core.IsUUID(req.SenderID) || !core.IsUUID(req.RecipientID) {
+			http.Error(w, "sender id and recipient id must be uuids", http.StatusBadRequest)
+			return
+		}
+
+		if req.SenderID == req.RecipientID {
+			http.Error(w, "sender and recipient must be different users", http.StatusBadRequest)
+			return
+		}
+
+		if req.Points <= 0 {
+			http.Error(w, "points must be greater than zero", http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"user_id": req.SenderID})
+		schedule, err := h.CreateScheduledTransfer(context.Background(), req, logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, schedule)
+	})
+
+	router.GET("/scheduled-transfers", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		senderID := r.URL.Query().Get("sender_id")
+		if !core.IsUUID(senderID) {
+			http.Error(w, "sender_id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"user_id": senderID})
+		schedules, err := h.ListScheduledTransfers(context.Background(), senderID, logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, schedules)
+	})
+
+	router.GET("/scheduled-transfers/:id", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if !core.IsUUID(params["id"]) {
+			http.Error(w, "scheduled transfer id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"scheduled_transfer_id": params["id"]})
+		schedule, err := h.GetScheduledTransfer(context.Background(), params["id"], logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, schedule)
+	})
+
+	router.GET("/scheduled-transfers/:id/executions", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if !core.IsUUID(params["id"]) {
+			http.Error(w, "scheduled transfer id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"scheduled_transfer_id": params["id"]})
+		executions, err := h.ListScheduledTransferExecutions(context.Background(), params["id"], logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, executions)
+	})
+
+	router.PATCH("/scheduled-transfers/:id", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		req := &handler.UpdateScheduledTransferRequest{}
+		err := getRequestBody(r.Body, req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		req.ID = params["id"]
+		if !core.IsUUID(req.ID) {
+			http.Error(w, "scheduled transfer id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		if req.Points != nil && *req.Points <= 0 {
+			http.Error(w, "points must be greater than zero", http.StatusBadRequest)
+			return
+		}
+
+		if req.Status != nil && *req.Status != core.ScheduleStatusActive && *req.Status != core.ScheduleStatusPaused {
+			http.Error(w, "status must be ACTIVE or PAUSED", http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"scheduled_transfer_id": req.ID})
+		schedule, err := h.UpdateScheduledTransfer(context.Background(), req, logger)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, schedule)
+	})
+
+	router.DELETE("/scheduled-transfers/:id", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if !core.IsUUID(params["id"]) {
+			http.Error(w, "scheduled transfer id must be a uuid", http.StatusBadRequest)
+			return
+		}
+
+		logger := log.WithFields(map[string]interface{}{"scheduled_transfer_id": params["id"]})
+		if err := h.DeleteScheduledTransfer(context.Background(), params["id"], logger); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
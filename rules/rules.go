@@ -0,0 +1,150 @@
+// Package rules evaluates the referral reward rules stored in Postgres. Active rules are kept in
+// memory and reloaded whenever they change through the engine or on Reload.
+package rules
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/errors"
+
+	"github.com/jackc/pgx/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+type Engine struct {
+	repository core.RewardRuleRepository
+
+	mu    sync.RWMutex
+	rules []*core.RewardRule
+}
+
+func New(repository core.RewardRuleRepository) *Engine {
+	return &Engine{
+		repository: repository,
+	}
+}
+
+// Reload replaces the rules in memory with the active rules in the database.
+func (e *Engine) Reload(ctx context.Context) error {
+	rules, err := e.repository.ListRewardRules(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.rules = rules
+	e.mu.Unlock()
+	return nil
+}
+
+// Rules returns the rules currently loaded.
+func (e *Engine) Rules() []*core.RewardRule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.rules
+}
+
+// Evaluate returns the rewards the loaded rules for the given level grant the user for the referral.
+// For direct referrers count is how many users they referred including this one. Every matching rule
+// grants its reward, cut down to what is left of its cap for the period.
+func (e *Engine) Evaluate(ctx context.Context, referral *core.Referral, userID string, level, count int, now time.Time) ([]*core.ReferralReward, error) {
+	rewards := []*core.ReferralReward{}
+	for _, rule := range e.Rules() {
+		if rule.Level != level || rule.CampaignID != "" {
+			continue
+		}
+
+		points := rule.Reward(count)
+		if points <= 0 {
+			continue
+		}
+
+		if rule.CapPoints > 0 {
+			granted, err := e.repository.SumRewardPoints(ctx, rule.ID, userID, rule.PeriodStart(now))
+			if err != nil {
+				return nil, err
+			}
+			if left := rule.CapPoints - granted; left < points {
+				points = left
+			}
+			if points <= 0 {
+				continue
+			}
+		}
+
+		reward := &core.ReferralReward{
+			ReferralID:  referral.ID,
+			UserID:      userID,
+			RuleID:      rule.ID,
+			RuleVersion: rule.Version,
+			Level:       level,
+			Points:      points,
+		}
+		if rule.Kind == core.RuleKindMilestone {
+			reward.Milestone = count
+		}
+		rewards = append(rewards, reward)
+	}
+
+	return rewards, nil
+}
+
+// CampaignRule returns the loaded rule of the campaign, nil when the campaign has no active rule.
+func (e *Engine) CampaignRule(campaignID string) *core.RewardRule {
+	for _, rule := range e.Rules() {
+		if rule.CampaignID == campaignID {
+			return rule
+		}
+	}
+	return nil
+}
+
+func (e *Engine) ListRules(ctx context.Context, logger *log.Entry) ([]*core.RewardRule, error) {
+	rules, err := e.repository.ListRewardRules(ctx, false)
+	if err != nil {
+		logger.WithError(err).Error("failed to list reward rules")
+		return nil, errors.ErrGeneric
+	}
+	return rules, nil
+}
+
+func (e *Engine) CreateRule(ctx context.Context, rule *core.RewardRule, logger *log.Entry) (*core.RewardRule, error) {
+	if err := e.repository.CreateRewardRule(ctx, rule); err != nil {
+		logger.WithError(err).Error("failed to create reward rule")
+		return nil, errors.ErrGeneric
+	}
+
+	return rule, e.reload(ctx, logger)
+}
+
+// UpdateRule replaces the definition of a rule, saving it as a new version.
+func (e *Engine) UpdateRule(ctx context.Context, rule *core.RewardRule, logger *log.Entry) (*core.RewardRule, error) {
+	existing, err := e.repository.FindRewardRuleByID(ctx, rule.ID)
+	if err == pgx.ErrNoRows {
+		return nil, errors.ErrRewardRuleNotFound
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to find reward rule")
+		return nil, errors.ErrGeneric
+	}
+
+	rule.CreatedAt = existing.CreatedAt
+	rule.CampaignID = existing.CampaignID
+	if err = e.repository.UpdateRewardRule(ctx, rule); err != nil {
+		logger.WithError(err).Error("failed to update reward rule")
+		return nil, errors.ErrGeneric
+	}
+
+	return rule, e.reload(ctx, logger)
+}
+
+func (e *Engine) reload(ctx context.Context, logger *log.Entry) error {
+	if err := e.Reload(ctx); err != nil {
+		logger.WithError(err).Error("failed to reload reward rules")
+		return errors.ErrGeneric
+	}
+	return nil
+}
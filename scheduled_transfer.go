@@ -0,0 +1,62 @@
+package aboki_africa_assessment
+
+import (
+	"context"
+	"time"
+)
+
+// Scheduled transfer states. ACTIVE schedules run at NextRunAt, PAUSED ones wait to be resumed
+// and COMPLETED one-off schedules have already run.
+const (
+	ScheduleStatusActive    = "ACTIVE"
+	ScheduleStatusPaused    = "PAUSED"
+	ScheduleStatusCompleted = "COMPLETED"
+)
+
+const (
+	ExecutionStatusSuccess = "SUCCESS"
+	ExecutionStatusFailed  = "FAILED"
+)
+
+// ScheduledTransfer sends points from the sender to the recipient once at NextRunAt, or repeatedly
+// following Rule when it is set.
+type ScheduledTransfer struct {
+	ID                  string     `json:"id"`
+	SenderID            string     `json:"sender_id"`
+	RecipientID         string     `json:"recipient_id"`
+	Points              int        `json:"points"`
+	Rule                string     `json:"rule,omitempty"`
+	Status              string     `json:"status"`
+	NextRunAt           *time.Time `json:"next_run_at"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+func (s *ScheduledTransfer) IsRecurring() bool {
+	return s.Rule != ""
+}
+
+// ScheduledTransferExecution is the outcome of one run of a scheduled transfer.
+type ScheduledTransferExecution struct {
+	ID                  string    `json:"id"`
+	ScheduledTransferID string    `json:"scheduled_transfer_id"`
+	TransactionID       string    `json:"transaction_id,omitempty"`
+	Status              string    `json:"status"`
+	Error               string    `json:"error,omitempty"`
+	ScheduledFor        time.Time `json:"scheduled_for"`
+	ExecutedAt          time.Time `json:"executed_at"`
+}
+
+type ScheduledTransferRepository interface {
+	CreateScheduledTransfer(ctx context.Context, schedule *ScheduledTransfer) error
+	// FindScheduledTransferByID locks the schedule for the rest of the surrounding transaction.
+	FindScheduledTransferByID(ctx context.Context, id string) (*ScheduledTransfer, error)
+	ListScheduledTransfers(ctx context.Context, senderID string) ([]*ScheduledTransfer, error)
+	// ListDueScheduledTransfers returns the IDs of active schedules due to run before the given time.
+	ListDueScheduledTransfers(ctx context.Context, before time.Time, limit int) ([]string, error)
+	UpdateScheduledTransfer(ctx context.Context, schedule *ScheduledTransfer) error
+	DeleteScheduledTransfer(ctx context.Context, id string) error
+	CreateExecution(ctx context.Context, execution *ScheduledTransferExecution) error
+	ListExecutions(ctx context.Context, scheduleID string, limit int) ([]*ScheduledTransferExecution, error)
+}
@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClaimReferrerBonus(t *testing.T) {
+	user, err := seedOneUser("Claimer", uniqueEmail("claimer"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	err = testHandler.userPointRepository.CreatePoint(context.Background(), &core.Point{UserID: user.ID, Bonus: 120})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tests := []struct {
+		points     int
+		wantCode   int
+		wantPoints int
+		wantBonus  int
+		wantPaid   bool
+	}{
+		// below the configured minimum
+		{points: 30, wantCode: http.StatusUnprocessableEntity, wantPoints: 0, wantBonus: 120},
+		// more than the outstanding bonus
+		{points: 500, wantCode: http.StatusUnprocessableEntity, wantPoints: 0, wantBonus: 120},
+		// partial claim
+		{points: 70, wantCode: http.StatusOK, wantPoints: 70, wantBonus: 50},
+		// claim everything that's left
+		{points: 0, wantCode: http.StatusOK, wantPoints: 120, wantBonus: 0, wantPaid: true},
+	}
+
+	for _, test := range tests {
+		resp, err := claimBonus(&handler.ClaimBonusRequest{UserID: user.ID, Points: test.points})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, test.wantCode, resp.StatusCode)
+
+		if test.wantCode == http.StatusOK {
+			body := &core.Transaction{}
+			err = getResponseBody(resp.Body, body)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.NotEmpty(t, body.ID)
+			assert.Equal(t, "BONUS", body.Type)
+		}
+
+		point, err := testHandler.userPointRepository.FindPointByUserID(context.Background(), user.ID)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, test.wantPoints, point.Points)
+		assert.Equal(t, test.wantBonus, point.Bonus)
+		assert.Equal(t, test.wantPaid, point.Paid)
+	}
+}
+
+func claimBonus(req *handler.ClaimBonusRequest) (*http.Response, error) {
+	return http.Post(url+"/bonus/claim", "application/json", serialize(req))
+}
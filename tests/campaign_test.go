@@ -0,0 +1,215 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/campaign"
+	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSponsoredCampaign(t *testing.T) {
+	sponsor, _, ok := registerReferrer(t)
+	if !ok {
+		return
+	}
+
+	code := campaignCode("EASTER")
+	c := createCampaign(t, &campaign.Request{
+		Name:          "Easter",
+		StartsAt:      time.Now().Add(-time.Hour),
+		EndsAt:        time.Now().Add(time.Hour),
+		BudgetPoints:  100,
+		SponsorUserID: sponsor.ID,
+		Codes:         []string{code},
+		Rule:          &core.RewardRule{Kind: core.RuleKindFlat, Points: 40},
+	}, http.StatusOK)
+	if c == nil {
+		return
+	}
+
+	// the code is used by the campaign already
+	createCampaign(t, &campaign.Request{
+		Name:         "Easter again",
+		StartsAt:     time.Now(),
+		EndsAt:       time.Now().Add(time.Hour),
+		BudgetPoints: 100,
+		Codes:        []string{strings.ToLower(code)},
+		Rule:         &core.RewardRule{Kind: core.RuleKindFlat, Points: 40},
+	}, http.StatusConflict)
+
+	// 40 + 40, the third registration gets the 20 left of the budget and the fourth nothing
+	for i := 0; i < 4; i++ {
+		if !registerReferee(t, strings.ToLower(code)) {
+			return
+		}
+	}
+
+	point, err := testHandler.userPointRepository.FindPointByUserID(context.Background(), sponsor.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 4, point.NumberOfReferredUsers)
+		assert.Equal(t, 100, point.Bonus)
+	}
+
+	c = getCampaign(t, c.ID)
+	if assert.NotNil(t, c) {
+		assert.Equal(t, 100, c.SpentPoints)
+		assert.Equal(t, 4, c.Registrations)
+		if assert.Len(t, c.Redemptions, 4) {
+			for i, want := range []int{40, 40, 20, 0} {
+				assert.Equal(t, want, c.Redemptions[i].Points)
+				assert.Equal(t, sponsor.ID, c.Redemptions[i].BeneficiaryID)
+				assert.NotEmpty(t, c.Redemptions[i].ReferralID)
+			}
+		}
+	}
+}
+
+func TestSponsoredCampaignQualification(t *testing.T) {
+	sponsor, _, ok := registerReferrer(t)
+	if !ok {
+		return
+	}
+
+	code := campaignCode("HARVEST")
+	c := createCampaign(t, &campaign.Request{
+		Name:          "Harvest",
+		StartsAt:      time.Now().Add(-time.Hour),
+		EndsAt:        time.Now().Add(time.Hour),
+		BudgetPoints:  100,
+		SponsorUserID: sponsor.ID,
+		Codes:         []string{code},
+		Rule:          &core.RewardRule{Kind: core.RuleKindFlat, Points: 40},
+	}, http.StatusOK)
+	if c == nil {
+		return
+	}
+
+	// the sponsor's reward waits on the referee's qualifying action like any other referral's
+	defer func(action string) { testHandler.config.Referral.QualifyingAction = action }(testHandler.config.Referral.QualifyingAction)
+	testHandler.config.Referral.QualifyingAction = core.QualifyEmailVerified
+	referee, _, ok := registerReferredUser(t, &code)
+	if !ok {
+		return
+	}
+	assertReferralStatus(t, referee.ID, core.ReferralStatusPending)
+	assertBonus(t, sponsor.ID, 0)
+
+	token := testHandler.handler.EmailVerificationToken(referee, time.Now().Add(time.Hour))
+	resp, err := http.Post(url+"/users/"+referee.ID+"/verify-email", "application/json",
+		serialize(&handler.VerifyEmailRequest{Token: token}))
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+	assertReferralStatus(t, referee.ID, core.ReferralStatusQualified)
+	assertBonus(t, sponsor.ID, 40)
+
+	resp, err = http.Get(url + "/users/" + sponsor.ID + "/referral-stats")
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+	stats := &core.ReferralStats{}
+	if assert.NoError(t, getResponseBody(resp.Body, stats)) {
+		assert.Equal(t, 40, stats.PointsEarned)
+	}
+
+	// and is taken back with the referral
+	clawback := clawbackReferral(t, findReferralID(t, referee.ID), &handler.ClawbackRequest{Reason: core.ClawbackReasonFraud}, http.StatusOK)
+	if assert.NotNil(t, clawback) {
+		assert.Equal(t, 40, clawback.Points)
+		assert.Equal(t, 0, clawback.Debt)
+	}
+	assertReferralCount(t, sponsor.ID, 0)
+	assertBonus(t, sponsor.ID, 0)
+}
+
+func TestSystemCampaign(t *testing.T) {
+	live := campaignCode("WELCOME")
+	expired := campaignCode("OLD")
+	rule := &core.RewardRule{Kind: core.RuleKindFlat, Points: 15}
+	if createCampaign(t, &campaign.Request{
+		Name:         "Welcome",
+		StartsAt:     time.Now().Add(-time.Hour),
+		EndsAt:       time.Now().Add(time.Hour),
+		BudgetPoints: 1000,
+		Codes:        []string{live},
+		Rule:         rule,
+	}, http.StatusOK) == nil {
+		return
+	}
+	if createCampaign(t, &campaign.Request{
+		Name:         "Old",
+		StartsAt:     time.Now().Add(-2 * time.Hour),
+		EndsAt:       time.Now().Add(-time.Hour),
+		BudgetPoints: 1000,
+		Codes:        []string{expired},
+		Rule:         &core.RewardRule{Kind: core.RuleKindFlat, Points: 15},
+	}, http.StatusOK) == nil {
+		return
+	}
+
+	// campaigns without a sponsor reward the new user, as long as they run
+	for code, want := range map[string]int{live: 15, expired: 0} {
+		code := code
+		user, _, ok := registerReferredUser(t, &code)
+		if !ok {
+			return
+		}
+
+		point, err := testHandler.userPointRepository.FindPointByUserID(context.Background(), user.ID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, want, point.Bonus, code)
+			assert.Equal(t, 0, point.NumberOfReferredUsers)
+		}
+	}
+}
+
+func TestCreateCampaignValidation(t *testing.T) {
+	for _, req := range []*campaign.Request{
+		{Name: "No codes", StartsAt: time.Now(), EndsAt: time.Now().Add(time.Hour), BudgetPoints: 10,
+			Rule: &core.RewardRule{Kind: core.RuleKindFlat, Points: 5}},
+		{Name: "Backwards", StartsAt: time.Now(), EndsAt: time.Now().Add(-time.Hour), BudgetPoints: 10, Codes: []string{campaignCode("BACK")},
+			Rule: &core.RewardRule{Kind: core.RuleKindFlat, Points: 5}},
+		{Name: "Capped", StartsAt: time.Now(), EndsAt: time.Now().Add(time.Hour), BudgetPoints: 10, Codes: []string{campaignCode("CAP")},
+			Rule: &core.RewardRule{Kind: core.RuleKindFlat, Points: 5, CapPoints: 5}},
+	} {
+		createCampaign(t, req, http.StatusBadRequest)
+	}
+}
+
+// campaignCode returns a campaign code unique to the test run.
+func campaignCode(prefix string) string {
+	return fmt.Sprintf("%s%d", prefix, time.Now().UnixNano()%1e8)
+}
+
+func createCampaign(t *testing.T, req *campaign.Request, wantCode int) *core.Campaign {
+	resp, err := http.Post(url+"/admin/campaigns", "application/json", serialize(req))
+	if !assert.NoError(t, err) || !assert.Equal(t, wantCode, resp.StatusCode) || wantCode != http.StatusOK {
+		return nil
+	}
+
+	c := &core.Campaign{}
+	if !assert.NoError(t, getResponseBody(resp.Body, c)) {
+		return nil
+	}
+	return c
+}
+
+func getCampaign(t *testing.T, id string) *core.Campaign {
+	resp, err := http.Get(url + "/admin/campaigns/" + id)
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return nil
+	}
+
+	c := &core.Campaign{}
+	if !assert.NoError(t, getResponseBody(resp.Body, c)) {
+		return nil
+	}
+	return c
+}
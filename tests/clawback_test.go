@@ -0,0 +1,288 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReferralClawback(t *testing.T) {
+	rule := saveRewardRule(t, http.MethodPost, url+"/admin/reward-rules", &core.RewardRule{
+		Name: "Clawback test rule", Kind: core.RuleKindFlat, Active: true, Points: 10,
+	})
+	if rule == nil {
+		return
+	}
+	defer func() {
+		rule.Active = false
+		saveRewardRule(t, http.MethodPut, url+"/admin/reward-rules/"+rule.ID, rule)
+	}()
+
+	referrer, code, ok := registerReferrer(t)
+	if !ok {
+		return
+	}
+
+	referees := make([]*core.User, 3)
+	for i := range referees {
+		if referees[i], _, ok = registerReferredUser(t, &code); !ok {
+			return
+		}
+	}
+
+	// the third referral also earns the 50 of the default every third referral rule
+	assertBonus(t, referrer.ID, 80)
+
+	resp, err := claimBonus(&handler.ClaimBonusRequest{UserID: referrer.ID})
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+	resp, err = transaction(&handler.TransferPointsRequest{SenderID: referrer.ID, RecipientID: referees[0].ID, Points: 60})
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+
+	referralID := findReferralID(t, referees[0].ID)
+	clawback := clawbackReferral(t, referralID, &handler.ClawbackRequest{Reason: core.ClawbackReasonFraud, Note: "fake account"}, http.StatusOK)
+	if clawback == nil {
+		return
+	}
+
+	// the referral's own reward and the milestone two referrals no longer reach, 20 of which were left to take
+	assert.Equal(t, core.ClawbackReasonFraud, clawback.Reason)
+	assert.Equal(t, 60, clawback.Points)
+	assert.Equal(t, 40, clawback.Debt)
+	assert.Len(t, clawback.Rewards, 2)
+	assertReferralStatus(t, referees[0].ID, core.ReferralStatusClawedBack)
+	assertReferralCount(t, referrer.ID, 2)
+	assertDebt(t, referrer.ID, -40, 40)
+
+	clawbackReferral(t, referralID, &handler.ClawbackRequest{Reason: core.ClawbackReasonFraud}, http.StatusUnprocessableEntity)
+	clawbackReferral(t, referralID, &handler.ClawbackRequest{Reason: core.ClawbackReasonOther}, http.StatusBadRequest)
+
+	// debt is paid out of the points earned next
+	entry := core.NewJournalEntry(core.EntryTypeOpening, "", core.SystemOpeningBalanceAccount, core.UserPointsAccount(referrer.ID), 50)
+	if !assert.NoError(t, testHandler.ledgerRepository.PostEntry(context.Background(), entry)) ||
+		!assert.NoError(t, testHandler.handler.SettleDebts(context.Background())) {
+		return
+	}
+	assertDebt(t, referrer.ID, 10, 0)
+
+	// a referee deleting their account takes their referral with them
+	req, err := http.NewRequest(http.MethodDelete, url+"/users/"+referees[1].ID, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	resp, err = http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusNoContent, resp.StatusCode) {
+		return
+	}
+	assertReferralStatus(t, referees[1].ID, core.ReferralStatusClawedBack)
+	assertReferralCount(t, referrer.ID, 1)
+	assertDebt(t, referrer.ID, 0, 0)
+
+	token := testHandler.handler.EmailVerificationToken(referees[1], time.Now().Add(time.Hour))
+	resp, err = http.Post(url+"/users/"+referees[1].ID+"/verify-email", "application/json",
+		serialize(&handler.VerifyEmailRequest{Token: token}))
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	}
+
+	var reason string
+	err = testHandler.client.QueryRow(context.Background(), `SELECT clawbacks.reason FROM clawbacks
+	INNER JOIN referrals ON referrals.id = clawbacks.referral_id WHERE referrals.referee_id = $1`, referees[1].ID).Scan(&reason)
+	if assert.NoError(t, err) {
+		assert.Equal(t, core.ClawbackReasonAccountDeleted, reason)
+	}
+}
+
+func TestDroppedReferralClawback(t *testing.T) {
+	rule := saveRewardRule(t, http.MethodPost, url+"/admin/reward-rules", &core.RewardRule{
+		Name: "Dropped referral test rule", Kind: core.RuleKindFlat, Active: true, Points: 10,
+	})
+	if rule == nil {
+		return
+	}
+	defer func() {
+		rule.Active = false
+		saveRewardRule(t, http.MethodPut, url+"/admin/reward-rules/"+rule.ID, rule)
+	}()
+
+	referrer, code, ok := registerReferrer(t)
+	if !ok {
+		return
+	}
+	referee, _, ok := registerReferredUser(t, &code)
+	if !ok {
+		return
+	}
+	assertBonus(t, referrer.ID, 10)
+
+	// dropped the way the referral integrity migration dropped duplicates, rewards left in place
+	_, err := testHandler.client.Exec(context.Background(),
+		"UPDATE referrals SET status = $1, deleted_at = CURRENT_TIMESTAMP WHERE referee_id = $2", core.ReferralStatusDropped, referee.ID)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = testHandler.client.Exec(context.Background(),
+		"UPDATE user_points SET number_of_referred_users = number_of_referred_users - 1 WHERE user_id = $1", referrer.ID)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	referralID := findReferralID(t, referee.ID)
+	if !assert.Equal(t, 10, droppedReferralPoints(t, referralID)) {
+		return
+	}
+
+	clawback := clawbackReferral(t, referralID, &handler.ClawbackRequest{Reason: core.ClawbackReasonFraud}, http.StatusOK)
+	if assert.NotNil(t, clawback) {
+		assert.Equal(t, 10, clawback.Points)
+	}
+	assertReferralCount(t, referrer.ID, 0)
+	assertBonus(t, referrer.ID, 0)
+	assert.Equal(t, 0, droppedReferralPoints(t, referralID))
+}
+
+func TestClawbackMilestoneAfterRuleChange(t *testing.T) {
+	rule := saveRewardRule(t, http.MethodPost, url+"/admin/reward-rules", &core.RewardRule{
+		Name: "Second referral test rule", Kind: core.RuleKindMilestone, Active: true, Points: 30, Milestones: []int{2},
+	})
+	if rule == nil {
+		return
+	}
+	defer func() {
+		rule.Active = false
+		saveRewardRule(t, http.MethodPut, url+"/admin/reward-rules/"+rule.ID, rule)
+	}()
+
+	referrer, code, ok := registerReferrer(t)
+	if !ok {
+		return
+	}
+	referees := make([]*core.User, 2)
+	for i := range referees {
+		if referees[i], _, ok = registerReferredUser(t, &code); !ok {
+			return
+		}
+	}
+	assertBonus(t, referrer.ID, 30)
+
+	// the reward was granted for the second referral, whatever the rule pays for now
+	rule.Milestones = []int{1}
+	if saveRewardRule(t, http.MethodPut, url+"/admin/reward-rules/"+rule.ID, rule) == nil {
+		return
+	}
+
+	clawback := clawbackReferral(t, findReferralID(t, referees[0].ID), &handler.ClawbackRequest{Reason: core.ClawbackReasonFraud}, http.StatusOK)
+	if assert.NotNil(t, clawback) && assert.Len(t, clawback.Rewards, 1) {
+		assert.Equal(t, 30, clawback.Points)
+		assert.Equal(t, 2, clawback.Rewards[0].Milestone)
+	}
+	assertBonus(t, referrer.ID, 0)
+}
+
+func TestClawbackUpdatesBonus(t *testing.T) {
+	rule := saveRewardRule(t, http.MethodPost, url+"/admin/reward-rules", &core.RewardRule{
+		Name: "Clawback bonus test rule", Kind: core.RuleKindFlat, Active: true, Points: 10,
+	})
+	if rule == nil {
+		return
+	}
+	defer func() {
+		rule.Active = false
+		saveRewardRule(t, http.MethodPut, url+"/admin/reward-rules/"+rule.ID, rule)
+	}()
+
+	referrer, code, ok := registerReferrer(t)
+	if !ok {
+		return
+	}
+	referees := make([]*core.User, 2)
+	for i := range referees {
+		if referees[i], _, ok = registerReferredUser(t, &code); !ok {
+			return
+		}
+	}
+
+	for i, want := range []struct {
+		bonus int
+		paid  bool
+	}{{bonus: 10}, {bonus: 0, paid: true}} {
+		if clawbackReferral(t, findReferralID(t, referees[i].ID), &handler.ClawbackRequest{Reason: core.ClawbackReasonFraud}, http.StatusOK) == nil {
+			return
+		}
+
+		point, err := testHandler.userPointRepository.FindPointByUserID(context.Background(), referrer.ID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, want.bonus, point.Bonus)
+			assert.Equal(t, want.paid, point.Paid)
+		}
+	}
+}
+
+// droppedReferralPoints returns the released points the dropped referral is reported with, zero when it isn't.
+func droppedReferralPoints(t *testing.T, referralID string) int {
+	resp, err := http.Get(url + "/admin/referrals/dropped?limit=100")
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return 0
+	}
+
+	dropped := []*core.DroppedReferral{}
+	if !assert.NoError(t, getResponseBody(resp.Body, &dropped)) {
+		return 0
+	}
+	for _, referral := range dropped {
+		if referral.ID == referralID {
+			return referral.ReleasedPoints
+		}
+	}
+	return 0
+}
+
+func findReferralID(t *testing.T, refereeID string) string {
+	var referralID string
+	err := testHandler.client.QueryRow(context.Background(), "SELECT id FROM referrals WHERE referee_id = $1", refereeID).Scan(&referralID)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return referralID
+}
+
+func clawbackReferral(t *testing.T, referralID string, req *handler.ClawbackRequest, wantStatus int) *core.Clawback {
+	resp, err := http.DefaultClient.Do(newJSONRequest(http.MethodPost, url+"/admin/referrals/"+referralID+"/clawback", req))
+	if !assert.NoError(t, err) || !assert.Equal(t, wantStatus, resp.StatusCode) || wantStatus != http.StatusOK {
+		return nil
+	}
+
+	clawback := &core.Clawback{}
+	if !assert.NoError(t, getResponseBody(resp.Body, clawback)) {
+		return nil
+	}
+	return clawback
+}
+
+func assertReferralCount(t *testing.T, userID string, want int) {
+	point, err := testHandler.userPointRepository.FindPointByUserID(context.Background(), userID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, want, point.NumberOfReferredUsers)
+	}
+}
+
+func assertDebt(t *testing.T, userID string, wantAvailable, wantDebt int) {
+	resp, err := http.Get(url + "/users/" + userID + "/balance")
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+
+	balance := &handler.PointsBalance{}
+	if assert.NoError(t, getResponseBody(resp.Body, balance)) {
+		assert.Equal(t, wantAvailable, balance.Available)
+		assert.Equal(t, wantDebt, balance.Debt)
+	}
+}
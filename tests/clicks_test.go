@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/Qalifah/aboki-africa-assessment/routes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReferralLinkFunnel(t *testing.T) {
+	referrer, code, ok := registerReferrer(t)
+	if !ok {
+		return
+	}
+
+	resp := followReferralLink(t, code+"?utm_source=newsletter&utm_campaign=launch", "203.0.113.1")
+	if !assert.Equal(t, http.StatusFound, resp.StatusCode) {
+		return
+	}
+	assert.Contains(t, resp.Header.Get("Location"), "ref="+code)
+
+	var cookie *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == routes.ReferralCookie {
+			cookie = c
+		}
+	}
+	if !assert.NotNil(t, cookie) {
+		return
+	}
+	assert.Equal(t, code, cookie.Value)
+
+	// a returning visitor and a new one
+	followReferralLink(t, code, "203.0.113.1")
+	followReferralLink(t, code, "203.0.113.2")
+
+	var utmSource, ipHash string
+	err := testHandler.client.QueryRow(context.Background(), `SELECT utm_source, ip_hash FROM referral_clicks
+	INNER JOIN referral_codes ON referral_codes.id = referral_clicks.referral_code_id
+	WHERE referral_codes.code = $1 ORDER BY referral_clicks.created_at LIMIT 1`, code).Scan(&utmSource, &ipHash)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "newsletter", utmSource)
+		assert.NotContains(t, ipHash, "203.0.113.1")
+	}
+
+	// the cookie attributes a registration that names no code
+	referee := registerWithCookie(t, &http.Cookie{Name: routes.ReferralCookie, Value: cookie.Value})
+	if referee == nil {
+		return
+	}
+	var referrerID string
+	err = testHandler.client.QueryRow(context.Background(), "SELECT referrer_id FROM referrals WHERE referee_id = $1", referee.ID).Scan(&referrerID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, referrer.ID, referrerID)
+	}
+
+	// a stale cookie doesn't get in the way of registering
+	if user := registerWithCookie(t, &http.Cookie{Name: routes.ReferralCookie, Value: "NOPE-404"}); user != nil {
+		var referrals int
+		err = testHandler.client.QueryRow(context.Background(), "SELECT COUNT(*) FROM referrals WHERE referee_id = $1", user.ID).Scan(&referrals)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 0, referrals)
+		}
+	}
+
+	resp = followReferralLink(t, "NOPE-404", "203.0.113.1")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.Get(url + "/users/" + referrer.ID + "/referral-funnel")
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+	funnels := []*core.ReferralFunnel{}
+	if assert.NoError(t, getResponseBody(resp.Body, &funnels)) && assert.Len(t, funnels, 1) {
+		funnel := funnels[0]
+		assert.Equal(t, code, funnel.Code)
+		assert.Equal(t, 3, funnel.Clicks)
+		assert.Equal(t, 2, funnel.UniqueVisitors)
+		assert.Equal(t, 1, funnel.Registrations)
+		assert.Equal(t, 1, funnel.Qualified)
+		assert.Equal(t, 0.5, funnel.ClickConversion)
+		assert.Equal(t, 1.0, funnel.QualifiedConversion)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	proxies, err := routes.NewProxies([]string{"10.0.0.0/8", "192.0.2.1"}, "")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for _, c := range []struct {
+		remoteAddr, forwarded, want string
+	}{
+		// X-Forwarded-For means nothing coming from anyone but a trusted proxy
+		{"198.51.100.7:4000", "203.0.113.1", "198.51.100.7"},
+		{"192.0.2.1:4000", "203.0.113.1", "203.0.113.1"},
+		{"192.0.2.1:4000", "", "192.0.2.1"},
+		// the client can only make up the addresses before the one the proxies added
+		{"10.0.0.2:4000", "198.51.100.9, 203.0.113.1, 10.0.0.1", "203.0.113.1"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = c.remoteAddr
+		if c.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", c.forwarded)
+		}
+		assert.Equal(t, c.want, proxies.ClientIP(req), c.remoteAddr+" "+c.forwarded)
+	}
+
+	_, err = routes.NewProxies([]string{"not-an-ip"}, "")
+	assert.Error(t, err)
+}
+
+// followReferralLink visits the referral link without following its redirect.
+func followReferralLink(t *testing.T, path, ip string) *http.Response {
+	req, err := http.NewRequest(http.MethodGet, url+"/r/"+path, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	req.Header.Set("X-Forwarded-For", ip)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Do(req)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return resp
+}
+
+func registerWithCookie(t *testing.T, cookie *http.Cookie) *core.User {
+	req := newJSONRequest(http.MethodPost, url+"/register", &handler.UserRequest{Name: "Visitor", Email: uniqueEmail("visitor")})
+	req.AddCookie(cookie)
+
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return nil
+	}
+
+	user := &core.User{}
+	if !assert.NoError(t, getResponseBody(resp.Body, user)) {
+		return nil
+	}
+	return user
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/errors"
+	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalEmail(t *testing.T) {
+	for email, want := range map[string]string{
+		"Jane.Doe+promo@Gmail.com":   "janedoe@gmail.com",
+		"j.a.n.e.doe@googlemail.com": "janedoe@gmail.com",
+		"Jane.Doe+promo@Outlook.com": "jane.doe@outlook.com",
+		// providers not known to drop tags may deliver "+" addresses to a mailbox of their own
+		"Jane.Doe+promo@example.com": "jane.doe+promo@example.com",
+		" jane@example.com ":         "jane@example.com",
+	} {
+		assert.Equal(t, want, core.CanonicalEmail(email), email)
+	}
+}
+
+func TestDuplicateAccounts(t *testing.T) {
+	mailbox := fmt.Sprintf("dup.account%d", time.Now().UnixNano())
+	resp, err := registerUser(&handler.UserRequest{Name: "Original", Email: mailbox + "@gmail.com"})
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+	original := &core.User{}
+	if !assert.NoError(t, getResponseBody(resp.Body, original)) {
+		return
+	}
+
+	for _, email := range []string{
+		mailbox + "@GMAIL.com",
+		mailbox + "+2@gmail.com",
+		"d.u.p." + mailbox[4:] + "@googlemail.com",
+	} {
+		resp, err = registerUser(&handler.UserRequest{Name: "Copy", Email: email})
+		if assert.NoError(t, err) {
+			assertError(t, resp, http.StatusConflict, errors.ErrDuplicateAccount)
+		}
+	}
+
+	// an account from before canonical emails, left in place by the backfill
+	var duplicateID string
+	err = testHandler.client.QueryRow(context.Background(), `INSERT INTO users (name, email, canonical_email, duplicate_of)
+	VALUES ('Copy', $1, $2, $3) RETURNING id`, mailbox+"+old@gmail.com", core.CanonicalEmail(original.Email), original.ID).Scan(&duplicateID)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	resp, err = http.Get(url + "/admin/fraud/duplicate-accounts?limit=100")
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+	duplicates := []*core.DuplicateAccounts{}
+	if !assert.NoError(t, getResponseBody(resp.Body, &duplicates)) {
+		return
+	}
+
+	var found *core.DuplicateAccounts
+	for _, duplicate := range duplicates {
+		if duplicate.CanonicalEmail == core.CanonicalEmail(original.Email) {
+			found = duplicate
+		}
+	}
+	if assert.NotNil(t, found) && assert.Len(t, found.Users, 2) {
+		assert.Equal(t, original.ID, found.Users[0].ID)
+		assert.Equal(t, duplicateID, found.Users[1].ID)
+	}
+}
@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPointsExpireFIFO(t *testing.T) {
+	user, err := seedOneUser("Spender", uniqueEmail("spender"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// opening balances never expire
+	_, err = seedPointBalanceForUser(user.ID, 100)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	resp, err := http.Post(url+"/admin/promos", "application/json", serialize(&handler.PromoRequest{UserID: user.ID, Points: 50}))
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+
+	balance := &handler.PointsBalance{}
+	resp, err = http.Get(url + "/users/" + user.ID + "/balance")
+	if !assert.NoError(t, err) || !assert.NoError(t, getResponseBody(resp.Body, balance)) {
+		return
+	}
+	assert.Equal(t, 150, balance.Available)
+	if assert.Len(t, balance.Expiring, 1) {
+		assert.Equal(t, 50, balance.Expiring[0].Points)
+	}
+
+	recipient, err := seedOneUser("Recipient", uniqueEmail("recipient"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = seedPointBalanceForUser(recipient.ID, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// the transfer spends the opening lot first and 20 points of the promo lot
+	resp, err = transaction(&handler.TransferPointsRequest{SenderID: user.ID, RecipientID: recipient.ID, Points: 120})
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+
+	_, err = testHandler.client.Exec(context.Background(),
+		"UPDATE point_lots SET expires_at = CURRENT_TIMESTAMP - INTERVAL '1 minute' WHERE user_id = $1 AND source = 'PROMO'", user.ID)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	err = testHandler.handler.ExpirePoints(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	assertBalances(t, user.ID, 0, 0)
+
+	// the recipient's points came from a transfer and are still spendable
+	assertBalances(t, recipient.ID, 120, 120)
+}
@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/Qalifah/aboki-africa-assessment/routes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFraudReview(t *testing.T) {
+	referralConfig, fraudConfig := testHandler.config.Referral, testHandler.config.Fraud
+	defer func() { testHandler.config.Referral, testHandler.config.Fraud = referralConfig, fraudConfig }()
+	testHandler.config.Referral.QualifyingAction = core.QualifyNone
+	testHandler.config.Fraud.HoldThreshold = 50
+
+	rule := saveRewardRule(t, http.MethodPost, url+"/admin/reward-rules", &core.RewardRule{
+		Name: "Fraud test rule", Kind: core.RuleKindFlat, Active: true, Points: 10,
+	})
+	if rule == nil {
+		return
+	}
+	defer func() {
+		rule.Active = false
+		saveRewardRule(t, http.MethodPut, url+"/admin/reward-rules/"+rule.ID, rule)
+	}()
+
+	n := time.Now().UnixNano()
+	device := fmt.Sprintf("device-%d", n)
+	referrer := registerFrom(t, &handler.UserRequest{Name: "Ring Leader", Email: uniqueEmail("leader")}, "10.20.0.1", device)
+	if referrer == nil {
+		return
+	}
+	code, err := testHandler.userRefCodeRepository.FindReferralCodeByUserID(context.Background(), referrer.ID)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	clean := registerFrom(t, &handler.UserRequest{Name: "Ada", Email: uniqueEmail("ada"), ReferralCode: &code.Code}, "10.20.0.2", "")
+	if clean == nil {
+		return
+	}
+	assertReferralStatus(t, clean.ID, core.ReferralStatusQualified)
+	assertBonus(t, referrer.ID, 10)
+
+	// the referrer's own device signing up referees
+	held := make([]*core.User, 2)
+	for i := range held {
+		req := &handler.UserRequest{Name: fmt.Sprintf("Ring %d", i+1), Email: fmt.Sprintf("ring%d%d+promo@example.com", n, i), ReferralCode: &code.Code}
+		if held[i] = registerFrom(t, req, fmt.Sprintf("10.20.1.%d", i+1), device); held[i] == nil {
+			return
+		}
+		assertReferralStatus(t, held[i].ID, core.ReferralStatusHeld)
+	}
+	assertBonus(t, referrer.ID, 10)
+
+	reviews := listFraudReviews(t, referrer.ID)
+	if !assert.Len(t, reviews, 2) {
+		return
+	}
+	for _, review := range reviews {
+		assert.Equal(t, core.FraudStatusHeld, review.Status)
+		assert.GreaterOrEqual(t, review.Score, 50)
+		assert.Contains(t, hitRules(review), "referrer_match")
+		assert.Contains(t, hitRules(review), "shared_device")
+		assert.Contains(t, hitRules(review), "plus_addressing")
+	}
+
+	approved := reviewFraud(t, reviews[0].ID, "approve", http.StatusOK)
+	if approved != nil {
+		assert.Equal(t, core.FraudStatusApproved, approved.Status)
+		assert.NotNil(t, approved.ReviewedAt)
+	}
+	assertReferralStatus(t, reviews[0].RefereeID, core.ReferralStatusQualified)
+	assertBonus(t, referrer.ID, 20)
+	reviewFraud(t, reviews[0].ID, "reject", http.StatusUnprocessableEntity)
+
+	rejected := reviewFraud(t, reviews[1].ID, "reject", http.StatusOK)
+	if rejected != nil {
+		assert.Equal(t, core.FraudStatusRejected, rejected.Status)
+	}
+	assertReferralStatus(t, reviews[1].RefereeID, core.ReferralStatusClawedBack)
+	assertReferralCount(t, referrer.ID, 2)
+	assertBonus(t, referrer.ID, 20)
+
+	reviewFraud(t, "00000000-0000-0000-0000-000000000000", "approve", http.StatusNotFound)
+}
+
+// registerFrom registers a user through the API as if from the given IP and device.
+func registerFrom(t *testing.T, req *handler.UserRequest, ip, device string) *core.User {
+	httpReq := newJSONRequest(http.MethodPost, url+"/register", req)
+	httpReq.Header.Set("X-Forwarded-For", ip)
+	if device != "" {
+		httpReq.Header.Set(routes.DeviceFingerprintHeader, device)
+	}
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return nil
+	}
+
+	user := &core.User{}
+	if !assert.NoError(t, getResponseBody(resp.Body, user)) {
+		return nil
+	}
+	return user
+}
+
+// listFraudReviews returns the held reviews of the referrer's referrals.
+func listFraudReviews(t *testing.T, referrerID string) []*core.FraudReview {
+	resp, err := http.Get(url + "/admin/fraud/reviews?limit=100")
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return nil
+	}
+
+	all := []*core.FraudReview{}
+	if !assert.NoError(t, getResponseBody(resp.Body, &all)) {
+		return nil
+	}
+
+	reviews := []*core.FraudReview{}
+	for _, review := range all {
+		if review.ReferrerID == referrerID {
+			reviews = append(reviews, review)
+		}
+	}
+	return reviews
+}
+
+func reviewFraud(t *testing.T, reviewID, action string, wantStatus int) *core.FraudReview {
+	req := newJSONRequest(http.MethodPost, url+"/admin/fraud/reviews/"+reviewID+"/"+action, &handler.FraudReviewRequest{Note: "checked"})
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) || !assert.Equal(t, wantStatus, resp.StatusCode) || wantStatus != http.StatusOK {
+		return nil
+	}
+
+	review := &core.FraudReview{}
+	if !assert.NoError(t, getResponseBody(resp.Body, review)) {
+		return nil
+	}
+	return review
+}
+
+func hitRules(review *core.FraudReview) []string {
+	rules := []string{}
+	for _, hit := range review.Hits {
+		rules = append(rules, hit.Rule)
+	}
+	return rules
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListTransactions(t *testing.T) {
+	sender, err := seedOneUser("Sender", uniqueEmail("sender"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = seedPointBalanceForUser(sender.ID, 1000)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	recipient, err := seedOneUser("Recipient", uniqueEmail("recipient"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = seedPointBalanceForUser(recipient.ID, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for i := 1; i <= 5; i++ {
+		resp, err := transaction(&handler.TransferPointsRequest{SenderID: sender.ID, RecipientID: recipient.ID, Points: i * 10})
+		if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+			return
+		}
+	}
+
+	// page through everything the sender sent, two at a time
+	seen := map[string]bool{}
+	points := []int{}
+	cursor := ""
+	for pages := 0; pages < 5; pages++ {
+		page, code, err := listTransactions(sender.ID, "direction=sent&limit=2&cursor="+cursor)
+		if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, code) {
+			return
+		}
+
+		for _, tran := range page.Data {
+			assert.False(t, seen[tran.ID], "transaction returned twice")
+			seen[tran.ID] = true
+			points = append(points, tran.Points)
+		}
+
+		cursor = page.NextCursor
+		if cursor == "" {
+			break
+		}
+	}
+	assert.Equal(t, []int{50, 40, 30, 20, 10}, points)
+
+	page, code, err := listTransactions(recipient.ID, "direction=received&type=TRANSFER&min_points=20&max_points=40")
+	if assert.NoError(t, err) && assert.Equal(t, http.StatusOK, code) {
+		assert.Len(t, page.Data, 3)
+		assert.Empty(t, page.NextCursor)
+	}
+
+	page, code, err = listTransactions(recipient.ID, "direction=sent")
+	if assert.NoError(t, err) && assert.Equal(t, http.StatusOK, code) {
+		assert.Empty(t, page.Data)
+	}
+
+	_, code, err = listTransactions(recipient.ID, "cursor=not-a-cursor")
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusBadRequest, code)
+	}
+}
+
+func listTransactions(userID, query string) (*handler.TransactionPage, int, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/users/%s/transactions?%s", url, userID, query))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page := &handler.TransactionPage{}
+	if resp.StatusCode != http.StatusOK {
+		return page, resp.StatusCode, nil
+	}
+	return page, resp.StatusCode, getResponseBody(resp.Body, page)
+}
@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorizeCaptureTransfer(t *testing.T) {
+	sender, recipient, ok := seedHoldUsers(t, 1000)
+	if !ok {
+		return
+	}
+
+	hold := authorizeTransfer(t, &handler.AuthorizeTransferRequest{SenderID: sender.ID, RecipientID: recipient.ID, Points: 600}, http.StatusOK)
+	if hold == nil {
+		return
+	}
+	assert.Equal(t, core.HoldStatusActive, hold.Status)
+	assertBalance(t, sender.ID, 1000, 400, 600)
+
+	// held points can't be spent elsewhere
+	resp, err := transaction(&handler.TransferPointsRequest{SenderID: sender.ID, RecipientID: recipient.ID, Points: 500})
+	if assert.NoError(t, err) {
+		assert.NotEqual(t, http.StatusOK, resp.StatusCode)
+	}
+	authorizeTransfer(t, &handler.AuthorizeTransferRequest{SenderID: sender.ID, RecipientID: recipient.ID, Points: 500}, http.StatusUnprocessableEntity)
+
+	// capturing more than was held is rejected
+	resp, err = captureTransfer(hold.ID, &handler.CaptureTransferRequest{Points: 700})
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+
+	// a partial capture releases the rest of the hold
+	resp, err = captureTransfer(hold.ID, &handler.CaptureTransferRequest{Points: 250})
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+	tran := &core.Transaction{}
+	if assert.NoError(t, getResponseBody(resp.Body, tran)) {
+		assert.Equal(t, 250, tran.Points)
+		assert.Equal(t, "TRANSFER", tran.Type)
+	}
+	assertBalance(t, sender.ID, 750, 750, 0)
+	assertBalance(t, recipient.ID, 250, 250, 0)
+
+	// a hold is captured once
+	resp, err = captureTransfer(hold.ID, &handler.CaptureTransferRequest{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+}
+
+func TestVoidTransfer(t *testing.T) {
+	sender, recipient, ok := seedHoldUsers(t, 300)
+	if !ok {
+		return
+	}
+
+	hold := authorizeTransfer(t, &handler.AuthorizeTransferRequest{SenderID: sender.ID, RecipientID: recipient.ID, Points: 300}, http.StatusOK)
+	if hold == nil {
+		return
+	}
+	assertBalance(t, sender.ID, 300, 0, 300)
+
+	resp, err := http.Post(url+"/holds/"+hold.ID+"/void", "application/json", nil)
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+	assertBalance(t, sender.ID, 300, 300, 0)
+
+	resp, err = captureTransfer(hold.ID, &handler.CaptureTransferRequest{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+	assertBalance(t, recipient.ID, 0, 0, 0)
+}
+
+func TestExpiredHold(t *testing.T) {
+	sender, recipient, ok := seedHoldUsers(t, 500)
+	if !ok {
+		return
+	}
+
+	hold := authorizeTransfer(t, &handler.AuthorizeTransferRequest{SenderID: sender.ID, RecipientID: recipient.ID, Points: 200}, http.StatusOK)
+	if hold == nil {
+		return
+	}
+
+	_, err := testHandler.client.Exec(context.Background(), "UPDATE holds SET expires_at = now() - interval '1 second' WHERE id = $1", hold.ID)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// an expired hold stops reserving points straight away
+	assertBalance(t, sender.ID, 500, 500, 0)
+
+	resp, err := captureTransfer(hold.ID, &handler.CaptureTransferRequest{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+
+	if !assert.NoError(t, testHandler.handler.ExpireHolds(context.Background())) {
+		return
+	}
+	expired, err := testHandler.holdRepository.FindHoldByID(context.Background(), hold.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, core.HoldStatusExpired, expired.Status)
+	}
+}
+
+func seedHoldUsers(t *testing.T, points int) (*core.User, *core.User, bool) {
+	sender, err := seedOneUser("Holder", uniqueEmail("holder"))
+	if !assert.NoError(t, err) {
+		return nil, nil, false
+	}
+
+	recipient, err := seedOneUser("Merchant", uniqueEmail("merchant"))
+	if !assert.NoError(t, err) {
+		return nil, nil, false
+	}
+
+	if _, err = seedPointBalanceForUser(sender.ID, points); !assert.NoError(t, err) {
+		return nil, nil, false
+	}
+	if _, err = seedPointBalanceForUser(recipient.ID, 0); !assert.NoError(t, err) {
+		return nil, nil, false
+	}
+
+	return sender, recipient, true
+}
+
+func assertBalance(t *testing.T, userID string, wantTotal, wantAvailable, wantHeld int) {
+	resp, err := http.Get(url + "/users/" + userID + "/balance")
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+
+	balance := &handler.PointsBalance{}
+	if assert.NoError(t, getResponseBody(resp.Body, balance)) {
+		assert.Equal(t, wantTotal, balance.Total)
+		assert.Equal(t, wantAvailable, balance.Available)
+		assert.Equal(t, wantHeld, balance.Held)
+	}
+}
+
+func authorizeTransfer(t *testing.T, req *handler.AuthorizeTransferRequest, wantCode int) *core.Hold {
+	resp, err := http.Post(url+"/holds", "application/json", serialize(req))
+	if !assert.NoError(t, err) || !assert.Equal(t, wantCode, resp.StatusCode) || wantCode != http.StatusOK {
+		return nil
+	}
+
+	hold := &core.Hold{}
+	if !assert.NoError(t, getResponseBody(resp.Body, hold)) {
+		return nil
+	}
+	return hold
+}
+
+func captureTransfer(holdID string, req *handler.CaptureTransferRequest) (*http.Response, error) {
+	return http.Post(url+"/holds/"+holdID+"/capture", "application/json", serialize(req))
+}
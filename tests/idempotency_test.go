@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/Qalifah/aboki-africa-assessment/routes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotentTransaction(t *testing.T) {
+	sender, err := seedOneUser("Sender", uniqueEmail("sender"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = seedPointBalanceForUser(sender.ID, 500)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	recipient, err := seedOneUser("Recipient", uniqueEmail("recipient"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = seedPointBalanceForUser(recipient.ID, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	key := uniqueEmail("key")
+	req := &handler.TransferPointsRequest{
+		SenderID:    sender.ID,
+		RecipientID: recipient.ID,
+		Points:      100,
+	}
+
+	first, err := idempotentTransaction(key, req)
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, first.StatusCode) {
+		return
+	}
+
+	retry, err := idempotentTransaction(key, req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, http.StatusOK, retry.StatusCode)
+	assert.Equal(t, "true", retry.Header.Get("Idempotent-Replayed"))
+
+	// the transfer was only applied once
+	assertBalances(t, sender.ID, 400, 400)
+
+	// the same key can't be reused for a different request
+	req.Points = 50
+	mismatch, err := idempotentTransaction(key, req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusUnprocessableEntity, mismatch.StatusCode)
+	}
+	assertBalances(t, sender.ID, 400, 400)
+}
+
+func idempotentTransaction(key string, req *handler.TransferPointsRequest) (*http.Response, error) {
+	r, err := http.NewRequest(http.MethodPost, url+"/transaction", serialize(req))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set(routes.IdempotencyKeyHeader, key)
+	return http.DefaultClient.Do(r)
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/errors"
+	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReferralIntegrity(t *testing.T) {
+	mailbox := fmt.Sprintf("alias%d", time.Now().UnixNano())
+	resp, err := registerUser(&handler.UserRequest{Name: "Referrer", Email: mailbox + "@gmail.com"})
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+	referrer := &core.User{}
+	if !assert.NoError(t, getResponseBody(resp.Body, referrer)) {
+		return
+	}
+	code, err := testHandler.userRefCodeRepository.FindReferralCodeByUserID(context.Background(), referrer.ID)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// another address of the referrer's mailbox doesn't even get an account
+	resp, err = registerUser(&handler.UserRequest{Name: "Alias", Email: strings.ToUpper(mailbox) + "+promo@gmail.com", ReferralCode: &code.Code})
+	if assert.NoError(t, err) {
+		assertError(t, resp, http.StatusConflict, errors.ErrDuplicateAccount)
+	}
+
+	missing := "NOPE-404"
+	resp, err = registerUser(&handler.UserRequest{Name: "Referee", Email: uniqueEmail("referee"), ReferralCode: &missing})
+	if assert.NoError(t, err) {
+		assertError(t, resp, http.StatusNotFound, errors.ErrReferralCodeNotFound)
+	}
+
+	referee, _, ok := registerReferredUser(t, &code.Code)
+	if !ok {
+		return
+	}
+	other, _, ok := registerReferrer(t)
+	if !ok {
+		return
+	}
+
+	// the database holds up without the handler's checks
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name     string
+		referral *core.Referral
+		want     error
+	}{
+		{"second referrer", &core.Referral{ReferrerID: other.ID, RefereeID: referee.ID}, errors.ErrAlreadyReferred},
+		{"self-referral", &core.Referral{ReferrerID: referrer.ID, RefereeID: referrer.ID}, errors.ErrSelfReferral},
+		{"cycle", &core.Referral{ReferrerID: referee.ID, RefereeID: referrer.ID}, errors.ErrReferralCycle},
+	} {
+		tc.referral.Status = core.ReferralStatusQualified
+		assert.Equal(t, tc.want, testHandler.userReferralRepository.CreateReferral(ctx, tc.referral), tc.name)
+	}
+}
+
+func assertError(t *testing.T, resp *http.Response, wantStatus int, want error) {
+	assert.Equal(t, wantStatus, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	if assert.NoError(t, err) {
+		assert.Equal(t, want.Error(), strings.TrimSpace(string(body)))
+	}
+}
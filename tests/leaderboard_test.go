@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaderboard(t *testing.T) {
+	// most referrals, then a tie broken by who got there first
+	users := make([]*core.User, 3)
+	for i, referrals := range []int{4, 3, 3} {
+		user, code, ok := registerReferrer(t)
+		if !ok {
+			return
+		}
+		for j := 0; j < referrals; j++ {
+			if !registerReferee(t, code) {
+				return
+			}
+		}
+		users[i] = user
+	}
+	loner, _, ok := registerReferrer(t)
+	if !ok {
+		return
+	}
+
+	if !assert.NoError(t, testHandler.leaderboard.Refresh(context.Background())) {
+		return
+	}
+
+	ranks := make([]int, len(users))
+	for i, user := range users {
+		board := getLeaderboard(t, "period=month&by=referrals&limit=1&user_id="+user.ID)
+		if board == nil || !assert.NotNil(t, board.Me) {
+			return
+		}
+		assert.Len(t, board.Entries, 1)
+		assert.NotNil(t, board.RefreshedAt)
+		ranks[i] = board.Me.Rank
+	}
+	// other tests' referrers may rank in between
+	assert.Less(t, ranks[0], ranks[1])
+	assert.Less(t, ranks[1], ranks[2])
+
+	board := getLeaderboard(t, "period=week&user_id="+loner.ID)
+	if board != nil {
+		assert.Nil(t, board.Me)
+	}
+
+	resp, err := http.Get(url + "/leaderboard?period=year")
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	}
+}
+
+func getLeaderboard(t *testing.T, query string) *core.Leaderboard {
+	resp, err := http.Get(url + "/leaderboard?" + query)
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return nil
+	}
+
+	board := &core.Leaderboard{}
+	if !assert.NoError(t, getResponseBody(resp.Body, board)) {
+		return nil
+	}
+	return board
+}
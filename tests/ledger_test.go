@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLedgerTransfer(t *testing.T) {
+	sender, err := seedOneUser("Sender", uniqueEmail("sender"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = seedPointBalanceForUser(sender.ID, 500)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	recipient, err := seedOneUser("Recipient", uniqueEmail("recipient"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = seedPointBalanceForUser(recipient.ID, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	resp, err := transaction(&handler.TransferPointsRequest{
+		SenderID:    sender.ID,
+		RecipientID: recipient.ID,
+		Points:      200,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	ctx := context.Background()
+	senderBalance, err := testHandler.userPointRepository.GetPointsBalance(ctx, sender.ID)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.EqualValues(t, 300, senderBalance)
+
+	recipientBalance, err := testHandler.userPointRepository.GetPointsBalance(ctx, recipient.ID)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.EqualValues(t, 200, recipientBalance)
+
+	// the cached balance must match the running balance of the last posting
+	postings, err := testHandler.ledgerRepository.ListPostingsByAccount(ctx, core.UserPointsAccount(sender.ID))
+	if !assert.NoError(t, err) || !assert.Len(t, postings, 2) {
+		return
+	}
+	assert.EqualValues(t, 500, postings[0].Amount)
+	assert.EqualValues(t, -200, postings[1].Amount)
+	assert.EqualValues(t, senderBalance, postings[1].BalanceAfter)
+
+	// overdrafts are rejected by the ledger and leave both balances untouched
+	resp, err = transaction(&handler.TransferPointsRequest{
+		SenderID:    sender.ID,
+		RecipientID: recipient.ID,
+		Points:      1000,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	senderBalance, err = testHandler.userPointRepository.GetPointsBalance(ctx, sender.ID)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.EqualValues(t, 300, senderBalance)
+
+	// a negative amount would pull points out of the recipient
+	resp, err = transaction(&handler.TransferPointsRequest{
+		SenderID:    sender.ID,
+		RecipientID: recipient.ID,
+		Points:      -100,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	recipientBalance, err = testHandler.userPointRepository.GetPointsBalance(ctx, recipient.ID)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.EqualValues(t, 200, recipientBalance)
+}
+
+func TestLedgerRejectsUnbalancedEntry(t *testing.T) {
+	entry := &core.JournalEntry{
+		Type: core.EntryTypeTransfer,
+		Postings: []*core.Posting{
+			{AccountCode: core.SystemOpeningBalanceAccount, Amount: -10},
+			{AccountCode: core.SystemReferralBonusAccount, Amount: 5},
+		},
+	}
+
+	err := testHandler.ledgerRepository.PostEntry(context.Background(), entry)
+	assert.Error(t, err)
+	assert.Empty(t, entry.ID)
+}
+
+func uniqueEmail(prefix string) string {
+	return fmt.Sprintf("%s%d@example.com", prefix, time.Now().UnixNano())
+}
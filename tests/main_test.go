@@ -12,9 +12,17 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Qalifah/aboki-africa-assessment/campaign"
+	"github.com/Qalifah/aboki-africa-assessment/clicks"
 	"github.com/Qalifah/aboki-africa-assessment/config"
 	"github.com/Qalifah/aboki-africa-assessment/database/postgres"
+	"github.com/Qalifah/aboki-africa-assessment/fraud"
 	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/Qalifah/aboki-africa-assessment/leaderboard"
+	"github.com/Qalifah/aboki-africa-assessment/payout"
+	"github.com/Qalifah/aboki-africa-assessment/paystack"
+	"github.com/Qalifah/aboki-africa-assessment/reconciliation"
+	"github.com/Qalifah/aboki-africa-assessment/rules"
 	"github.com/dimfeld/httptreemux"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
@@ -23,12 +31,23 @@ import (
 var url = "http://localhost:%s"
 
 type TestHandler struct {
-	userRepository         		core.UserRepository
-	userRefCodeRepository  		core.ReferralCodeRepository
-	userReferralRepository 		core.ReferralRepository
-	userPointRepository    		core.PointRepository
-	userTransactionRepository	core.TransactionRepository
-	client                 		*postgres.Client
+	userRepository            core.UserRepository
+	userRefCodeRepository     core.ReferralCodeRepository
+	userReferralRepository    core.ReferralRepository
+	userPointRepository       core.PointRepository
+	userTransactionRepository core.TransactionRepository
+	ledgerRepository          core.LedgerRepository
+	payoutRepository          core.PayoutRepository
+	idempotencyRepository     core.IdempotencyRepository
+	reconciliationRepository  core.ReconciliationRepository
+	holdRepository            core.HoldRepository
+	rewardRuleRepository      core.RewardRuleRepository
+	campaignRepository        core.CampaignRepository
+	leaderboard               *leaderboard.Service
+	paystack                  *paystack.FakeServer
+	config                    *config.BaseConfig
+	handler                   *handler.Handler
+	client                    *postgres.Client
 }
 
 var testHandler *TestHandler
@@ -55,12 +74,49 @@ func TestMain(m *testing.M) {
 	referralRepo := postgres.NewReferralRepository(postgresClient)
 	pointsRepo := postgres.NewPointRepository(postgresClient)
 	transactionRepo := postgres.NewTransactionRepository(postgresClient)
+	ledgerRepo := postgres.NewLedgerRepository(postgresClient)
+	payoutRepo := postgres.NewPayoutRepository(postgresClient)
+	idempotencyRepo := postgres.NewIdempotencyRepository(postgresClient)
+	holdRepo := postgres.NewHoldRepository(postgresClient)
+	scheduledTransferRepo := postgres.NewScheduledTransferRepository(postgresClient)
+	rewardRuleRepo := postgres.NewRewardRuleRepository(postgresClient)
+	campaignRepo := postgres.NewCampaignRepository(postgresClient)
+	leaderboardRepo := postgres.NewLeaderboardRepository(postgresClient)
+	referralClickRepo := postgres.NewReferralClickRepository(postgresClient)
+	clawbackRepo := postgres.NewClawbackRepository(postgresClient)
+	fraudRepo := postgres.NewFraudRepository(postgresClient)
+
+	rewardRules := rules.New(rewardRuleRepo)
+	if err = rewardRules.Reload(context.Background()); err != nil {
+		log.Fatalf("failed to load reward rules: %v", err)
+	}
+	fraudRules := fraud.New(fraudRepo, cfg.ReferralLinks.IPHashKey, cfg.Fraud.Window)
+	reconciliationRepo := postgres.NewReconciliationRepository(postgresClient)
+
+	h := handler.New(userRepo, referralCodeRepo, referralRepo, pointsRepo, transactionRepo, ledgerRepo, holdRepo, scheduledTransferRepo, rewardRuleRepo, campaignRepo, clawbackRepo, fraudRepo, rewardRules, fraudRules, cfg, postgresClient.BeginTx)
+
+	// payouts talk to a local fake of the Paystack API
+	cfg.PaystackAPIKey = "sk_test_fake"
+	fakePaystack := paystack.NewFakeServer(cfg.PaystackAPIKey)
+	payoutService := payout.New(payoutRepo, pointsRepo, ledgerRepo, paystack.New(cfg.PaystackAPIKey, fakePaystack.URL), cfg, postgresClient.BeginTx)
 
-	h := handler.New(userRepo, referralCodeRepo, referralRepo, pointsRepo, transactionRepo, postgresClient.BeginTx)
+	// the tests call from loopback as if through a proxy, setting X-Forwarded-For and countryHeader for
+	// the client's IP and country
+	proxies, err := routes.NewProxies([]string{"127.0.0.1", "::1"}, countryHeader)
+	if err != nil {
+		log.Fatalf("failed to parse trusted proxies: %v", err)
+	}
 
 	router := httptreemux.New()
 
-	routes.SetupRoutes(router, h)
+	routes.SetupRoutes(router, h, routes.NewIdempotency(idempotencyRepo, time.Minute), proxies)
+	routes.SetupPayoutRoutes(router, payoutService)
+	routes.SetupRewardRuleRoutes(router, rewardRules)
+	leaderboardService := leaderboard.New(leaderboardRepo, postgresClient.BeginTx)
+	routes.SetupLeaderboardRoutes(router, leaderboardService)
+	routes.SetupReferralLinkRoutes(router, clicks.New(referralClickRepo, userRepo, cfg.ReferralLinks.IPHashKey), cfg.ReferralLinks, proxies)
+	routes.SetupCampaignRoutes(router, campaign.New(campaignRepo, userRepo, rewardRuleRepo, rewardRules, postgresClient.BeginTx))
+	routes.SetupReconciliationRoutes(router, reconciliation.New(reconciliationRepo, ledgerRepo, postgresClient.BeginTx))
 
 	url = fmt.Sprintf(url, cfg.ServePort)
 	srv := &http.Server{
@@ -78,20 +134,33 @@ func TestMain(m *testing.M) {
 	time.Sleep(time.Second)
 
 	testHandler = &TestHandler{
-		userRepository:         userRepo,
-		userRefCodeRepository: referralCodeRepo,
-		userReferralRepository: referralRepo,
-		userPointRepository:    pointsRepo,
+		userRepository:            userRepo,
+		userRefCodeRepository:     referralCodeRepo,
+		userReferralRepository:    referralRepo,
+		userPointRepository:       pointsRepo,
 		userTransactionRepository: transactionRepo,
-		client:                 postgresClient,
+		ledgerRepository:          ledgerRepo,
+		payoutRepository:          payoutRepo,
+		idempotencyRepository:     idempotencyRepo,
+		reconciliationRepository:  reconciliationRepo,
+		holdRepository:            holdRepo,
+		rewardRuleRepository:      rewardRuleRepo,
+		campaignRepository:        campaignRepo,
+		leaderboard:               leaderboardService,
+		paystack:                  fakePaystack,
+		config:                    cfg,
+		handler:                   h,
+		client:                    postgresClient,
 	}
 	// run the tests
 	code := m.Run()
 
-	ctx, _ := context.WithTimeout(context.Background(), 1*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatalf("unable to shutdown server gracefully: %v", err)
 	}
+	fakePaystack.Close()
 
 	os.Exit(code)
 }
@@ -108,4 +177,4 @@ func serialize(obj interface{}) *bytes.Buffer {
 func deleteAllFromTable(name string) error {
 	_, err := testHandler.client.Exec(context.Background(), fmt.Sprintf("DELETE FROM %s", name))
 	return err
-}
\ No newline at end of file
+}
@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/payout"
+	"github.com/Qalifah/aboki-africa-assessment/paystack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayout(t *testing.T) {
+	user, err := seedOneUser("Payee", uniqueEmail("payee"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = seedPointBalanceForUser(user.ID, 2000)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	req := &payout.Request{
+		UserID:        user.ID,
+		Points:        800,
+		AccountNumber: "0123456789",
+		BankCode:      "058",
+		AccountName:   "Payee",
+	}
+
+	// below the minimum payout
+	resp, err := requestPayout(&payout.Request{UserID: user.ID, Points: 100, AccountNumber: "0123456789", BankCode: "058", AccountName: "Payee"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	resp, err = requestPayout(req)
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+
+	pending := &core.Payout{}
+	if !assert.NoError(t, getResponseBody(resp.Body, pending)) {
+		return
+	}
+	assert.Equal(t, core.PayoutStatusPending, pending.Status)
+	assert.Equal(t, 80000, pending.AmountKobo)
+
+	// pending payouts reduce the spendable balance but aren't debited yet
+	assertBalances(t, user.ID, 1200, 2000)
+
+	resp, err = testHandler.paystack.SendEvent(url+"/paystack/webhook", paystack.EventTransferSuccess, pending.Reference)
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+	assertBalances(t, user.ID, 1200, 1200)
+
+	// replayed events don't debit twice
+	resp, err = testHandler.paystack.SendEvent(url+"/paystack/webhook", paystack.EventTransferSuccess, pending.Reference)
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+	assertBalances(t, user.ID, 1200, 1200)
+
+	// a reversal refunds the points
+	resp, err = testHandler.paystack.SendEvent(url+"/paystack/webhook", paystack.EventTransferReversed, pending.Reference)
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+	assertBalances(t, user.ID, 2000, 2000)
+
+	resp, err = http.Get(url + "/payouts/" + pending.Reference)
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+	reversed := &core.Payout{}
+	if assert.NoError(t, getResponseBody(resp.Body, reversed)) {
+		assert.Equal(t, core.PayoutStatusReversed, reversed.Status)
+	}
+}
+
+func TestPayoutFailed(t *testing.T) {
+	user, err := seedOneUser("Payee", uniqueEmail("payee"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = seedPointBalanceForUser(user.ID, 1000)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	testHandler.paystack.SetTransferStatus(paystack.TransferFailed)
+	defer testHandler.paystack.SetTransferStatus(paystack.TransferPending)
+
+	resp, err := requestPayout(&payout.Request{UserID: user.ID, Points: 1000, AccountNumber: "0123456789", BankCode: "058", AccountName: "Payee"})
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+
+	failed := &core.Payout{}
+	if assert.NoError(t, getResponseBody(resp.Body, failed)) {
+		assert.Equal(t, core.PayoutStatusFailed, failed.Status)
+	}
+	assertBalances(t, user.ID, 1000, 1000)
+}
+
+func TestPayoutPaystackServerError(t *testing.T) {
+	user, err := seedOneUser("Payee", uniqueEmail("payee"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = seedPointBalanceForUser(user.ID, 1000)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Paystack may have queued the transfer before failing, so the payout can't be failed yet
+	testHandler.paystack.SetTransferError(http.StatusBadGateway)
+	defer testHandler.paystack.SetTransferError(0)
+
+	resp, err := requestPayout(&payout.Request{UserID: user.ID, Points: 600, AccountNumber: "0123456789", BankCode: "058", AccountName: "Payee"})
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+
+	pending := &core.Payout{}
+	if !assert.NoError(t, getResponseBody(resp.Body, pending)) {
+		return
+	}
+	assert.Equal(t, core.PayoutStatusPending, pending.Status)
+	assertBalances(t, user.ID, 400, 1000)
+
+	resp, err = testHandler.paystack.SendEvent(url+"/paystack/webhook", paystack.EventTransferSuccess, pending.Reference)
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+	assertBalances(t, user.ID, 400, 400)
+}
+
+func TestPaystackWebhookRejectsBadSignature(t *testing.T) {
+	resp, err := http.Post(url+"/paystack/webhook", "application/json", serialize(&paystack.Event{Event: paystack.EventTransferSuccess}))
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+}
+
+func assertBalances(t *testing.T, userID string, wantSpendable, wantLedger int) {
+	ctx := context.Background()
+	balance, err := testHandler.userPointRepository.GetPointsBalance(ctx, userID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, wantSpendable, balance)
+	}
+
+	point, err := testHandler.userPointRepository.FindPointByUserID(ctx, userID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, wantLedger, point.Points)
+	}
+}
+
+func requestPayout(req *payout.Request) (*http.Response, error) {
+	return http.Post(url+"/payouts", "application/json", serialize(req))
+}
@@ -0,0 +1,163 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeferredReferralRewards(t *testing.T) {
+	referralConfig := testHandler.config.Referral
+	defer func() { testHandler.config.Referral = referralConfig }()
+	testHandler.config.Referral.QualifyingAction = core.QualifyFirstTransfer
+
+	rule := saveRewardRule(t, http.MethodPost, url+"/admin/reward-rules", &core.RewardRule{
+		Name: "Deferred test rule", Kind: core.RuleKindFlat, Active: true, Points: 10,
+	})
+	if rule == nil {
+		return
+	}
+	defer func() {
+		rule.Active = false
+		saveRewardRule(t, http.MethodPut, url+"/admin/reward-rules/"+rule.ID, rule)
+	}()
+
+	referrer, code, ok := registerReferrer(t)
+	if !ok {
+		return
+	}
+	referee, _, ok := registerReferredUser(t, &code)
+	if !ok {
+		return
+	}
+
+	// nothing is granted until the referee qualifies
+	assertReferralStatus(t, referee.ID, core.ReferralStatusPending)
+	assertBonus(t, referrer.ID, 0)
+
+	entry := core.NewJournalEntry(core.EntryTypeOpening, "", core.SystemOpeningBalanceAccount, core.UserPointsAccount(referee.ID), 10)
+	if !assert.NoError(t, testHandler.ledgerRepository.PostEntry(context.Background(), entry)) {
+		return
+	}
+	resp, err := transaction(&handler.TransferPointsRequest{SenderID: referee.ID, RecipientID: referrer.ID, Points: 5})
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+
+	assertReferralStatus(t, referee.ID, core.ReferralStatusQualified)
+	assertBonus(t, referrer.ID, 10)
+
+	// a referee who doesn't qualify in time never releases the reward
+	late, _, ok := registerReferredUser(t, &code)
+	if !ok {
+		return
+	}
+	_, err = testHandler.client.Exec(context.Background(),
+		"UPDATE referrals SET expires_at = CURRENT_TIMESTAMP - INTERVAL '1 minute' WHERE referee_id = $1", late.ID)
+	if !assert.NoError(t, err) || !assert.NoError(t, testHandler.handler.QualifyReferrals(context.Background())) {
+		return
+	}
+	assertReferralStatus(t, late.ID, core.ReferralStatusExpired)
+
+	var status string
+	err = testHandler.client.QueryRow(context.Background(), `SELECT referral_rewards.status FROM referral_rewards
+	INNER JOIN referrals ON referrals.id = referral_rewards.referral_id WHERE referrals.referee_id = $1`, late.ID).Scan(&status)
+	if assert.NoError(t, err) {
+		assert.Equal(t, core.RewardStatusExpired, status)
+	}
+	assertBonus(t, referrer.ID, 10)
+
+	// with email verification as the qualifying action
+	testHandler.config.Referral.QualifyingAction = core.QualifyEmailVerified
+	verified, _, ok := registerReferredUser(t, &code)
+	if !ok {
+		return
+	}
+	assertBonus(t, referrer.ID, 10)
+
+	// a token for another user, or one that expired, verifies nothing
+	for _, token := range []string{
+		testHandler.handler.EmailVerificationToken(referrer, time.Now().Add(time.Hour)),
+		testHandler.handler.EmailVerificationToken(verified, time.Now().Add(-time.Minute)),
+	} {
+		resp, err = http.Post(url+"/users/"+verified.ID+"/verify-email", "application/json",
+			serialize(&handler.VerifyEmailRequest{Token: token}))
+		if !assert.NoError(t, err) || !assert.Equal(t, http.StatusUnauthorized, resp.StatusCode) {
+			return
+		}
+	}
+	assertReferralStatus(t, verified.ID, core.ReferralStatusPending)
+
+	token := testHandler.handler.EmailVerificationToken(verified, time.Now().Add(time.Hour))
+	resp, err = http.Post(url+"/users/"+verified.ID+"/verify-email", "application/json",
+		serialize(&handler.VerifyEmailRequest{Token: token}))
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+	user := &core.User{}
+	if assert.NoError(t, getResponseBody(resp.Body, user)) {
+		assert.NotNil(t, user.EmailVerifiedAt)
+	}
+
+	// the third referral also earns the 50 of the default every third referral rule
+	assertReferralStatus(t, verified.ID, core.ReferralStatusQualified)
+	assertBonus(t, referrer.ID, 70)
+}
+
+func TestMinPointsQualification(t *testing.T) {
+	referralConfig := testHandler.config.Referral
+	defer func() { testHandler.config.Referral = referralConfig }()
+	testHandler.config.Referral.QualifyingAction = core.QualifyMinPoints
+	testHandler.config.Referral.QualifyingPoints = 100
+
+	referrer, code, ok := registerReferrer(t)
+	if !ok {
+		return
+	}
+	referee, _, ok := registerReferredUser(t, &code)
+	if !ok {
+		return
+	}
+
+	// points the referee can't spend don't count towards the threshold
+	entry := core.NewJournalEntry(core.EntryTypeOpening, "", core.SystemOpeningBalanceAccount, core.UserPointsAccount(referee.ID), 150)
+	if !assert.NoError(t, testHandler.ledgerRepository.PostEntry(context.Background(), entry)) {
+		return
+	}
+	if authorizeTransfer(t, &handler.AuthorizeTransferRequest{SenderID: referee.ID, RecipientID: referrer.ID, Points: 100}, http.StatusOK) == nil {
+		return
+	}
+	if !assert.NoError(t, testHandler.handler.QualifyReferrals(context.Background())) {
+		return
+	}
+	assertReferralStatus(t, referee.ID, core.ReferralStatusPending)
+
+	entry = core.NewJournalEntry(core.EntryTypeOpening, "", core.SystemOpeningBalanceAccount, core.UserPointsAccount(referee.ID), 50)
+	if !assert.NoError(t, testHandler.ledgerRepository.PostEntry(context.Background(), entry)) {
+		return
+	}
+	if !assert.NoError(t, testHandler.handler.QualifyReferrals(context.Background())) {
+		return
+	}
+	assertReferralStatus(t, referee.ID, core.ReferralStatusQualified)
+}
+
+func assertReferralStatus(t *testing.T, refereeID, want string) {
+	var status string
+	err := testHandler.client.QueryRow(context.Background(), "SELECT status FROM referrals WHERE referee_id = $1", refereeID).Scan(&status)
+	if assert.NoError(t, err) {
+		assert.Equal(t, want, status)
+	}
+}
+
+func assertBonus(t *testing.T, userID string, want int) {
+	point, err := testHandler.userPointRepository.FindPointByUserID(context.Background(), userID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, want, point.Bonus)
+	}
+}
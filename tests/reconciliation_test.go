@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/reconciliation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconciliation(t *testing.T) {
+	user, err := seedOneUser("Drifter", uniqueEmail("drifter"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = seedPointBalanceForUser(user.ID, 1000)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// the cached balance drifts and a promo transaction never reaches the ledger
+	ctx := context.Background()
+	_, err = testHandler.client.Exec(ctx, "UPDATE ledger_accounts SET balance = 700 WHERE code = $1", core.UserPointsAccount(user.ID))
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = testHandler.client.Exec(ctx, "INSERT INTO transactions (sender_id, recipient_id, points, type) VALUES ($1, $1, 100, 'PROMO')", user.ID)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	report := runReconciliation(t, false)
+	item := findReconciliationItem(report, user.ID, core.CheckPoints)
+	if !assert.NotNil(t, item) {
+		return
+	}
+	assert.Equal(t, 1100, item.Expected)
+	assert.Equal(t, 700, item.Actual)
+	assert.False(t, item.Repaired)
+	assertBalances(t, user.ID, 700, 700)
+
+	report = runReconciliation(t, true)
+	item = findReconciliationItem(report, user.ID, core.CheckPoints)
+	if assert.NotNil(t, item) {
+		assert.True(t, item.Repaired)
+	}
+	assertBalances(t, user.ID, 1100, 1100)
+
+	// once repaired the user is consistent again
+	report = runReconciliation(t, false)
+	assert.Nil(t, findReconciliationItem(report, user.ID, core.CheckPoints))
+
+	resp, err := http.Get(url + "/admin/reconciliations/" + report.ID)
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+	stored := &core.ReconciliationRun{}
+	if assert.NoError(t, getResponseBody(resp.Body, stored)) {
+		assert.Equal(t, core.RunStatusCompleted, stored.Status)
+		assert.Equal(t, report.Discrepancies, len(stored.Items))
+	}
+}
+
+func runReconciliation(t *testing.T, repair bool) *core.ReconciliationRun {
+	run := &core.ReconciliationRun{}
+	resp, err := http.Post(url+"/admin/reconciliations", "application/json", serialize(&reconciliation.Request{Repair: repair}))
+	if assert.NoError(t, err) && assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		assert.NoError(t, getResponseBody(resp.Body, run))
+	}
+	return run
+}
+
+func findReconciliationItem(run *core.ReconciliationRun, userID, check string) *core.ReconciliationItem {
+	for _, item := range run.Items {
+		if item.UserID == userID && item.Check == check {
+			return item
+		}
+	}
+	return nil
+}
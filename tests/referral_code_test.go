@@ -0,0 +1,231 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVanityReferralCode(t *testing.T) {
+	user, oldCode, ok := registerReferrer(t)
+	if !ok {
+		return
+	}
+
+	for _, invalid := range []string{"abc", "admin", "bad--code", "-code", "way-too-long-for-a-code", "ShitHot"} {
+		resp := setReferralCode(t, user.ID, invalid)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, invalid)
+	}
+
+	vanity := fmt.Sprintf("Vanity-%d", time.Now().UnixNano()%1e8)
+	resp := setReferralCode(t, user.ID, vanity)
+	if !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+	code := &core.ReferralCode{}
+	if assert.NoError(t, getResponseBody(resp.Body, code)) {
+		assert.Equal(t, vanity, code.Code)
+		assert.True(t, code.Vanity)
+	}
+
+	// the code is taken regardless of case
+	other, _, ok := registerReferrer(t)
+	if !ok {
+		return
+	}
+	resp = setReferralCode(t, other.ID, strings.ToUpper(vanity))
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	// the new code matches regardless of case and the old one still works as an alias
+	if !registerReferee(t, strings.ToLower(vanity)) || !registerReferee(t, oldCode) {
+		return
+	}
+	point, err := testHandler.userPointRepository.FindPointByUserID(context.Background(), user.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, point.NumberOfReferredUsers)
+	}
+
+	// two more changes use up the limit of three per window
+	for i := 0; i < 2; i++ {
+		resp = setReferralCode(t, user.ID, fmt.Sprintf("%s-%d", vanity, i))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	resp = setReferralCode(t, user.ID, vanity+"-x")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+}
+
+func setReferralCode(t *testing.T, userID, code string) *http.Response {
+	req := newJSONRequest(http.MethodPut, url+"/users/"+userID+"/referral-code", &handler.ReferralCodeRequest{Code: code})
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return resp
+}
+
+func TestRotateAndRevokeReferralCode(t *testing.T) {
+	user, first, ok := registerReferrer(t)
+	if !ok {
+		return
+	}
+
+	rotated := reissueReferralCode(t, user.ID, "rotate")
+	if rotated == nil {
+		return
+	}
+	assert.NotEqual(t, first, rotated.Code)
+
+	// the rotated code still works for the grace period
+	if !registerReferee(t, first) || !registerReferee(t, rotated.Code) {
+		return
+	}
+
+	revoked := reissueReferralCode(t, user.ID, "revoke")
+	if revoked == nil {
+		return
+	}
+
+	code := rotated.Code
+	resp, err := registerUser(&handler.UserRequest{Name: "Referee", Email: uniqueEmail("referee"), ReferralCode: &code})
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+	if !registerReferee(t, revoked.Code) {
+		return
+	}
+
+	resp, err = http.Get(url + "/users/" + user.ID + "/referral-codes")
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+	history := []*core.ReferralCode{}
+	if assert.NoError(t, getResponseBody(resp.Body, &history)) && assert.Len(t, history, 3) {
+		assert.Equal(t, core.CodeRetiredRotated, history[0].RetiredReason)
+		assert.NotNil(t, history[0].AliasExpiresAt)
+		assert.Equal(t, core.CodeRetiredRevoked, history[1].RetiredReason)
+		assert.Nil(t, history[1].AliasExpiresAt)
+		assert.Empty(t, history[2].RetiredReason)
+		assert.Nil(t, history[2].DeletedAt)
+	}
+
+	point, err := testHandler.userPointRepository.FindPointByUserID(context.Background(), user.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3, point.NumberOfReferredUsers)
+	}
+}
+
+func reissueReferralCode(t *testing.T, userID, action string) *core.ReferralCode {
+	resp, err := http.Post(url+"/users/"+userID+"/referral-code/"+action, "application/json", nil)
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return nil
+	}
+
+	code := &core.ReferralCode{}
+	if !assert.NoError(t, getResponseBody(resp.Body, code)) {
+		return nil
+	}
+	return code
+}
+
+func TestReferralCodeLimits(t *testing.T) {
+	defer func(limit int) { testHandler.config.Referral.MaxRedemptions = limit }(testHandler.config.Referral.MaxRedemptions)
+	testHandler.config.Referral.MaxRedemptions = 5
+
+	user, code, ok := registerReferrer(t)
+	if !ok {
+		return
+	}
+
+	resp := setReferralCodeLimits(t, &handler.ReferralCodeLimitsRequest{UserID: user.ID, AllowedDomains: []string{"not a domain"}})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = setReferralCodeLimits(t, &handler.ReferralCodeLimitsRequest{UserID: user.ID, MaxRedemptions: 2, AllowedDomains: []string{"Acme.test"}})
+	if !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+
+	resp = registerWithEmail(t, uniqueEmail("referee"), code)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	// concurrent registrations can't overshoot the limit
+	statuses := make(chan int, 5)
+	for i := 0; i < 5; i++ {
+		go func(i int) {
+			resp, err := registerUser(&handler.UserRequest{Name: "Referee", Email: fmt.Sprintf("referee%d-%d@acme.test", time.Now().UnixNano(), i),
+				ReferralCode: &code})
+			if err != nil {
+				statuses <- 0
+				return
+			}
+			statuses <- resp.StatusCode
+		}(i)
+	}
+	succeeded := 0
+	for i := 0; i < 5; i++ {
+		status := <-statuses
+		if status == http.StatusOK {
+			succeeded++
+		} else {
+			assert.Equal(t, http.StatusUnprocessableEntity, status)
+		}
+	}
+	assert.Equal(t, 2, succeeded)
+
+	expired := time.Now().Add(-time.Minute)
+	resp = setReferralCodeLimits(t, &handler.ReferralCodeLimitsRequest{UserID: user.ID, ExpiresAt: &expired})
+	if !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+	resp = registerWithEmail(t, uniqueEmail("referee"), code)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	point, err := testHandler.userPointRepository.FindPointByUserID(context.Background(), user.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, point.NumberOfReferredUsers)
+	}
+
+	// owners can lower the configured cap but not lift it
+	limit := testHandler.config.Referral.MaxRedemptions
+	resp = setReferralCodeLimits(t, &handler.ReferralCodeLimitsRequest{UserID: user.ID, MaxRedemptions: limit + 1, ExpiresAt: &expired})
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	// a new code doesn't lift the limits, and counts against them along with the old one
+	rotated := reissueReferralCode(t, user.ID, "rotate")
+	if !assert.NotNil(t, rotated) {
+		return
+	}
+	assert.Equal(t, limit, rotated.MaxRedemptions)
+	assert.NotNil(t, rotated.ExpiresAt)
+
+	resp = setReferralCodeLimits(t, &handler.ReferralCodeLimitsRequest{UserID: user.ID, MaxRedemptions: 3})
+	if !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+	resp = registerWithEmail(t, uniqueEmail("referee"), rotated.Code)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = registerWithEmail(t, uniqueEmail("referee"), rotated.Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+}
+
+func setReferralCodeLimits(t *testing.T, req *handler.ReferralCodeLimitsRequest) *http.Response {
+	resp, err := http.DefaultClient.Do(newJSONRequest(http.MethodPut, url+"/users/"+req.UserID+"/referral-code/limits", req))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return resp
+}
+
+func registerWithEmail(t *testing.T, email, code string) *http.Response {
+	resp, err := registerUser(&handler.UserRequest{Name: "Referee", Email: email, ReferralCode: &code})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return resp
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReferralTreeAndStats(t *testing.T) {
+	root, rootCode, ok := registerReferrer(t)
+	if !ok {
+		return
+	}
+	first, firstCode, ok := registerReferredUser(t, &rootCode)
+	if !ok {
+		return
+	}
+	second, _, ok := registerReferredUser(t, &rootCode)
+	if !ok {
+		return
+	}
+	grandchild, _, ok := registerReferredUser(t, &firstCode)
+	if !ok {
+		return
+	}
+
+	// the first referee becomes active by making a transfer
+	entry := core.NewJournalEntry(core.EntryTypeOpening, "", core.SystemOpeningBalanceAccount, core.UserPointsAccount(first.ID), 10)
+	if !assert.NoError(t, testHandler.ledgerRepository.PostEntry(context.Background(), entry)) {
+		return
+	}
+	resp, err := transaction(&handler.TransferPointsRequest{SenderID: first.ID, RecipientID: second.ID, Points: 5})
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+
+	// newest first, one per page
+	page := listReferrals(t, root.ID, "limit=1")
+	if page == nil || !assert.Len(t, page.Data, 1) {
+		return
+	}
+	assert.Equal(t, second.ID, page.Data[0].UserID)
+	assert.False(t, page.Data[0].Active)
+	page = listReferrals(t, root.ID, "limit=1&cursor="+page.NextCursor)
+	if page == nil || !assert.Len(t, page.Data, 1) {
+		return
+	}
+	assert.Equal(t, first.ID, page.Data[0].UserID)
+	assert.True(t, page.Data[0].Active)
+	assert.Empty(t, page.NextCursor)
+
+	tree := getReferralTree(t, root.ID, 2)
+	if tree != nil && assert.Len(t, tree.Children, 2) {
+		assert.Equal(t, first.ID, tree.Children[0].UserID)
+		if assert.Len(t, tree.Children[0].Children, 1) {
+			assert.Equal(t, grandchild.ID, tree.Children[0].Children[0].UserID)
+			assert.Equal(t, 2, tree.Children[0].Children[0].Level)
+		}
+		assert.Equal(t, second.ID, tree.Children[1].UserID)
+	}
+
+	tree = getReferralTree(t, root.ID, 1)
+	if tree != nil && assert.Len(t, tree.Children, 2) {
+		assert.Empty(t, tree.Children[0].Children)
+	}
+
+	resp, err = http.Get(url + "/users/" + root.ID + "/referral-stats")
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+	stats := &core.ReferralStats{}
+	if assert.NoError(t, getResponseBody(resp.Body, stats)) {
+		assert.Equal(t, 2, stats.TotalReferees)
+		assert.Equal(t, 1, stats.ActiveReferees)
+		if assert.Len(t, stats.ByMonth, 1) {
+			assert.Equal(t, time.Now().UTC().Format("2006-01"), stats.ByMonth[0].Month)
+			assert.Equal(t, 0.5, stats.ByMonth[0].ConversionRate)
+		}
+	}
+}
+
+func listReferrals(t *testing.T, userID, query string) *handler.ReferralPage {
+	resp, err := http.Get(url + "/users/" + userID + "/referrals?" + query)
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return nil
+	}
+
+	page := &handler.ReferralPage{}
+	if !assert.NoError(t, getResponseBody(resp.Body, page)) {
+		return nil
+	}
+	return page
+}
+
+func getReferralTree(t *testing.T, userID string, depth int) *core.ReferralTreeNode {
+	resp, err := http.Get(url + "/users/" + userID + "/referral-tree?depth=" + strconv.Itoa(depth))
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return nil
+	}
+
+	tree := &core.ReferralTreeNode{}
+	if !assert.NoError(t, getResponseBody(resp.Body, tree)) {
+		return nil
+	}
+	return tree
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReverseTransaction(t *testing.T) {
+	sender, err := seedOneUser("Sender", uniqueEmail("sender"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = seedPointBalanceForUser(sender.ID, 500)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	recipient, err := seedOneUser("Recipient", uniqueEmail("recipient"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = seedPointBalanceForUser(recipient.ID, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	original := &core.Transaction{
+		SenderID:    sender.ID,
+		RecipientID: recipient.ID,
+		Points:      300,
+		Type:        "TRANSFER",
+	}
+
+	err = testHandler.userTransactionRepository.CreateTransaction(context.Background(), original)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	entry := core.NewJournalEntry(core.EntryTypeTransfer, original.ID, core.UserPointsAccount(sender.ID), core.UserPointsAccount(recipient.ID), 300)
+	err = testHandler.ledgerRepository.PostEntry(context.Background(), entry)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tests := []struct {
+		points        int
+		wantCode      int
+		wantSender    int
+		wantRecipient int
+	}{
+		// partial reversal
+		{points: 100, wantCode: http.StatusOK, wantSender: 300, wantRecipient: 200},
+		// more than what's left of the transfer
+		{points: 250, wantCode: http.StatusUnprocessableEntity, wantSender: 300, wantRecipient: 200},
+		// reverse the rest
+		{points: 0, wantCode: http.StatusOK, wantSender: 500, wantRecipient: 0},
+		// nothing left to reverse
+		{points: 0, wantCode: http.StatusUnprocessableEntity, wantSender: 500, wantRecipient: 0},
+	}
+
+	var reversalID string
+	for _, test := range tests {
+		resp, err := reverseTransaction(original.ID, &handler.ReverseTransactionRequest{Points: test.points, Reason: "sent to the wrong user"})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, test.wantCode, resp.StatusCode)
+
+		if test.wantCode == http.StatusOK {
+			body := &core.Transaction{}
+			if assert.NoError(t, getResponseBody(resp.Body, body)) {
+				assert.Equal(t, "REVERSAL", body.Type)
+				assert.Equal(t, original.ID, body.ReversalOf)
+				assert.Equal(t, recipient.ID, body.SenderID)
+				reversalID = body.ID
+			}
+		}
+
+		assertBalances(t, sender.ID, test.wantSender, test.wantSender)
+		assertBalances(t, recipient.ID, test.wantRecipient, test.wantRecipient)
+	}
+
+	// reversals can't be reversed themselves
+	resp, err := reverseTransaction(reversalID, &handler.ReverseTransactionRequest{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+}
+
+func reverseTransaction(id string, req *handler.ReverseTransactionRequest) (*http.Response, error) {
+	return http.Post(url+"/transactions/"+id+"/reverse", "application/json", serialize(req))
+}
@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewardRules(t *testing.T) {
+	tiered := &core.RewardRule{
+		Name:      "Tiered test rule",
+		Kind:      core.RuleKindTiered,
+		Active:    true,
+		Tiers:     []core.RewardTier{{From: 1, To: 2, Points: 10}, {From: 3, Points: 20}},
+		CapPoints: 25,
+		CapPeriod: core.CapPeriodDay,
+	}
+
+	// overlapping tiers are rejected
+	resp, err := http.Post(url+"/admin/reward-rules", "application/json", serialize(&core.RewardRule{
+		Name:  "Overlapping",
+		Kind:  core.RuleKindTiered,
+		Tiers: []core.RewardTier{{From: 1, To: 5, Points: 10}, {From: 3, Points: 20}},
+	}))
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	}
+
+	rule := saveRewardRule(t, http.MethodPost, url+"/admin/reward-rules", tiered)
+	if rule == nil {
+		return
+	}
+	assert.Equal(t, 1, rule.Version)
+	// keep the rule from applying to the other tests
+	defer func() {
+		rule.Active = false
+		saveRewardRule(t, http.MethodPut, url+"/admin/reward-rules/"+rule.ID, rule)
+	}()
+
+	referrer, code, ok := registerReferrer(t)
+	if !ok {
+		return
+	}
+
+	for i := 0; i < 3; i++ {
+		if !registerReferee(t, code) {
+			return
+		}
+	}
+
+	// 10 + 10 from the first tier, 20 cut to the 5 left of the daily cap and the
+	// 50 of the default every third referral rule
+	point, err := testHandler.userPointRepository.FindPointByUserID(context.Background(), referrer.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3, point.NumberOfReferredUsers)
+		assert.Equal(t, 75, point.Bonus)
+	}
+
+	granted, err := testHandler.rewardRuleRepository.SumRewardPoints(context.Background(), rule.ID, referrer.ID, time.Time{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 25, granted)
+	}
+
+	var version int
+	err = testHandler.client.QueryRow(context.Background(), "SELECT MAX(rule_version) FROM referral_rewards WHERE rule_id = $1", rule.ID).Scan(&version)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, version)
+	}
+
+	// changing a rule saves it as a new version
+	rule.CapPoints = 100
+	updated := saveRewardRule(t, http.MethodPut, url+"/admin/reward-rules/"+rule.ID, rule)
+	if assert.NotNil(t, updated) {
+		assert.Equal(t, 2, updated.Version)
+	}
+}
+
+func TestMultiLevelRewards(t *testing.T) {
+	levelRules := []*core.RewardRule{
+		{Name: "Level 2 test rule", Kind: core.RuleKindFlat, Active: true, Level: 2, Points: 10},
+		{Name: "Level 3 test rule", Kind: core.RuleKindFlat, Active: true, Level: 3, Points: 5},
+		// deeper than the configured max depth of 3, never applies
+		{Name: "Level 4 test rule", Kind: core.RuleKindFlat, Active: true, Level: 4, Points: 1},
+	}
+
+	// only flat rules can reward indirect referrers
+	resp, err := http.Post(url+"/admin/reward-rules", "application/json", serialize(&core.RewardRule{
+		Name: "Level 2 milestone", Kind: core.RuleKindMilestone, Level: 2, Points: 10, Every: 2,
+	}))
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	}
+
+	for _, rule := range levelRules {
+		saved := saveRewardRule(t, http.MethodPost, url+"/admin/reward-rules", rule)
+		if saved == nil {
+			return
+		}
+		defer func() {
+			saved.Active = false
+			saveRewardRule(t, http.MethodPut, url+"/admin/reward-rules/"+saved.ID, saved)
+		}()
+	}
+
+	// a refers b, b refers c, c refers d and d refers e
+	users := make([]*core.User, 5)
+	var code *string
+	for i := range users {
+		user, userCode, ok := registerReferredUser(t, code)
+		if !ok {
+			return
+		}
+		users[i], code = user, &userCode
+	}
+
+	wantBonus := []int{
+		10 + 5, // level 2 of c, level 3 of d
+		10 + 5, // level 2 of d, level 3 of e
+		10,     // level 2 of e
+		0,
+		0,
+	}
+	for i, user := range users {
+		point, err := testHandler.userPointRepository.FindPointByUserID(context.Background(), user.ID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, wantBonus[i], point.Bonus, "user %d", i)
+		}
+	}
+
+	var levels []int
+	rows, err := testHandler.client.Query(context.Background(), `SELECT level FROM referral_rewards
+	WHERE referral_id = (SELECT id FROM referrals WHERE referee_id = $1) ORDER BY level`, users[4].ID)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var level int
+		if assert.NoError(t, rows.Scan(&level)) {
+			levels = append(levels, level)
+		}
+	}
+	assert.Equal(t, []int{2, 3}, levels)
+}
+
+func saveRewardRule(t *testing.T, method, target string, rule *core.RewardRule) *core.RewardRule {
+	resp, err := http.DefaultClient.Do(newJSONRequest(method, target, rule))
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return nil
+	}
+
+	saved := &core.RewardRule{}
+	if !assert.NoError(t, getResponseBody(resp.Body, saved)) {
+		return nil
+	}
+	return saved
+}
+
+// registerReferrer registers a user through the API and returns them with their referral code.
+func registerReferrer(t *testing.T) (*core.User, string, bool) {
+	return registerReferredUser(t, nil)
+}
+
+// registerReferredUser registers a user through the API with the given referral code and returns
+// them with their own referral code.
+func registerReferredUser(t *testing.T, referralCode *string) (*core.User, string, bool) {
+	resp, err := registerUser(&handler.UserRequest{Name: "Referrer", Email: uniqueEmail("referrer"), ReferralCode: referralCode})
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return nil, "", false
+	}
+
+	user := &core.User{}
+	if !assert.NoError(t, getResponseBody(resp.Body, user)) {
+		return nil, "", false
+	}
+
+	code, err := testHandler.userRefCodeRepository.FindReferralCodeByUserID(context.Background(), user.ID)
+	if !assert.NoError(t, err) {
+		return nil, "", false
+	}
+	return user, code.Code, true
+}
+
+func registerReferee(t *testing.T, code string) bool {
+	resp, err := registerUser(&handler.UserRequest{Name: "Referee", Email: uniqueEmail("referee"), ReferralCode: &code})
+	return assert.NoError(t, err) && assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+// maxScheduleFailures matches schedules.max_failures in config/config.yml.
+const maxScheduleFailures = 3
+
+func TestScheduledTransfer(t *testing.T) {
+	sender, recipient, ok := seedHoldUsers(t, 500)
+	if !ok {
+		return
+	}
+
+	runAt := time.Now().Add(time.Hour)
+	invalid := []*handler.ScheduledTransferRequest{
+		// neither a run time nor a rule
+		{SenderID: sender.ID, RecipientID: recipient.ID, Points: 100},
+		// both a run time and a rule
+		{SenderID: sender.ID, RecipientID: recipient.ID, Points: 100, RunAt: &runAt, Rule: "@weekly"},
+		{SenderID: sender.ID, RecipientID: recipient.ID, Points: 100, Rule: "0 25 * * *"},
+	}
+	for _, req := range invalid {
+		createScheduledTransfer(t, req, http.StatusBadRequest)
+	}
+
+	schedule := createScheduledTransfer(t, &handler.ScheduledTransferRequest{SenderID: sender.ID, RecipientID: recipient.ID, Points: 200, RunAt: &runAt}, http.StatusOK)
+	if schedule == nil {
+		return
+	}
+	assert.Equal(t, core.ScheduleStatusActive, schedule.Status)
+
+	// not due yet
+	runScheduledTransfers(t)
+	assertBalance(t, recipient.ID, 0, 0, 0)
+
+	if !makeScheduleDue(t, schedule.ID) {
+		return
+	}
+	runScheduledTransfers(t)
+	assertBalance(t, sender.ID, 300, 300, 0)
+	assertBalance(t, recipient.ID, 200, 200, 0)
+
+	ran := getScheduledTransfer(t, schedule.ID)
+	if assert.NotNil(t, ran) {
+		assert.Equal(t, core.ScheduleStatusCompleted, ran.Status)
+		assert.Nil(t, ran.NextRunAt)
+	}
+
+	executions := listScheduledTransferExecutions(t, schedule.ID)
+	if assert.Len(t, executions, 1) {
+		assert.Equal(t, core.ExecutionStatusSuccess, executions[0].Status)
+		assert.NotEmpty(t, executions[0].TransactionID)
+	}
+}
+
+func TestRecurringScheduledTransferPausesWithoutFunds(t *testing.T) {
+	sender, recipient, ok := seedHoldUsers(t, 50)
+	if !ok {
+		return
+	}
+
+	schedule := createScheduledTransfer(t, &handler.ScheduledTransferRequest{SenderID: sender.ID, RecipientID: recipient.ID, Points: 100, Rule: "@weekly"}, http.StatusOK)
+	if schedule == nil {
+		return
+	}
+	assert.True(t, schedule.NextRunAt.After(time.Now()))
+
+	for i := 0; i < maxScheduleFailures; i++ {
+		if !makeScheduleDue(t, schedule.ID) {
+			return
+		}
+		runScheduledTransfers(t)
+	}
+
+	paused := getScheduledTransfer(t, schedule.ID)
+	if !assert.NotNil(t, paused) {
+		return
+	}
+	assert.Equal(t, core.ScheduleStatusPaused, paused.Status)
+
+	executions := listScheduledTransferExecutions(t, schedule.ID)
+	assert.Len(t, executions, maxScheduleFailures)
+	for _, execution := range executions {
+		assert.Equal(t, core.ExecutionStatusFailed, execution.Status)
+	}
+	assertBalance(t, sender.ID, 50, 50, 0)
+
+	// resuming clears the failures and picks up at the next occurrence
+	status := core.ScheduleStatusActive
+	resp, err := http.DefaultClient.Do(newJSONRequest(http.MethodPatch, url+"/scheduled-transfers/"+schedule.ID, &handler.UpdateScheduledTransferRequest{Status: &status}))
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return
+	}
+	resumed := &core.ScheduledTransfer{}
+	if assert.NoError(t, getResponseBody(resp.Body, resumed)) {
+		assert.Equal(t, core.ScheduleStatusActive, resumed.Status)
+		assert.Equal(t, 0, resumed.ConsecutiveFailures)
+		assert.True(t, resumed.NextRunAt.After(time.Now()))
+	}
+
+	resp, err = http.DefaultClient.Do(newJSONRequest(http.MethodDelete, url+"/scheduled-transfers/"+schedule.ID, nil))
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	}
+
+	resp, err = http.Get(url + "/scheduled-transfers/" + schedule.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+func createScheduledTransfer(t *testing.T, req *handler.ScheduledTransferRequest, wantCode int) *core.ScheduledTransfer {
+	resp, err := http.Post(url+"/scheduled-transfers", "application/json", serialize(req))
+	if !assert.NoError(t, err) || !assert.Equal(t, wantCode, resp.StatusCode) || wantCode != http.StatusOK {
+		return nil
+	}
+
+	schedule := &core.ScheduledTransfer{}
+	if !assert.NoError(t, getResponseBody(resp.Body, schedule)) {
+		return nil
+	}
+	return schedule
+}
+
+func getScheduledTransfer(t *testing.T, id string) *core.ScheduledTransfer {
+	resp, err := http.Get(url + "/scheduled-transfers/" + id)
+	if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return nil
+	}
+
+	schedule := &core.ScheduledTransfer{}
+	if !assert.NoError(t, getResponseBody(resp.Body, schedule)) {
+		return nil
+	}
+	return schedule
+}
+
+func listScheduledTransferExecutions(t *testing.T, id string) []*core.ScheduledTransferExecution {
+	executions := []*core.ScheduledTransferExecution{}
+	resp, err := http.Get(url + "/scheduled-transfers/" + id + "/executions")
+	if assert.NoError(t, err) && assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		assert.NoError(t, getResponseBody(resp.Body, &executions))
+	}
+	return executions
+}
+
+func makeScheduleDue(t *testing.T, id string) bool {
+	_, err := testHandler.client.Exec(context.Background(), "UPDATE scheduled_transfers SET next_run_at = now() - interval '1 second' WHERE id = $1", id)
+	return assert.NoError(t, err)
+}
+
+func runScheduledTransfers(t *testing.T) {
+	assert.NoError(t, testHandler.handler.RunScheduledTransfers(context.Background()))
+}
+
+func newJSONRequest(method, target string, body interface{}) *http.Request {
+	req, err := http.NewRequest(method, target, serialize(body))
+	if err != nil {
+		panic(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	core "github.com/Qalifah/aboki-africa-assessment"
+	"github.com/Qalifah/aboki-africa-assessment/campaign"
+	"github.com/Qalifah/aboki-africa-assessment/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+// countryHeader is the header the tests tell the client's country in, as a proxy in front of the service would.
+const countryHeader = "X-Client-Country"
+
+func TestWelcomeBonus(t *testing.T) {
+	welcomeConfig := testHandler.config.Welcome
+	defer func() { testHandler.config.Welcome = welcomeConfig }()
+	testHandler.config.Welcome.Points = 15
+	testHandler.config.Welcome.ByCountry = map[string]int{"GH": 25}
+
+	_, code, ok := registerReferrer(t)
+	if !ok {
+		return
+	}
+
+	welcomePoints := 40
+	c := createCampaign(t, &campaign.Request{
+		Name:          "Welcome bonus",
+		StartsAt:      time.Now().Add(-time.Hour),
+		EndsAt:        time.Now().Add(time.Hour),
+		BudgetPoints:  1000,
+		WelcomePoints: &welcomePoints,
+		Codes:         []string{campaignCode("HELLO")},
+		Rule:          &core.RewardRule{Kind: core.RuleKindFlat, Points: 5},
+	}, http.StatusOK)
+	if c == nil {
+		return
+	}
+
+	for _, tc := range []struct {
+		name          string
+		code          string
+		country       string
+		clientCountry string
+		want          int
+	}{
+		{"default", code, "", "", 15},
+		{"country", code, "", "gh", 25},
+		// what the user says of their country doesn't pick their bonus
+		{"stated country", code, "GH", "", 15},
+		{"campaign", c.Codes[0], "", "GH", 40},
+		{"not referred", "", "", "", 0},
+	} {
+		req := &handler.UserRequest{Name: "Referee", Email: uniqueEmail("welcome"), Country: tc.country}
+		if tc.code != "" {
+			code := tc.code
+			req.ReferralCode = &code
+		}
+
+		httpReq := newJSONRequest(http.MethodPost, url+"/register", req)
+		if tc.clientCountry != "" {
+			httpReq.Header.Set(countryHeader, tc.clientCountry)
+		}
+		resp, err := http.DefaultClient.Do(httpReq)
+		if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, resp.StatusCode, tc.name) {
+			return
+		}
+
+		registration := &handler.Registration{}
+		if !assert.NoError(t, getResponseBody(resp.Body, registration)) {
+			return
+		}
+
+		if tc.want == 0 {
+			assert.Nil(t, registration.WelcomeBonus, tc.name)
+		} else if assert.NotNil(t, registration.WelcomeBonus, tc.name) {
+			assert.Equal(t, core.EntryTypeWelcome, registration.WelcomeBonus.Type)
+			assert.Equal(t, tc.want, registration.WelcomeBonus.Points, tc.name)
+		}
+
+		balance, err := testHandler.userPointRepository.GetPointsBalance(context.Background(), registration.ID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, tc.want, balance, tc.name)
+		}
+	}
+
+	// countries are ISO 3166-1 alpha-2 codes
+	resp, err := registerUser(&handler.UserRequest{Name: "Referee", Email: uniqueEmail("welcome"), ReferralCode: &code, Country: "Ghana"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	}
+}
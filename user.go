@@ -5,94 +5,232 @@ import (
 	"time"
 )
 
-const bonus = 50
-
 type User struct {
-	ID 			string		`json:"id"`
-	Name		string		`json:"name"`
-	Email		string		`json:"email"`
-	CreatedAt   time.Time	`json:"created_at"`
-	UpdatedAt	time.Time	`json:"updated_at"`
-	DeletedAt	time.Time	`json:"deleted_at"`
+	ID              string     `json:"id"`
+	Name            string     `json:"name"`
+	Email           string     `json:"email"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	Country         string     `json:"country,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	DeletedAt       time.Time  `json:"deleted_at"`
 }
 
 type ReferralCode struct {
-	ID			string		`json:"id"`
-	UserID		string		`json:"user_id"`
-	Code		string		`json:"code"`
-	CreatedAt   time.Time	`json:"created_at"`
-	DeletedAt	time.Time	`json:"deleted_at"`
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+	Code   string `json:"code"`
+	// Vanity codes are chosen by their users rather than generated, and match case-insensitively.
+	Vanity bool `json:"vanity"`
+	// AliasExpiresAt is when a replaced code stops referring new users to its owner.
+	AliasExpiresAt *time.Time `json:"alias_expires_at,omitempty"`
+	// RetiredReason tells why the code is no longer its user's active code, empty while it is.
+	RetiredReason string `json:"retired_reason,omitempty"`
+	// MaxRedemptions caps how many users may register with the code, zero leaves it uncapped.
+	MaxRedemptions int        `json:"max_redemptions"`
+	Redemptions    int        `json:"redemptions"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	// AllowedDomains restricts the code to users with an email at one of the domains, when set.
+	AllowedDomains []string   `json:"allowed_domains"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
 }
 
+// Reasons a referral code was retired.
+const (
+	CodeRetiredReplaced = "REPLACED"
+	CodeRetiredRotated  = "ROTATED"
+	CodeRetiredRevoked  = "REVOKED"
+)
+
 type Referral struct {
-	ID			string		`json:"id"`
-	ReferrerID  string      `json:"referrer_id"` 
-	RefereeID   string      `json:"referee_id"` 
-	CreatedAt   time.Time	`json:"created_at"`
-	DeletedAt	time.Time	`json:"deleted_at"`
+	ID         string `json:"id"`
+	ReferrerID string `json:"referrer_id"`
+	RefereeID  string `json:"referee_id"`
+	CampaignID string `json:"campaign_id,omitempty"`
+	// ReferralCodeID is the code the referee registered with, empty for campaign referrals.
+	ReferralCodeID string `json:"referral_code_id,omitempty"`
+	// Status is PENDING until the referee completes the qualifying action, its rewards are only released then.
+	Status      string     `json:"status"`
+	QualifiedAt *time.Time `json:"qualified_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	DeletedAt   time.Time  `json:"deleted_at"`
+}
+
+// DroppedReferral is a dropped referral along with the points it released that were never taken back.
+type DroppedReferral struct {
+	*Referral
+	ReleasedPoints int `json:"released_points"`
 }
 
+// ReferralAncestor is a user up the referral chain of another, Level 1 being their direct referrer.
+type ReferralAncestor struct {
+	UserID string
+	Level  int
+}
+
+// Point is a projection of a user's ledger accounts: Points and Bonus are the balances
+// of their POINTS and BONUS accounts.
 type Point struct {
-	ID						string 		`json:"id"`
-	UserID					string		`json:"user_id"`
-	Points					int			`json:"points"`
-	NumberOfReferredUsers	int			`json:"number_of_referred_users"`
-	Bonus					int			`json:"bonus"`
-	Paid					bool		`json:"paid"`
-	CreatedAt   			time.Time	`json:"created_at"`
-	UpdatedAt				time.Time	`json:"updated_at"`
-	DeletedAt				time.Time	`json:"deleted_at"`
-}
-
-func(p *Point) Deduct(points int) {
+	ID                    string    `json:"id"`
+	UserID                string    `json:"user_id"`
+	Points                int       `json:"points"`
+	NumberOfReferredUsers int       `json:"number_of_referred_users"`
+	Bonus                 int       `json:"bonus"`
+	Paid                  bool      `json:"paid"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
+	DeletedAt             time.Time `json:"deleted_at"`
+}
+
+func (p *Point) Deduct(points int) {
 	p.Points -= points
 }
 
-func(p *Point) Add(points int) {
+func (p *Point) Add(points int) {
 	p.Points += points
 }
 
-func(p *Point) AddBonus() {
-	p.Bonus += bonus
+func (p *Point) AddBonus(points int) {
+	p.Bonus += points
+}
+
+func (p *Point) DeductBonus(points int) {
+	p.Bonus -= points
 }
 
-func(p *Point) IncreaseUserReferrals() {
+func (p *Point) IncreaseUserReferrals() {
 	p.NumberOfReferredUsers++
 }
 
+func (p *Point) DecreaseUserReferrals() {
+	p.NumberOfReferredUsers--
+}
+
 type Transaction struct {
-	ID              string     `json:"id"`
-	SenderID        string     `json:"sender_id"`
-	RecipientID 	string     `json:"recipient_id"`
-	Points			int		   `json:"points"`
-	Type			string	   `json:"type"`
-	CreatedAt   	time.Time  `json:"created_at"`
-	DeletedAt		time.Time  `json:"deleted_at"`
+	ID          string `json:"id"`
+	SenderID    string `json:"sender_id"`
+	RecipientID string `json:"recipient_id"`
+	Points      int    `json:"points"`
+	Type        string `json:"type"`
+	// ReversalOf links a REVERSAL to the transaction it compensates.
+	ReversalOf string `json:"reversal_of,omitempty"`
+	// ReversedPoints is how much of the transaction has been reversed so far.
+	ReversedPoints int `json:"reversed_points"`
+	// ExpiresAt is when the points credited by the transaction expire, nil if never.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt time.Time  `json:"deleted_at"`
+}
+
+// Directions a user's transactions can be filtered by.
+const (
+	DirectionSent     = "sent"
+	DirectionReceived = "received"
+)
+
+// TransactionFilter selects a page of a user's transactions, newest first. Nil and
+// zero fields don't filter, After continues from the last transaction of the previous page.
+type TransactionFilter struct {
+	UserID    string
+	Direction string
+	Type      string
+	MinPoints *int
+	MaxPoints *int
+	From      *time.Time
+	To        *time.Time
+	After     *TransactionCursor
+	Limit     int
+}
+
+// TransactionCursor is the position of a transaction in the (created_at, id) ordering.
+type TransactionCursor struct {
+	CreatedAt time.Time
+	ID        string
 }
 
 type UserRepository interface {
 	CreateUser(ctx context.Context, user *User) error
 	FindUserByID(ctx context.Context, id string) (*User, error)
+	// FindUserByReferralCode returns the user the code refers new users to, errors.ErrReferralCodeRevoked
+	// when the code was revoked.
 	FindUserByReferralCode(ctx context.Context, code string) (*User, error)
+	// VerifyEmail marks the user's email as verified, keeping the time it was first verified.
+	VerifyEmail(ctx context.Context, user *User) error
+	// DeleteUser soft-deletes the user.
+	DeleteUser(ctx context.Context, user *User) error
+	// ListDuplicateAccounts returns the live users sharing a mailbox, grouped by canonical email, the limit
+	// applying to groups.
+	ListDuplicateAccounts(ctx context.Context, limit int) ([]*DuplicateAccounts, error)
 }
 
 type ReferralCodeRepository interface {
 	CreateReferralCode(ctx context.Context, uRefCode *ReferralCode) error
+	// FindReferralCodeByUserID returns the user's active code, locked for the rest of the surrounding transaction.
 	FindReferralCodeByUserID(ctx context.Context, userID string) (*ReferralCode, error)
+	// FindReferralCodeByCode returns the live code new users can register with, locked for the rest of the
+	// surrounding transaction. Revoked codes return errors.ErrReferralCodeRevoked.
+	FindReferralCodeByCode(ctx context.Context, code string) (*ReferralCode, error)
+	IncrementRedemptions(ctx context.Context, uRefCode *ReferralCode) error
+	// CountRedemptions returns how many users registered with any of the user's codes, retired ones included.
+	CountRedemptions(ctx context.Context, userID string) (int, error)
+	// UpdateReferralCodeLimits saves the max redemptions, expiry and allowed domains of the code.
+	UpdateReferralCodeLimits(ctx context.Context, uRefCode *ReferralCode) error
+	// RetireReferralCode soft-deletes the code for the given reason, keeping it as an alias of its user
+	// until aliasExpiresAt when set.
+	RetireReferralCode(ctx context.Context, uRefCode *ReferralCode, reason string, aliasExpiresAt *time.Time) error
+	// ListReferralCodes returns every code the user ever had, oldest first.
+	ListReferralCodes(ctx context.Context, userID string) ([]*ReferralCode, error)
+	// CountVanityCodesSince returns how many vanity codes the user claimed since the given time.
+	CountVanityCodesSince(ctx context.Context, userID string, since time.Time) (int, error)
 }
 
 type ReferralRepository interface {
+	// CreateReferral returns errors.ErrAlreadyReferred, errors.ErrSelfReferral or errors.ErrReferralCycle
+	// for referrals that would break the referral chain.
 	CreateReferral(ctx context.Context, referral *Referral) error
+	// ListAncestors walks the referral chain up from the user, nearest first, stopping at maxDepth
+	// levels or when the chain loops back on itself.
+	ListAncestors(ctx context.Context, userID string, maxDepth int) ([]*ReferralAncestor, error)
+	// ListReferees returns the users the user referred, newest first, starting after the given position.
+	ListReferees(ctx context.Context, userID string, after *RefereeCursor, limit int) ([]*Referee, error)
+	// ListDescendants walks the referral chain down from the user, level by level, up to maxDepth levels.
+	ListDescendants(ctx context.Context, userID string, maxDepth int) ([]*ReferralDescendant, error)
+	GetReferralStats(ctx context.Context, userID string) (*ReferralStats, error)
+	// FindPendingReferral returns the user's referral while it waits for them to qualify and hasn't expired,
+	// locked for the rest of the surrounding transaction.
+	FindPendingReferral(ctx context.Context, refereeID string) (*Referral, error)
+	UpdateReferralStatus(ctx context.Context, referral *Referral) error
+	// ListQualifiedReferees returns referees whose pending referral the qualifying action was completed for.
+	ListQualifiedReferees(ctx context.Context, action string, minPoints int, limit int) ([]string, error)
+	// ExpirePendingReferrals expires pending referrals, and their pending rewards, whose window ended before the given time.
+	ExpirePendingReferrals(ctx context.Context, before time.Time) (int, error)
+	// FindReferralByID returns the referral, clawed back ones included, locked for the rest of the surrounding transaction.
+	FindReferralByID(ctx context.Context, id string) (*Referral, error)
+	// FindReferralByReferee returns the referral of the user, locked for the rest of the surrounding transaction.
+	FindReferralByReferee(ctx context.Context, refereeID string) (*Referral, error)
+	// ClawBackReferral marks the referral clawed back and soft-deletes it, taking it out of the referral chain.
+	ClawBackReferral(ctx context.Context, referral *Referral) error
+	// ListDroppedReferrals returns the dropped referrals whose released rewards weren't clawed back yet,
+	// oldest first.
+	ListDroppedReferrals(ctx context.Context, limit int) ([]*DroppedReferral, error)
 }
 
 type PointRepository interface {
 	CreatePoint(ctx context.Context, Point *Point) error
 	FindPointByUserID(ctx context.Context, userID string) (*Point, error)
+	LockPoint(ctx context.Context, userID string) (*Point, error)
 	UpdatePoint(ctx context.Context, Point *Point) error
 	GetPointsBalance(ctx context.Context, userID string) (int, error)
+	FindLotByID(ctx context.Context, id string) (*PointLot, error)
+	ListExpiredLots(ctx context.Context, before time.Time, limit int) ([]*PointLot, error)
+	GetExpiringPoints(ctx context.Context, userID string, before time.Time) ([]*ExpiringPoints, error)
 }
 
 type TransactionRepository interface {
 	CreateTransaction(ctx context.Context, transaction *Transaction) error
-	// ClaimReferrerBonus(ctx context.Context, userID string) error
-}
\ No newline at end of file
+	FindTransactionByID(ctx context.Context, id string) (*Transaction, error)
+	ListTransactions(ctx context.Context, filter *TransactionFilter) ([]*Transaction, error)
+	ClaimReferrerBonus(ctx context.Context, transaction *Transaction) error
+}
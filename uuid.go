@@ -0,0 +1,10 @@
+package aboki_africa_assessment
+
+import "regexp"
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// IsUUID reports whether s is a textual uuid, as used for every id in the database.
+func IsUUID(s string) bool {
+	return uuidPattern.MatchString(s)
+}